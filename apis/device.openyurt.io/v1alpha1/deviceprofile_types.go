@@ -18,10 +18,18 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
 const (
 	DeviceProfileFinalizer = "v1alpha1.deviceProfile.finalizer"
+	// DeviceProfileSyncedCondition indicates that the deviceProfile exists in both OpenYurt and edge platform
+	DeviceProfileSyncedCondition clusterv1.ConditionType = "DeviceProfileSynced"
+	// DeviceProfileManagingCondition indicates that the deviceProfile is being managed by cloud and its fields are being reconciled
+	DeviceProfileManagingCondition clusterv1.ConditionType = "DeviceProfileManaging"
+	// DeviceProfileAuthorizedCondition indicates whether the edge platform accepts the credentials of the controller,
+	// it is only set once the edge platform has rejected a request about the deviceProfile
+	DeviceProfileAuthorizedCondition clusterv1.ConditionType = "DeviceProfileAuthorized"
 )
 
 type DeviceResource struct {
@@ -30,7 +38,9 @@ type DeviceResource struct {
 	Tag         string             `json:"tag,omitempty"`
 	IsHidden    bool               `json:"isHidden"`
 	Properties  ResourceProperties `json:"properties"`
-	Attributes  map[string]string  `json:"attributes,omitempty"`
+	// Attributes whose values are not strings are encoded as JSON and listed in the
+	// "device-controller/edgex-typed-attributes" annotation of the deviceProfile, v1alpha2 keeps them as JSON values
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
 type ResourceProperties struct {
@@ -75,19 +85,27 @@ type DeviceProfileSpec struct {
 	Labels          []string         `json:"labels,omitempty"`
 	DeviceResources []DeviceResource `json:"deviceResources,omitempty"`
 	DeviceCommands  []DeviceCommand  `json:"deviceCommands,omitempty"`
+	// True means deviceProfile is managed by cloud, cloud can update the related fields
+	// False means cloud can't update the fields, and they are synchronized from the edge platform
+	Managed bool `json:"managed,omitempty"`
 }
 
 // DeviceProfileStatus defines the observed state of DeviceProfile
 type DeviceProfileStatus struct {
 	EdgeId string `json:"id,omitempty"`
 	Synced bool   `json:"synced,omitempty"`
+	// current deviceProfile state
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:shortName=dp
+//+kubebuilder:storageversion
 //+kubebuilder:printcolumn:name="NODEPOOL",type="string",JSONPath=".spec.nodePool",description="The nodepool of deviceProfile"
 //+kubebuilder:printcolumn:name="SYNCED",type="boolean",JSONPath=".status.synced",description="The synced status of deviceProfile"
+//+kubebuilder:printcolumn:name="MANAGED",type="boolean",priority=1,JSONPath=".spec.managed",description="The managed status of deviceProfile"
 //+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 // DeviceProfile represents the attributes and operational capabilities of a device.
@@ -102,6 +120,14 @@ type DeviceProfile struct {
 	Status DeviceProfileStatus `json:"status,omitempty"`
 }
 
+func (dp *DeviceProfile) SetConditions(conditions clusterv1.Conditions) {
+	dp.Status.Conditions = conditions
+}
+
+func (dp *DeviceProfile) GetConditions() clusterv1.Conditions {
+	return dp.Status.Conditions
+}
+
 //+kubebuilder:object:root=true
 
 // DeviceProfileList contains a list of DeviceProfile
@@ -27,6 +27,9 @@ const (
 	DeviceServiceSyncedCondition clusterv1.ConditionType = "DeviceServiceSynced"
 	// DeviceServiceManagingCondition indicates that the deviceService is being managed by cloud and its field are being reconciled
 	DeviceServiceManagingCondition clusterv1.ConditionType = "DeviceServiceManaging"
+	// DeviceServiceAuthorizedCondition indicates whether the edge platform accepts the credentials of the controller,
+	// it is only set once the edge platform has rejected a request about the deviceService
+	DeviceServiceAuthorizedCondition clusterv1.ConditionType = "DeviceServiceAuthorized"
 )
 
 // DeviceServiceSpec defines the desired state of DeviceService
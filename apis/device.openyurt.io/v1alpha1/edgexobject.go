@@ -16,6 +16,24 @@ limitations under the License.
 
 package v1alpha1
 
+// The fields of the EdgeX objects which can't be represented by the v1alpha1 API are kept in these annotations,
+// so that the objects are converted back to EdgeX, and between v1alpha1 and v1alpha2, without loss
+const (
+	// EdgeXLocationFormat is set to EdgeXLocationFormatJSON if the location of the device on EdgeX is not a string,
+	// the location in the device spec is then the JSON encoding of it
+	EdgeXLocationFormat     = "device-controller/edgex-location.format"
+	EdgeXLocationFormatJSON = "json"
+	// EdgeXTypedAttributes lists the attributes of the deviceResources whose values on EdgeX are not strings,
+	// e.g. {"Temperature":["startingAddress"]}, the attributes in the deviceProfile spec are then the JSON encoding of them
+	EdgeXTypedAttributes = "device-controller/edgex-typed-attributes"
+	// EdgeXV3Fields keeps the fields of the EdgeX v3 object which have no counterpart in the v1alpha1 API in JSON,
+	// e.g. {"tags":{"floor":1}}
+	EdgeXV3Fields = "device-controller/edgex-v3-fields"
+	// EdgeXV1Fields keeps the fields of the EdgeX v1 object which have no counterpart in the v1alpha1 API in JSON,
+	// e.g. the addressable of the deviceService and the coreCommands of the deviceProfile
+	EdgeXV1Fields = "device-controller/edgex-v1-fields"
+)
+
 type EdgeXObject interface {
 	IsAddedToEdgeX() bool
 }
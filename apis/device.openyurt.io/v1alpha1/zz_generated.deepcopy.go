@@ -29,6 +29,16 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActualPropertyState) DeepCopyInto(out *ActualPropertyState) {
 	*out = *in
+	if in.ObjectValue != nil {
+		in, out := &in.ObjectValue, &out.ObjectValue
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BinaryValue != nil {
+		in, out := &in.BinaryValue, &out.BinaryValue
+		*out = new(BinaryValue)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActualPropertyState.
@@ -41,9 +51,71 @@ func (in *ActualPropertyState) DeepCopy() *ActualPropertyState {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoEvent) DeepCopyInto(out *AutoEvent) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoEvent.
+func (in *AutoEvent) DeepCopy() *AutoEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinaryValue) DeepCopyInto(out *BinaryValue) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(BinaryValueSource)
+		**out = **in
+	}
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinaryValue.
+func (in *BinaryValue) DeepCopy() *BinaryValue {
+	if in == nil {
+		return nil
+	}
+	out := new(BinaryValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinaryValueSource) DeepCopyInto(out *BinaryValueSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinaryValueSource.
+func (in *BinaryValueSource) DeepCopy() *BinaryValueSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BinaryValueSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DesiredPropertyState) DeepCopyInto(out *DesiredPropertyState) {
 	*out = *in
+	if in.DesiredValues != nil {
+		in, out := &in.DesiredValues, &out.DesiredValues
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DesiredPropertyState.
@@ -143,7 +215,7 @@ func (in *DeviceProfile) DeepCopyInto(out *DeviceProfile) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceProfile.
@@ -233,6 +305,13 @@ func (in *DeviceProfileSpec) DeepCopy() *DeviceProfileSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceProfileStatus) DeepCopyInto(out *DeviceProfileStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1alpha4.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceProfileStatus.
@@ -394,11 +473,16 @@ func (in *DeviceSpec) DeepCopyInto(out *DeviceSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AutoEvents != nil {
+		in, out := &in.AutoEvents, &out.AutoEvents
+		*out = make([]AutoEvent, len(*in))
+		copy(*out, *in)
+	}
 	if in.DeviceProperties != nil {
 		in, out := &in.DeviceProperties, &out.DeviceProperties
 		*out = make(map[string]DesiredPropertyState, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 }
@@ -420,7 +504,7 @@ func (in *DeviceStatus) DeepCopyInto(out *DeviceStatus) {
 		in, out := &in.DeviceProperties, &out.DeviceProperties
 		*out = make(map[string]ActualPropertyState, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 	if in.Conditions != nil {
@@ -0,0 +1,403 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var _ conversion.Convertible = &Device{}
+var _ conversion.Convertible = &DeviceProfile{}
+
+// ConvertTo converts this Device to the Hub version (v1alpha1)
+func (src *Device) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.Device)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	loc, isJSON, err := fromJSONValue(src.Spec.Location)
+	if err != nil {
+		return err
+	}
+	if isJSON {
+		setAnnotation(&dst.ObjectMeta, v1alpha1.EdgeXLocationFormat, v1alpha1.EdgeXLocationFormatJSON)
+	} else {
+		deleteAnnotation(&dst.ObjectMeta, v1alpha1.EdgeXLocationFormat)
+	}
+
+	dst.Spec = v1alpha1.DeviceSpec{
+		Description:    src.Spec.Description,
+		AdminState:     v1alpha1.AdminState(src.Spec.AdminState),
+		OperatingState: v1alpha1.OperatingState(src.Spec.OperatingState),
+		Labels:         src.Spec.Labels,
+		Location:       loc,
+		Service:        src.Spec.Service,
+		Profile:        src.Spec.Profile,
+		Notify:         src.Spec.Notify,
+		Managed:        src.Spec.Managed,
+		NodePool:       src.Spec.NodePool,
+	}
+	if src.Spec.Protocols != nil {
+		dst.Spec.Protocols = make(map[string]v1alpha1.ProtocolProperties, len(src.Spec.Protocols))
+		for k, v := range src.Spec.Protocols {
+			dst.Spec.Protocols[k] = v1alpha1.ProtocolProperties(v)
+		}
+	}
+	if src.Spec.AutoEvents != nil {
+		dst.Spec.AutoEvents = make([]v1alpha1.AutoEvent, 0, len(src.Spec.AutoEvents))
+		for _, ae := range src.Spec.AutoEvents {
+			dst.Spec.AutoEvents = append(dst.Spec.AutoEvents, v1alpha1.AutoEvent(ae))
+		}
+	}
+	if src.Spec.DeviceProperties != nil {
+		dst.Spec.DeviceProperties = make(map[string]v1alpha1.DesiredPropertyState, len(src.Spec.DeviceProperties))
+		for k, v := range src.Spec.DeviceProperties {
+			dst.Spec.DeviceProperties[k] = v1alpha1.DesiredPropertyState(v)
+		}
+	}
+
+	dst.Status = v1alpha1.DeviceStatus{
+		LastConnected:  src.Status.LastConnected,
+		LastReported:   src.Status.LastReported,
+		Synced:         src.Status.Synced,
+		EdgeId:         src.Status.EdgeId,
+		AdminState:     v1alpha1.AdminState(src.Status.AdminState),
+		OperatingState: v1alpha1.OperatingState(src.Status.OperatingState),
+		Conditions:     src.Status.Conditions,
+	}
+	if src.Status.DeviceProperties != nil {
+		dst.Status.DeviceProperties = make(map[string]v1alpha1.ActualPropertyState, len(src.Status.DeviceProperties))
+		for k, v := range src.Status.DeviceProperties {
+			aps := v1alpha1.ActualPropertyState{
+				Name:        v.Name,
+				GetURL:      v.GetURL,
+				ActualValue: v.ActualValue,
+				Timestamp:   v.Timestamp,
+				ValueType:   v.ValueType,
+				MediaType:   v.MediaType,
+				ObjectValue: v.ObjectValue,
+				Unknown:     v.Unknown,
+			}
+			if v.BinaryValue != nil {
+				aps.BinaryValue = &v1alpha1.BinaryValue{
+					SHA256: v.BinaryValue.SHA256,
+					Size:   v.BinaryValue.Size,
+					Data:   v.BinaryValue.Data,
+				}
+				if v.BinaryValue.ValueFrom != nil {
+					vf := v1alpha1.BinaryValueSource(*v.BinaryValue.ValueFrom)
+					aps.BinaryValue.ValueFrom = &vf
+				}
+			}
+			dst.Status.DeviceProperties[k] = aps
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this Device
+func (dst *Device) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.Device)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	// the location is a JSON value in v1alpha2, the annotation is set again when it is converted back
+	deleteAnnotation(&dst.ObjectMeta, v1alpha1.EdgeXLocationFormat)
+
+	dst.Spec = DeviceSpec{
+		Description:    src.Spec.Description,
+		AdminState:     AdminState(src.Spec.AdminState),
+		OperatingState: OperatingState(src.Spec.OperatingState),
+		Labels:         src.Spec.Labels,
+		Location:       toJSONValue(src.Spec.Location, src.Annotations[v1alpha1.EdgeXLocationFormat] == v1alpha1.EdgeXLocationFormatJSON),
+		Service:        src.Spec.Service,
+		Profile:        src.Spec.Profile,
+		Notify:         src.Spec.Notify,
+		Managed:        src.Spec.Managed,
+		NodePool:       src.Spec.NodePool,
+	}
+	if src.Spec.Protocols != nil {
+		dst.Spec.Protocols = make(map[string]ProtocolProperties, len(src.Spec.Protocols))
+		for k, v := range src.Spec.Protocols {
+			dst.Spec.Protocols[k] = ProtocolProperties(v)
+		}
+	}
+	if src.Spec.AutoEvents != nil {
+		dst.Spec.AutoEvents = make([]AutoEvent, 0, len(src.Spec.AutoEvents))
+		for _, ae := range src.Spec.AutoEvents {
+			dst.Spec.AutoEvents = append(dst.Spec.AutoEvents, AutoEvent(ae))
+		}
+	}
+	if src.Spec.DeviceProperties != nil {
+		dst.Spec.DeviceProperties = make(map[string]DesiredPropertyState, len(src.Spec.DeviceProperties))
+		for k, v := range src.Spec.DeviceProperties {
+			dst.Spec.DeviceProperties[k] = DesiredPropertyState(v)
+		}
+	}
+
+	dst.Status = DeviceStatus{
+		LastConnected:  src.Status.LastConnected,
+		LastReported:   src.Status.LastReported,
+		Synced:         src.Status.Synced,
+		EdgeId:         src.Status.EdgeId,
+		AdminState:     AdminState(src.Status.AdminState),
+		OperatingState: OperatingState(src.Status.OperatingState),
+		Conditions:     src.Status.Conditions,
+	}
+	if src.Status.DeviceProperties != nil {
+		dst.Status.DeviceProperties = make(map[string]ActualPropertyState, len(src.Status.DeviceProperties))
+		for k, v := range src.Status.DeviceProperties {
+			aps := ActualPropertyState{
+				Name:        v.Name,
+				GetURL:      v.GetURL,
+				ActualValue: v.ActualValue,
+				Timestamp:   v.Timestamp,
+				ValueType:   v.ValueType,
+				MediaType:   v.MediaType,
+				ObjectValue: v.ObjectValue,
+				Unknown:     v.Unknown,
+			}
+			if v.BinaryValue != nil {
+				aps.BinaryValue = &BinaryValue{
+					SHA256: v.BinaryValue.SHA256,
+					Size:   v.BinaryValue.Size,
+					Data:   v.BinaryValue.Data,
+				}
+				if v.BinaryValue.ValueFrom != nil {
+					vf := BinaryValueSource(*v.BinaryValue.ValueFrom)
+					aps.BinaryValue.ValueFrom = &vf
+				}
+			}
+			dst.Status.DeviceProperties[k] = aps
+		}
+	}
+	return nil
+}
+
+// ConvertTo converts this DeviceProfile to the Hub version (v1alpha1)
+func (src *DeviceProfile) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.DeviceProfile)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = v1alpha1.DeviceProfileSpec{
+		NodePool:     src.Spec.NodePool,
+		Description:  src.Spec.Description,
+		Manufacturer: src.Spec.Manufacturer,
+		Model:        src.Spec.Model,
+		Labels:       src.Spec.Labels,
+		Managed:      src.Spec.Managed,
+	}
+	typed := map[string][]string{}
+	if src.Spec.DeviceResources != nil {
+		dst.Spec.DeviceResources = make([]v1alpha1.DeviceResource, 0, len(src.Spec.DeviceResources))
+		for _, dr := range src.Spec.DeviceResources {
+			attrs, typedAttrs, err := fromJSONAttributes(dr.Attributes)
+			if err != nil {
+				return err
+			}
+			if len(typedAttrs) != 0 {
+				typed[dr.Name] = typedAttrs
+			}
+			dst.Spec.DeviceResources = append(dst.Spec.DeviceResources, v1alpha1.DeviceResource{
+				Description: dr.Description,
+				Name:        dr.Name,
+				Tag:         dr.Tag,
+				IsHidden:    dr.IsHidden,
+				Properties:  v1alpha1.ResourceProperties(dr.Properties),
+				Attributes:  attrs,
+			})
+		}
+	}
+	if len(typed) != 0 {
+		b, err := json.Marshal(typed)
+		if err != nil {
+			return err
+		}
+		setAnnotation(&dst.ObjectMeta, v1alpha1.EdgeXTypedAttributes, string(b))
+	} else {
+		deleteAnnotation(&dst.ObjectMeta, v1alpha1.EdgeXTypedAttributes)
+	}
+	if src.Spec.DeviceCommands != nil {
+		dst.Spec.DeviceCommands = make([]v1alpha1.DeviceCommand, 0, len(src.Spec.DeviceCommands))
+		for _, dc := range src.Spec.DeviceCommands {
+			cmd := v1alpha1.DeviceCommand{
+				Name:      dc.Name,
+				IsHidden:  dc.IsHidden,
+				ReadWrite: dc.ReadWrite,
+			}
+			if dc.ResourceOperations != nil {
+				cmd.ResourceOperations = make([]v1alpha1.ResourceOperation, 0, len(dc.ResourceOperations))
+				for _, ro := range dc.ResourceOperations {
+					cmd.ResourceOperations = append(cmd.ResourceOperations, v1alpha1.ResourceOperation(ro))
+				}
+			}
+			dst.Spec.DeviceCommands = append(dst.Spec.DeviceCommands, cmd)
+		}
+	}
+
+	dst.Status = v1alpha1.DeviceProfileStatus(src.Status)
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this DeviceProfile
+func (dst *DeviceProfile) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.DeviceProfile)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	deleteAnnotation(&dst.ObjectMeta, v1alpha1.EdgeXTypedAttributes)
+
+	dst.Spec = DeviceProfileSpec{
+		NodePool:     src.Spec.NodePool,
+		Description:  src.Spec.Description,
+		Manufacturer: src.Spec.Manufacturer,
+		Model:        src.Spec.Model,
+		Labels:       src.Spec.Labels,
+		Managed:      src.Spec.Managed,
+	}
+	// the typed attributes are kept as strings if the annotation is broken
+	var typed map[string][]string
+	_ = json.Unmarshal([]byte(src.Annotations[v1alpha1.EdgeXTypedAttributes]), &typed)
+	if src.Spec.DeviceResources != nil {
+		dst.Spec.DeviceResources = make([]DeviceResource, 0, len(src.Spec.DeviceResources))
+		for _, dr := range src.Spec.DeviceResources {
+			dst.Spec.DeviceResources = append(dst.Spec.DeviceResources, DeviceResource{
+				Description: dr.Description,
+				Name:        dr.Name,
+				Tag:         dr.Tag,
+				IsHidden:    dr.IsHidden,
+				Properties:  ResourceProperties(dr.Properties),
+				Attributes:  toJSONAttributes(dr.Attributes, typed[dr.Name]),
+			})
+		}
+	}
+	if src.Spec.DeviceCommands != nil {
+		dst.Spec.DeviceCommands = make([]DeviceCommand, 0, len(src.Spec.DeviceCommands))
+		for _, dc := range src.Spec.DeviceCommands {
+			cmd := DeviceCommand{
+				Name:      dc.Name,
+				IsHidden:  dc.IsHidden,
+				ReadWrite: dc.ReadWrite,
+			}
+			if dc.ResourceOperations != nil {
+				cmd.ResourceOperations = make([]ResourceOperation, 0, len(dc.ResourceOperations))
+				for _, ro := range dc.ResourceOperations {
+					cmd.ResourceOperations = append(cmd.ResourceOperations, ResourceOperation(ro))
+				}
+			}
+			dst.Spec.DeviceCommands = append(dst.Spec.DeviceCommands, cmd)
+		}
+	}
+
+	dst.Status = DeviceProfileStatus(src.Status)
+	return nil
+}
+
+// fromJSONValue converts the JSON value to its v1alpha1 string, the value which is not a string
+// is kept as its JSON encoding and isJSON is true
+func fromJSONValue(v *apiextensionsv1.JSON) (s string, isJSON bool, err error) {
+	if v == nil || len(v.Raw) == 0 {
+		return "", false, nil
+	}
+	// the JSON null is decoded to an empty string without error, so only the strings are decoded
+	if raw := bytes.TrimSpace(v.Raw); len(raw) != 0 && raw[0] == '"' {
+		err := json.Unmarshal(raw, &s)
+		return s, false, err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, v.Raw); err != nil {
+		return "", false, err
+	}
+	return buf.String(), true, nil
+}
+
+// toJSONValue converts the v1alpha1 string to the JSON value, the string is decoded if it is marked as JSON,
+// an empty string is converted to nil
+func toJSONValue(s string, isJSON bool) *apiextensionsv1.JSON {
+	if s == "" {
+		return nil
+	}
+	if isJSON && json.Valid([]byte(s)) {
+		return &apiextensionsv1.JSON{Raw: []byte(s)}
+	}
+	b, _ := json.Marshal(s)
+	return &apiextensionsv1.JSON{Raw: b}
+}
+
+// fromJSONAttributes converts the attributes to the v1alpha1 strings and returns the keys of the typed attributes in order
+func fromJSONAttributes(attrs map[string]apiextensionsv1.JSON) (map[string]string, []string, error) {
+	if attrs == nil {
+		return nil, nil, nil
+	}
+	ret := make(map[string]string, len(attrs))
+	var typed []string
+	for k := range attrs {
+		v := attrs[k]
+		s, isJSON, err := fromJSONValue(&v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(v.Raw) == 0 {
+			// an empty value is the JSON null
+			s, isJSON = "null", true
+		}
+		ret[k] = s
+		if isJSON {
+			typed = append(typed, k)
+		}
+	}
+	sort.Strings(typed)
+	return ret, typed, nil
+}
+
+// toJSONAttributes converts the v1alpha1 attributes to the JSON values, the typed attributes are decoded
+func toJSONAttributes(attrs map[string]string, typed []string) map[string]apiextensionsv1.JSON {
+	if attrs == nil {
+		return nil
+	}
+	isTyped := make(map[string]bool, len(typed))
+	for _, k := range typed {
+		isTyped[k] = true
+	}
+	ret := make(map[string]apiextensionsv1.JSON, len(attrs))
+	for k, v := range attrs {
+		if isTyped[k] && json.Valid([]byte(v)) {
+			ret[k] = apiextensionsv1.JSON{Raw: []byte(v)}
+			continue
+		}
+		b, _ := json.Marshal(v)
+		ret[k] = apiextensionsv1.JSON{Raw: b}
+	}
+	return ret
+}
+
+func setAnnotation(meta *metav1.ObjectMeta, key, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = value
+}
+
+func deleteAnnotation(meta *metav1.ObjectMeta, key string) {
+	delete(meta.Annotations, key)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	deviceV1alpha2 = `{"apiVersion":"device.openyurt.io/v1alpha2","kind":"Device",
+		"metadata":{"name":"modbus-01","namespace":"default","annotations":{"floor":"3"}},
+		"spec":{"location":{"building":"A","floor":3},"serviceName":"device-modbus","profileName":"Modbus-Device","notify":true,
+		"protocols":{"modbus-tcp":{"Address":"10.0.0.1","Port":"502"}},"autoEvents":[{"interval":"1s","sourceName":"Temperature"}],
+		"deviceProperties":{"Temperature":{"name":"Temperature","desiredValue":"20"}}},
+		"status":{"edgeId":"1","deviceProperties":{"Snapshot":{"name":"Snapshot","actualValue":"sha256:00","valueType":"Binary",
+		"binaryValue":{"sha256":"00","size":1,"valueFrom":{"kind":"Secret","name":"modbus-01-snapshot","key":"value"}}}}}}`
+	deviceProfileV1alpha2 = `{"apiVersion":"device.openyurt.io/v1alpha2","kind":"DeviceProfile",
+		"metadata":{"name":"modbus-device","namespace":"default"},
+		"spec":{"deviceResources":[
+			{"name":"Temperature","description":"","isHidden":false,"properties":{"valueType":"Float32"},
+			"attributes":{"primaryTable":"HOLDING_REGISTERS","startingAddress":1,"rawType":"1","scale":0.1,"bits":[1,2],"unit":null}},
+			{"name":"Humidity","description":"","isHidden":true,"properties":{"valueType":"Int16"},"attributes":{"primaryTable":"INPUT_REGISTERS"}}],
+		"deviceCommands":[{"name":"Climate","isHidden":false,"readWrite":"RW","resourceOperations":[{"deviceResource":"Temperature","defaultValue":""}]}]},
+		"status":{"id":"1","synced":true}}`
+)
+
+func TestDeviceConversion(t *testing.T) {
+	var d Device
+	assert.NoError(t, json.Unmarshal([]byte(deviceV1alpha2), &d))
+
+	var hub v1alpha1.Device
+	assert.NoError(t, d.ConvertTo(&hub))
+	assert.Equal(t, `{"building":"A","floor":3}`, hub.Spec.Location)
+	assert.Equal(t, v1alpha1.EdgeXLocationFormatJSON, hub.Annotations[v1alpha1.EdgeXLocationFormat])
+	assert.Equal(t, "3", hub.Annotations["floor"])
+	assert.Equal(t, "modbus-01-snapshot", hub.Status.DeviceProperties["Snapshot"].BinaryValue.ValueFrom.Name)
+
+	var back Device
+	assert.NoError(t, back.ConvertFrom(&hub))
+	// the type meta is set by the webhook
+	back.TypeMeta = d.TypeMeta
+	assert.Equal(t, d, back)
+
+	// a string location is not marked as JSON
+	hub.Annotations[v1alpha1.EdgeXLocationFormat] = "json"
+	d.Spec.Location.Raw = []byte(`"building A"`)
+	assert.NoError(t, d.ConvertTo(&hub))
+	assert.Equal(t, "building A", hub.Spec.Location)
+	assert.NotContains(t, hub.Annotations, v1alpha1.EdgeXLocationFormat)
+
+	// the location of v1alpha1 which is not marked as JSON is a JSON string
+	hub.Spec.Location = `{"building":"A"}`
+	assert.NoError(t, back.ConvertFrom(&hub))
+	assert.Equal(t, `"{\"building\":\"A\"}"`, string(back.Spec.Location.Raw))
+}
+
+func TestDeviceProfileConversion(t *testing.T) {
+	var dp DeviceProfile
+	assert.NoError(t, json.Unmarshal([]byte(deviceProfileV1alpha2), &dp))
+
+	var hub v1alpha1.DeviceProfile
+	assert.NoError(t, dp.ConvertTo(&hub))
+	assert.Equal(t, map[string]string{
+		"primaryTable": "HOLDING_REGISTERS", "startingAddress": "1", "rawType": "1", "scale": "0.1", "bits": "[1,2]", "unit": "null",
+	}, hub.Spec.DeviceResources[0].Attributes)
+	assert.Equal(t, `{"Temperature":["bits","scale","startingAddress","unit"]}`, hub.Annotations[v1alpha1.EdgeXTypedAttributes])
+	assert.True(t, hub.Spec.DeviceResources[1].IsHidden)
+
+	var back DeviceProfile
+	assert.NoError(t, back.ConvertFrom(&hub))
+	back.TypeMeta = dp.TypeMeta
+	assertJSONEqual(t, dp, back)
+}
+
+func TestDeviceProfileConversionFromHub(t *testing.T) {
+	hub := v1alpha1.DeviceProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "modbus-device",
+			Annotations: map[string]string{v1alpha1.EdgeXTypedAttributes: `{"Temperature":["startingAddress","broken"]}`},
+		},
+		Spec: v1alpha1.DeviceProfileSpec{DeviceResources: []v1alpha1.DeviceResource{{
+			Name:       "Temperature",
+			Attributes: map[string]string{"startingAddress": "1", "rawType": "1", "broken": "{"},
+		}}},
+	}
+	var dp DeviceProfile
+	assert.NoError(t, dp.ConvertFrom(&hub))
+	attrs := dp.Spec.DeviceResources[0].Attributes
+	assert.Equal(t, `1`, string(attrs["startingAddress"].Raw))
+	assert.Equal(t, `"1"`, string(attrs["rawType"].Raw))
+	// the typed attribute which is not valid JSON is kept as a string
+	assert.Equal(t, `"{"`, string(attrs["broken"].Raw))
+
+	var back v1alpha1.DeviceProfile
+	assert.NoError(t, dp.ConvertTo(&back))
+	assert.Equal(t, hub.Spec, back.Spec)
+	assert.Equal(t, `{"Temperature":["startingAddress"]}`, back.Annotations[v1alpha1.EdgeXTypedAttributes])
+}
+
+// assertJSONEqual compares the objects by their JSON encoding, on which the equal JSON values have the same form
+func assertJSONEqual(t *testing.T, expected, actual interface{}) {
+	e, err := json.Marshal(expected)
+	assert.NoError(t, err)
+	a, err := json.Marshal(actual)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(e), string(a))
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+type AdminState string
+
+const (
+	Locked   AdminState = "LOCKED"
+	UnLocked AdminState = "UNLOCKED"
+)
+
+type OperatingState string
+
+const (
+	Unknown OperatingState = "UNKNOWN"
+	Up      OperatingState = "UP"
+	Down    OperatingState = "DOWN"
+)
+
+type ProtocolProperties map[string]string
+
+// DeviceSpec defines the desired state of Device
+type DeviceSpec struct {
+	// Information describing the device
+	Description string `json:"description,omitempty"`
+	// Admin state (locked/unlocked)
+	AdminState AdminState `json:"adminState,omitempty"`
+	// Operating state (enabled/disabled)
+	OperatingState OperatingState `json:"operatingState,omitempty"`
+	// A map of supported protocols for the given device
+	Protocols map[string]ProtocolProperties `json:"protocols,omitempty"`
+	// Other labels applied to the device to help with searching
+	Labels []string `json:"labels,omitempty"`
+	// Device service specific location, it can be any JSON value
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Location *apiextensionsv1.JSON `json:"location,omitempty"`
+	// Associated Device Service - One per device
+	Service string `json:"serviceName"`
+	// Associated Device Profile - Describes the device
+	Profile string `json:"profileName"`
+	// Notify asks EdgeX to notify the device service of the changes of the device, it is only sent to EdgeX
+	// if it is true and it can't round-trip, EdgeX never returns it so the devices synchronized from EdgeX leave it unset
+	Notify bool `json:"notify"`
+	// True means device is managed by cloud, cloud can update the related fields
+	// False means cloud can't update the fields
+	Managed bool `json:"managed,omitempty"`
+	// NodePool indicates which nodePool the device comes from
+	NodePool string `json:"nodePool,omitempty"`
+	// A list of auto-generated events coming from the device
+	AutoEvents []AutoEvent `json:"autoEvents,omitempty"`
+	// DeviceProperties represents the expected state of the device's properties
+	DeviceProperties map[string]DesiredPropertyState `json:"deviceProperties,omitempty"`
+}
+
+// AutoEvent supports auto-generated events sourced from a device service
+type AutoEvent struct {
+	// Interval indicates how often the specific resource needs to be polled.
+	// It is represented as a duration string, such as "30s" or "1m"
+	Interval string `json:"interval"`
+	// OnChange indicates whether the device service will generate an event only
+	// if the reading value is different from the previous one
+	OnChange bool `json:"onChange,omitempty"`
+	// SourceName is the name of the resource in the deviceProfile which describes the event to generate
+	SourceName string `json:"sourceName"`
+}
+
+type DesiredPropertyState struct {
+	Name         string `json:"name"`
+	PutURL       string `json:"putURL,omitempty"`
+	DesiredValue string `json:"desiredValue,omitempty"`
+	// DesiredValues are the values of the parameters of a command which sets several resources at once,
+	// keyed by the resource names. DesiredValue is ignored if it is set
+	DesiredValues map[string]string `json:"desiredValues,omitempty"`
+}
+
+type ActualPropertyState struct {
+	Name   string `json:"name"`
+	GetURL string `json:"getURL,omitempty"`
+	// ActualValue is the value in the form of a string, an object value is serialized as JSON,
+	// and a binary value is represented by its hash, e.g. "sha256:<hex>"
+	ActualValue string `json:"actualValue"`
+	// Time (nanoseconds) that the actual value was first read by the device, reading the same value again keeps it
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// ValueType is the type of the value reported by the device, e.g. "Float32", "Object" and "Binary"
+	ValueType string `json:"valueType,omitempty"`
+	// MediaType is the media type of a binary value, e.g. "image/jpeg"
+	MediaType string `json:"mediaType,omitempty"`
+	// ObjectValue is the structured value of an object reading
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	ObjectValue *runtime.RawExtension `json:"objectValue,omitempty"`
+	// BinaryValue describes the value of a binary reading
+	// +optional
+	BinaryValue *BinaryValue `json:"binaryValue,omitempty"`
+	// Unknown means the actual value is not known, e.g. the property is read with ds-returnevent=false
+	// and the reading is not returned, the value fields are empty then
+	// +optional
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// BinaryValue describes a binary value read from the device, the value itself is not kept in the status
+type BinaryValue struct {
+	// SHA256 is the hex encoded SHA-256 hash of the value
+	SHA256 string `json:"sha256"`
+	// Size is the number of bytes of the value
+	Size int64 `json:"size"`
+	// ValueFrom refers to the object which stores the value, it is not set if the value is not stored,
+	// e.g. the value is larger than the size limit
+	// +optional
+	ValueFrom *BinaryValueSource `json:"valueFrom,omitempty"`
+	// Data is the value itself, it is handed over by the edge platform client to be stored and never persisted in the status
+	Data []byte `json:"-"`
+}
+
+// BinaryValueSource refers to the key of a Secret or ConfigMap in the namespace of the device which stores a binary value
+type BinaryValueSource struct {
+	// Kind is either "Secret" or "ConfigMap"
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// DeviceStatus defines the observed state of Device
+type DeviceStatus struct {
+	// Time (milliseconds) that the device last provided any feedback or
+	// responded to any request
+	LastConnected int64 `json:"lastConnected,omitempty"`
+	// Time (milliseconds) that the device reported data to the core
+	// microservice
+	LastReported int64 `json:"lastReported,omitempty"`
+	// Synced indicates whether the device already exists on both OpenYurt and edge platform
+	Synced bool `json:"synced,omitempty"`
+	// it represents the actual state of the device's properties
+	DeviceProperties map[string]ActualPropertyState `json:"deviceProperties,omitempty"`
+	EdgeId           string                         `json:"edgeId,omitempty"`
+	// Admin state (locked/unlocked)
+	AdminState AdminState `json:"adminState,omitempty"`
+	// Operating state (up/down/unknown)
+	OperatingState OperatingState `json:"operatingState,omitempty"`
+	// current device state
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dev
+//+kubebuilder:printcolumn:name="NODEPOOL",type="string",JSONPath=".spec.nodePool",description="The nodepool of device"
+//+kubebuilder:printcolumn:name="SYNCED",type="boolean",JSONPath=".status.synced",description="The synced status of device"
+//+kubebuilder:printcolumn:name="MANAGED",type="boolean",priority=1,JSONPath=".spec.managed",description="The managed status of device"
+//+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Device is the Schema for the devices API
+type Device struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DeviceSpec   `json:"spec,omitempty"`
+	Status DeviceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DeviceList contains a list of Device
+type DeviceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Device `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Device{}, &DeviceList{})
+}
@@ -21,10 +21,14 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/openyurtio/device-controller/pkg/clients"
+	// register the edge platform drivers
+	_ "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	devicev1alpha2 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha2"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -49,6 +53,7 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(devicev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(devicev1alpha2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -84,6 +89,7 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 		LeaderElection:         opts.EnableLeaderElection,
 		LeaderElectionID:       "yurt-device-controller",
 		Namespace:              opts.Namespace,
+		Port:                   opts.WebhookPort,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -99,7 +105,7 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 
 	// register the field indexers
 	setupLog.Info("[preflight] Registering the field indexers")
-	if err := util.RegisterFieldIndexers(mgr.GetFieldIndexer()); err != nil {
+	if err := util.RegisterFieldIndexers(mgr.GetFieldIndexer(), controllers.EdgeXObjectName); err != nil {
 		setupLog.Error(err, "failed to register field indexers")
 		os.Exit(1)
 	}
@@ -113,15 +119,33 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 		}
 	}
 
+	// create the clients of the edge platform by the selected driver, the driver connects to the edge platform once
+	// and derives all the clients from the connection, which are shared by the reconcilers, syncers and pollers below
+	edgeConfig := opts.EdgePlatformConfig()
+	if key, ok, _ := opts.EdgeAuthSecretKey(); ok {
+		// the Secret is read by the uncached reader, since the cache is not started yet
+		// and may not cover the namespace of the Secret
+		edgeConfig.Credentials = util.SecretCredentials(mgr.GetAPIReader(), key)
+	}
+	if key, ok, _ := opts.EdgeTLSSecretKey(); ok {
+		edgeConfig.TLS = util.SecretTLS(mgr.GetAPIReader(), key)
+	}
+	edgeClients, err := clients.NewEdgePlatformClients(opts.EdgePlatform, edgeConfig)
+	if err != nil {
+		setupLog.Error(err, "failed to create the edge platform clients", "edgePlatform", opts.EdgePlatform)
+		os.Exit(1)
+	}
+
 	// setup the DeviceProfile Reconciler and Syncer
 	if err = (&controllers.DeviceProfileReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		DeviceProfileCli: edgeClients.DeviceProfileCli,
 	}).SetupWithManager(mgr, opts); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DeviceProfile")
 		os.Exit(1)
 	}
-	dfs, err := controllers.NewDeviceProfileSyncer(mgr.GetClient(), opts)
+	dfs, err := controllers.NewDeviceProfileSyncer(mgr.GetClient(), edgeClients, opts)
 	if err != nil {
 		setupLog.Error(err, "unable to create syncer", "syncer", "DeviceProfile")
 		os.Exit(1)
@@ -134,13 +158,14 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 
 	// setup the Device Reconciler and Syncer
 	if err = (&controllers.DeviceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		DeviceCli: edgeClients.DeviceCli,
 	}).SetupWithManager(mgr, opts); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Device")
 		os.Exit(1)
 	}
-	ds, err := controllers.NewDeviceSyncer(mgr.GetClient(), opts)
+	ds, err := controllers.NewDeviceSyncer(mgr.GetClient(), edgeClients.DeviceCli, opts)
 	if err != nil {
 		setupLog.Error(err, "unable to create syncer", "controller", "Device")
 		os.Exit(1)
@@ -150,16 +175,27 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 		setupLog.Error(err, "unable to create syncer runnable", "syncer", "Device")
 		os.Exit(1)
 	}
+	pp, err := controllers.NewPropertyPoller(mgr.GetClient(), edgeClients.DeviceCli, opts)
+	if err != nil {
+		setupLog.Error(err, "unable to create poller", "poller", "Property")
+		os.Exit(1)
+	}
+	err = mgr.Add(pp.NewPropertyPollerRunnable())
+	if err != nil {
+		setupLog.Error(err, "unable to create poller runnable", "poller", "Property")
+		os.Exit(1)
+	}
 
 	// setup the DeviceService Reconciler and Syncer
 	if err = (&controllers.DeviceServiceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		DeviceServiceCli: edgeClients.DeviceServiceCli,
 	}).SetupWithManager(mgr, opts); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DeviceService")
 		os.Exit(1)
 	}
-	dss, err := controllers.NewDeviceServiceSyncer(mgr.GetClient(), opts)
+	dss, err := controllers.NewDeviceServiceSyncer(mgr.GetClient(), edgeClients.DeviceServiceCli, opts)
 	if err != nil {
 		setupLog.Error(err, "unable to create syncer", "syncer", "DeviceService")
 		os.Exit(1)
@@ -169,6 +205,17 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 		setupLog.Error(err, "unable to create syncer runnable", "syncer", "DeviceService")
 		os.Exit(1)
 	}
+	// setup the webhook which converts the devices and deviceProfiles between v1alpha1 and v1alpha2
+	if opts.EnableConversionWebhook {
+		if err = (&devicev1alpha2.Device{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Device")
+			os.Exit(1)
+		}
+		if err = (&devicev1alpha2.DeviceProfile{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DeviceProfile")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
@@ -180,7 +227,7 @@ func Run(opts *options.YurtDeviceControllerOptions, stopCh <-chan struct{}) {
 		os.Exit(1)
 	}
 
-	setupLog.Info("[run controllers] Starting manager, acting on " + fmt.Sprintf("[NodePool: %s, Namespace: %s]", opts.Nodepool, opts.Namespace))
+	setupLog.Info("[run controllers] Starting manager, acting on " + fmt.Sprintf("[EdgePlatform: %s, NodePool: %s, Namespace: %s]", opts.EdgePlatform, opts.Nodepool, opts.Namespace))
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "failed to running manager")
 		os.Exit(1)
@@ -19,41 +19,106 @@ package options
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
 
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // YurtDeviceControllerOptions is the main settings for the yurt-device-controller
 type YurtDeviceControllerOptions struct {
-	MetricsAddr          string
-	ProbeAddr            string
-	EnableLeaderElection bool
-	Nodepool             string
-	Namespace            string
-	CoreDataAddr         string
-	CoreMetadataAddr     string
-	CoreCommandAddr      string
-	EdgeSyncPeriod       uint
+	MetricsAddr                string
+	ProbeAddr                  string
+	EnableLeaderElection       bool
+	Nodepool                   string
+	Namespace                  string
+	EdgePlatform               string
+	CoreDataAddr               string
+	CoreMetadataAddr           string
+	CoreCommandAddr            string
+	EdgeSyncPeriod             uint
+	EdgeRequestTimeout         uint
+	EdgeMetadataTimeout        uint
+	EdgeCommandTimeout         uint
+	EdgeSyncTimeout            uint
+	EdgeAPIVersion             string
+	EdgeAuthSecret             string
+	EdgeCAFile                 string
+	EdgeCertFile               string
+	EdgeKeyFile                string
+	EdgeTLSSecret              string
+	EdgeRequestHeaders         map[string]string
+	EdgeSyncSelector           string
+	ConcurrentDeviceReconciles uint
+	PropertyPollPeriod         uint
+	PropertyPollQPS            float64
+	ConcurrentPropertyPolls    uint
+	PropertySource             string
+	BinaryValueStore           string
+	BinaryValueSizeLimit       uint
+	EnableConversionWebhook    bool
+	WebhookPort                int
 }
 
 func NewYurtDeviceControllerOptions() *YurtDeviceControllerOptions {
 	return &YurtDeviceControllerOptions{
-		MetricsAddr:          ":8080",
-		ProbeAddr:            ":8080",
-		EnableLeaderElection: false,
-		Nodepool:             "",
-		Namespace:            "default",
-		CoreDataAddr:         "edgex-core-data:59880",
-		CoreMetadataAddr:     "edgex-core-metadata:59881",
-		CoreCommandAddr:      "edgex-core-command:59882",
-		EdgeSyncPeriod:       5,
+		MetricsAddr:                ":8080",
+		ProbeAddr:                  ":8080",
+		EnableLeaderElection:       false,
+		Nodepool:                   "",
+		Namespace:                  "default",
+		EdgePlatform:               "edgex",
+		CoreDataAddr:               "edgex-core-data:59880",
+		CoreMetadataAddr:           "edgex-core-metadata:59881",
+		CoreCommandAddr:            "edgex-core-command:59882",
+		EdgeSyncPeriod:             5,
+		EdgeRequestTimeout:         10,
+		EdgeMetadataTimeout:        30,
+		EdgeCommandTimeout:         30,
+		EdgeSyncTimeout:            120,
+		EdgeAPIVersion:             clients.APIVersionAuto,
+		ConcurrentDeviceReconciles: 5,
+		PropertyPollPeriod:         30,
+		PropertyPollQPS:            10,
+		ConcurrentPropertyPolls:    5,
+		PropertySource:             string(clients.PropertySourceDevice),
+		BinaryValueSizeLimit:       512 * 1024,
+		WebhookPort:                9443,
 	}
 }
 
 func ValidateOptions(options *YurtDeviceControllerOptions) error {
+	if err := ValidateEdgePlatform(options); err != nil {
+		return err
+	}
 	if err := ValidateEdgePlatformAddress(options); err != nil {
 		return err
 	}
+	if _, err := clients.ParseSelector(options.EdgeSyncSelector); err != nil {
+		return fmt.Errorf("invalid edge-sync-label-selector: %s", err)
+	}
+	if ps := clients.PropertySource(options.PropertySource); ps != clients.PropertySourceDevice && ps != clients.PropertySourceReadings {
+		return fmt.Errorf("invalid property-source: %q, it must be %q or %q", options.PropertySource, clients.PropertySourceDevice, clients.PropertySourceReadings)
+	}
+	if options.BinaryValueStore != "" && options.BinaryValueStore != "Secret" && options.BinaryValueStore != "ConfigMap" {
+		return fmt.Errorf("invalid binary-value-store: %q, it must be empty, \"Secret\" or \"ConfigMap\"", options.BinaryValueStore)
+	}
+	if options.EnableConversionWebhook && (options.WebhookPort <= 0 || options.WebhookPort > 65535) {
+		return fmt.Errorf("invalid webhook-port: %d", options.WebhookPort)
+	}
+	if _, _, err := options.EdgeAuthSecretKey(); err != nil {
+		return err
+	}
+	if err := ValidateEdgeTLS(options); err != nil {
+		return err
+	}
+	if options.PropertyPollQPS < 0 {
+		return fmt.Errorf("invalid property-poll-qps: %v, it must not be negative", options.PropertyPollQPS)
+	}
 	return nil
 }
 
@@ -63,20 +128,127 @@ func (o *YurtDeviceControllerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.EnableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. "+"Enabling this will ensure there is only one active controller manager.")
 	fs.StringVar(&o.Nodepool, "nodepool", "", "The nodePool deviceController is deployed in.(just for debugging)")
 	fs.StringVar(&o.Namespace, "namespace", "default", "The cluster namespace for edge resources synchronization.")
-	fs.StringVar(&o.CoreDataAddr, "core-data-address", "edgex-core-data:59880", "The address of edge core-data service.")
-	fs.StringVar(&o.CoreMetadataAddr, "core-metadata-address", "edgex-core-metadata:59881", "The address of edge core-metadata service.")
-	fs.StringVar(&o.CoreCommandAddr, "core-command-address", "edgex-core-command:59882", "The address of edge core-command service.")
+	fs.StringVar(&o.EdgePlatform, "edge-platform", o.EdgePlatform, fmt.Sprintf("The edge platform deviceController connects to, registered platforms: %v.", clients.Drivers()))
+	fs.StringVar(&o.CoreDataAddr, "core-data-address", "edgex-core-data:59880", "The address of edge core-data service, e.g. \"https://edgex-core-data:59880\".(http is used if the scheme is omitted)")
+	fs.StringVar(&o.CoreMetadataAddr, "core-metadata-address", "edgex-core-metadata:59881", "The address of edge core-metadata service, e.g. \"https://edgex-core-metadata:59881\".(http is used if the scheme is omitted)")
+	fs.StringVar(&o.CoreCommandAddr, "core-command-address", "edgex-core-command:59882", "The address of edge core-command service, e.g. \"https://edgex-core-command:59882\".(http is used if the scheme is omitted)")
+	fs.UintVar(&o.EdgeRequestTimeout, "edge-request-timeout", 10, "The deadline of each request sent to the edge platform, a retried request gets the deadline again.(in seconds)")
+	fs.UintVar(&o.EdgeMetadataTimeout, "edge-metadata-timeout", o.EdgeMetadataTimeout, "The deadline of creating, getting, updating or deleting an object on the edge platform, including the retries.(in seconds, 0 means no deadline)")
+	fs.UintVar(&o.EdgeCommandTimeout, "edge-command-timeout", o.EdgeCommandTimeout, "The deadline of reading or setting the properties of a device on the edge platform, including the retries.(in seconds, 0 means no deadline)")
+	fs.UintVar(&o.EdgeSyncTimeout, "edge-sync-timeout", o.EdgeSyncTimeout, "The deadline of listing the objects on the edge platform in each synchronization.(in seconds, 0 means no deadline)")
+	fs.StringToStringVar(&o.EdgeRequestHeaders, "edge-request-headers", o.EdgeRequestHeaders, "The headers sent with every request to the edge platform, e.g. the ones required by a gateway, \"Header1=value1,Header2=value2\".")
+	fs.StringVar(&o.EdgeAPIVersion, "edge-api-version", o.EdgeAPIVersion, fmt.Sprintf("The version of the edge platform API, e.g. \"v1\", \"v2\" or \"v3\" for EdgeX, %q detects it at startup.", clients.APIVersionAuto))
+	fs.StringVar(&o.EdgeAuthSecret, "edge-auth-secret", o.EdgeAuthSecret, "The Secret, \"namespace/name\" or a name in the namespace of --namespace, which holds the token or the gateway credentials of the edge platform.(empty means the requests are not authenticated)")
+	fs.StringVar(&o.EdgeCAFile, "edge-ca-file", o.EdgeCAFile, "The CA bundle which verifies the https addresses of the edge platform, it is reloaded once it rotates.(empty means the system CA bundle)")
+	fs.StringVar(&o.EdgeCertFile, "edge-cert-file", o.EdgeCertFile, "The client certificate presented to the https addresses of the edge platform, it is reloaded once it rotates.")
+	fs.StringVar(&o.EdgeKeyFile, "edge-key-file", o.EdgeKeyFile, "The key of the client certificate presented to the https addresses of the edge platform.")
+	fs.StringVar(&o.EdgeTLSSecret, "edge-tls-secret", o.EdgeTLSSecret, "The Secret, \"namespace/name\" or a name in the namespace of --namespace, which holds the \"ca.crt\", \"tls.crt\" and \"tls.key\" used instead of the files.")
 	fs.UintVar(&o.EdgeSyncPeriod, "edge-sync-period", 5, "The period of the device management platform synchronizing the device status to the cloud.(in seconds,not less than 5 seconds)")
+	fs.UintVar(&o.ConcurrentDeviceReconciles, "concurrent-device-reconciles", o.ConcurrentDeviceReconciles, "The number of devices that are allowed to reconcile concurrently, the devices created concurrently are added to the edge platform in batches of up to this number of devices(at most 100), raise it to bring up many devices with fewer requests.")
+	fs.UintVar(&o.PropertyPollPeriod, "property-poll-period", o.PropertyPollPeriod, "The default period of polling the actual properties of the devices, it can be overridden by the poll-interval annotations of the device.(in seconds, 0 means the properties are not polled)")
+	fs.Float64Var(&o.PropertyPollQPS, "property-poll-qps", o.PropertyPollQPS, "The maximum number of requests per second sent to the devices to poll their actual properties.(0 means no limit)")
+	fs.UintVar(&o.ConcurrentPropertyPolls, "concurrent-property-polls", o.ConcurrentPropertyPolls, "The number of devices whose actual properties are allowed to be polled concurrently.")
+	fs.StringVar(&o.PropertySource, "property-source", o.PropertySource, fmt.Sprintf("Where the actual values of the device properties are read from, %q reads the devices through core-command, %q uses the latest readings in core-data.", clients.PropertySourceDevice, clients.PropertySourceReadings))
+	fs.StringVar(&o.BinaryValueStore, "binary-value-store", o.BinaryValueStore, "The kind of objects, \"Secret\" or \"ConfigMap\", which store the binary values read from the devices, the values are not stored if it is empty.")
+	fs.UintVar(&o.BinaryValueSizeLimit, "binary-value-size-limit", o.BinaryValueSizeLimit, "The maximum size of a binary value to be stored.(in bytes)")
+	fs.BoolVar(&o.EnableConversionWebhook, "enable-conversion-webhook", o.EnableConversionWebhook, "Serve the webhook which converts the devices and deviceProfiles between the API versions, it is required to use the v1alpha2 API.")
+	fs.IntVar(&o.WebhookPort, "webhook-port", o.WebhookPort, "The port the conversion webhook binds to.")
+	fs.StringVar(&o.EdgeSyncSelector, "edge-sync-label-selector", "", "Only the objects on the edge platform with these labels are synchronized to the cloud, e.g. \"floor=1,sensor\".(empty means all objects)")
 }
 
 func ValidateEdgePlatformAddress(options *YurtDeviceControllerOptions) error {
 	addrs := []string{options.CoreDataAddr, options.CoreMetadataAddr, options.CoreCommandAddr}
 	for _, addr := range addrs {
-		if addr != "" {
-			if _, _, err := net.SplitHostPort(addr); err != nil {
+		if addr == "" {
+			continue
+		}
+		if strings.Contains(addr, "://") {
+			// the address with a scheme may also carry the path prefix of a gateway
+			u, err := url.Parse(addr)
+			if err != nil {
 				return fmt.Errorf("invalid address: %s", err)
 			}
+			if u.Scheme != "http" && u.Scheme != "https" {
+				return fmt.Errorf("invalid address: %s, the scheme must be http or https", addr)
+			}
+			if u.Host == "" {
+				return fmt.Errorf("invalid address: %s, the host is missing", addr)
+			}
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid address: %s", err)
 		}
 	}
 	return nil
 }
+
+// ValidateEdgeTLS checks that the client certificate comes with its key,
+// and that the TLS material is read either from the files or from the Secret
+func ValidateEdgeTLS(options *YurtDeviceControllerOptions) error {
+	if (options.EdgeCertFile == "") != (options.EdgeKeyFile == "") {
+		return fmt.Errorf("edge-cert-file and edge-key-file must be set together")
+	}
+	if options.EdgeTLSSecret != "" && (options.EdgeCAFile != "" || options.EdgeCertFile != "") {
+		return fmt.Errorf("edge-tls-secret can't be set together with edge-ca-file or edge-cert-file")
+	}
+	_, _, err := options.EdgeTLSSecretKey()
+	return err
+}
+
+func ValidateEdgePlatform(options *YurtDeviceControllerOptions) error {
+	if !clients.IsDriverRegistered(options.EdgePlatform) {
+		return fmt.Errorf("unsupported edge platform: %q, registered platforms: %v", options.EdgePlatform, clients.Drivers())
+	}
+	return nil
+}
+
+// EdgeAuthSecretKey returns the key of the Secret which holds the credentials of the edge platform,
+// and whether the Secret is set
+func (o *YurtDeviceControllerOptions) EdgeAuthSecretKey() (types.NamespacedName, bool, error) {
+	return o.secretKey("edge-auth-secret", o.EdgeAuthSecret)
+}
+
+// EdgeTLSSecretKey returns the key of the Secret which holds the TLS material of the edge platform,
+// and whether the Secret is set
+func (o *YurtDeviceControllerOptions) EdgeTLSSecretKey() (types.NamespacedName, bool, error) {
+	return o.secretKey("edge-tls-secret", o.EdgeTLSSecret)
+}
+
+// secretKey parses the Secret given by the flag as "namespace/name", or a name in the namespace of the controller
+func (o *YurtDeviceControllerOptions) secretKey(flag, secret string) (types.NamespacedName, bool, error) {
+	if secret == "" {
+		return types.NamespacedName{}, false, nil
+	}
+	key := types.NamespacedName{Namespace: o.Namespace, Name: secret}
+	if parts := strings.Split(secret, "/"); len(parts) > 1 {
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return types.NamespacedName{}, false, fmt.Errorf("invalid %s: %q, it must be \"namespace/name\" or a name", flag, secret)
+		}
+		key = types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+	}
+	return key, true, nil
+}
+
+// EdgePlatformConfig returns the settings used by the driver to connect to the edge platform
+func (o *YurtDeviceControllerOptions) EdgePlatformConfig() clients.EdgePlatformConfig {
+	return clients.EdgePlatformConfig{
+		CoreDataAddr:     o.CoreDataAddr,
+		CoreMetadataAddr: o.CoreMetadataAddr,
+		CoreCommandAddr:  o.CoreCommandAddr,
+		RequestTimeout:   time.Duration(o.EdgeRequestTimeout) * time.Second,
+		PropertySource:   clients.PropertySource(o.PropertySource),
+		Namespace:        o.Namespace,
+		APIVersion:       o.EdgeAPIVersion,
+		TLS:              o.edgeFileTLS(),
+		Headers:          o.EdgeRequestHeaders,
+	}
+}
+
+// edgeFileTLS returns the TLS material read from the files, it is nil if none of the files is set
+func (o *YurtDeviceControllerOptions) edgeFileTLS() clients.TLSFunc {
+	if o.EdgeCAFile == "" && o.EdgeCertFile == "" {
+		return nil
+	}
+	return clients.FileTLS(o.EdgeCAFile, o.EdgeCertFile, o.EdgeKeyFile)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EdgePlatformConfig defines the settings used by a driver to connect to the edge platform
+type EdgePlatformConfig struct {
+	CoreDataAddr     string
+	CoreMetadataAddr string
+	CoreCommandAddr  string
+	// RequestTimeout is the deadline of every request sent to the edge platform, 0 means the driver default
+	RequestTimeout time.Duration
+	// PropertySource is where the actual values of the device properties are read from
+	PropertySource PropertySource
+	// Namespace is the namespace of the objects converted from the edge platform
+	Namespace string
+	// APIVersion is the version of the edge platform API the driver speaks, APIVersionAuto detects it at startup,
+	// and the driver default is used if it is empty
+	APIVersion string
+	// Credentials returns the credentials which authenticate the requests to the edge platform,
+	// the requests are not authenticated if it is nil
+	Credentials CredentialsFunc
+	// TLS returns the CA bundle and the client certificate used to connect to the https addresses of the edge platform,
+	// the system CA bundle is used and no client certificate is presented if it is nil
+	TLS TLSFunc
+	// Headers are sent with every request to the edge platform, e.g. the ones required by a gateway
+	Headers map[string]string
+}
+
+// Credentials authenticate the controller to the edge platform, they are either a ready-made bearer token,
+// or the username and password which the driver exchanges for a token at AuthURL
+type Credentials struct {
+	Token    string
+	Username string
+	Password string
+	AuthURL  string
+}
+
+// CredentialsFunc returns the current credentials, it is called again whenever the token is renewed
+// so that the rotated credentials are picked up
+type CredentialsFunc func(ctx context.Context) (*Credentials, error)
+
+// APIVersionAuto lets the driver detect the version of the edge platform API
+const APIVersionAuto = "auto"
+
+// PropertySource is where the actual values of the device properties are read from
+type PropertySource string
+
+const (
+	// PropertySourceDevice reads the actual values from the devices on demand
+	PropertySourceDevice PropertySource = "device"
+	// PropertySourceReadings reads the latest values the devices have reported to the edge platform,
+	// so that no extra load is put on the devices
+	PropertySourceReadings PropertySource = "readings"
+)
+
+// EdgePlatformClients groups the clients which are used to manage the objects on the edge platform
+type EdgePlatformClients struct {
+	DeviceCli        DeviceInterface
+	DeviceServiceCli DeviceServiceInterface
+	DeviceProfileCli DeviceProfileInterface
+	// PreservedAnnotations are the annotations the driver keeps on the objects to convert them back to the edge platform
+	// without loss, they are mirrored along with the specs of the objects synchronized from the edge platform
+	PreservedAnnotations []string
+}
+
+// DriverFactory creates the clients of an edge platform with the given config
+type DriverFactory func(cfg EdgePlatformConfig) (*EdgePlatformClients, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]DriverFactory{}
+)
+
+// RegisterDriver makes an edge platform driver available by the provided name,
+// it panics if the factory is nil or the driver is registered twice
+func RegisterDriver(name string, factory DriverFactory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if factory == nil {
+		panic("clients: the factory of driver " + name + " is nil")
+	}
+	if _, exists := drivers[name]; exists {
+		panic("clients: driver " + name + " is registered twice")
+	}
+	drivers[name] = factory
+}
+
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsDriverRegistered checks whether a driver is registered with the provided name
+func IsDriverRegistered(name string) bool {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	_, exists := drivers[name]
+	return exists
+}
+
+// NewEdgePlatformClients creates the clients of the edge platform by the driver registered with the provided name
+func NewEdgePlatformClients(name string, cfg EdgePlatformConfig) (*EdgePlatformClients, error) {
+	driversMu.RLock()
+	factory, exists := drivers[name]
+	driversMu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown edge platform driver %q (registered drivers: %v)", name, Drivers())
+	}
+	return factory(cfg)
+}
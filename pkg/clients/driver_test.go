@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RegisterDriver(t *testing.T) {
+	var received EdgePlatformConfig
+	RegisterDriver("fake", func(cfg EdgePlatformConfig) (*EdgePlatformClients, error) {
+		received = cfg
+		return &EdgePlatformClients{}, nil
+	})
+	assert.True(t, IsDriverRegistered("fake"))
+	assert.Contains(t, Drivers(), "fake")
+
+	cfg := EdgePlatformConfig{CoreMetadataAddr: "metadata:59881"}
+	cs, err := NewEdgePlatformClients("fake", cfg)
+	assert.Nil(t, err)
+	assert.NotNil(t, cs)
+	assert.Equal(t, cfg, received)
+
+	_, err = NewEdgePlatformClients("unknown", cfg)
+	assert.NotNil(t, err)
+	assert.False(t, IsDriverRegistered("unknown"))
+
+	assert.Panics(t, func() {
+		RegisterDriver("fake", func(cfg EdgePlatformConfig) (*EdgePlatformClients, error) { return nil, nil })
+	})
+	assert.Panics(t, func() { RegisterDriver("nil", nil) })
+}
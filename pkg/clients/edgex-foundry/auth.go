@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/go-resty/resty/v2"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// in secure mode, a user of the EdgeX API gateway logs in to the secret store with the username and password,
+	// and gets the JWT which authenticates the requests sent through the gateway
+	SecretStoreLoginPath = "/v1/auth/userpass/login/%s"
+	SecretStoreTokenPath = "/v1/identity/oidc/token/%s"
+
+	// the token is renewed once this fraction of its lifetime has elapsed
+	tokenRenewFraction = 0.8
+)
+
+// tokenSource provides the JWT attached to the requests sent through the EdgeX API gateway,
+// the token is cached and renewed before it expires
+type tokenSource struct {
+	sync.Mutex
+	credentials clients.CredentialsFunc
+	// client exchanges the username and password for the token
+	client *resty.Client
+	token  string
+	// the token is renewed at renewAt and is not used after expiresAt,
+	// the token without an expiry is kept until it is rejected by EdgeX
+	renewAt   time.Time
+	expiresAt time.Time
+	// now is replaceable for testing
+	now func() time.Time
+}
+
+func newTokenSource(credentials clients.CredentialsFunc) *tokenSource {
+	return &tokenSource{
+		credentials: credentials,
+		client:      resty.New().SetTimeout(DefaultRequestTimeout),
+		now:         time.Now,
+	}
+}
+
+// Token returns the cached token, or a new one if the cached token is due to be renewed.
+// The cached token is still returned if it fails to be renewed before it expires
+func (ts *tokenSource) Token(ctx context.Context) (string, error) {
+	ts.Lock()
+	defer ts.Unlock()
+	now := ts.now()
+	if ts.token != "" && (ts.renewAt.IsZero() || now.Before(ts.renewAt)) {
+		return ts.token, nil
+	}
+	token, err := ts.renew(ctx)
+	if err != nil {
+		if ts.token != "" && now.Before(ts.expiresAt) {
+			klog.V(3).InfoS("failed to renew the EdgeX token, keep using it until it expires", "ExpiresAt", ts.expiresAt, "Error", err.Error())
+			return ts.token, nil
+		}
+		return "", err
+	}
+	ts.token, ts.renewAt, ts.expiresAt = token, time.Time{}, time.Time{}
+	if exp, ok := jwtExpiry(token); ok {
+		ts.expiresAt = exp
+		ts.renewAt = now.Add(time.Duration(float64(exp.Sub(now)) * tokenRenewFraction))
+	}
+	klog.V(4).InfoS("renewed the EdgeX token", "RenewAt", ts.renewAt)
+	return token, nil
+}
+
+// invalidate drops the token rejected by EdgeX, so that the next request renews it
+func (ts *tokenSource) invalidate(token string) {
+	ts.Lock()
+	defer ts.Unlock()
+	if token != "" && token == ts.token {
+		klog.V(3).Info("the EdgeX token is rejected, it will be renewed")
+		ts.token = ""
+	}
+}
+
+// renew reads the current credentials, and exchanges them for a token unless they carry one
+func (ts *tokenSource) renew(ctx context.Context) (string, error) {
+	creds, err := ts.credentials(ctx)
+	if err != nil {
+		return "", &clients.StatusError{
+			Reason:  clients.StatusReasonUnauthorized,
+			Message: fmt.Sprintf("failed to get the credentials of EdgeX: %v", err),
+			Err:     err,
+		}
+	}
+	if creds.Token != "" {
+		return creds.Token, nil
+	}
+	if creds.Username == "" || creds.AuthURL == "" {
+		return "", clients.NewStatusError(clients.StatusReasonUnauthorized, 0,
+			"the credentials of EdgeX have neither a token nor a username and an auth URL", nil)
+	}
+	return ts.exchange(ctx, creds)
+}
+
+// exchange logs in to the EdgeX secret store and gets the JWT of the user
+func (ts *tokenSource) exchange(ctx context.Context, creds *clients.Credentials) (string, error) {
+	authURL := strings.TrimSuffix(creds.AuthURL, "/")
+	user := url.PathEscape(creds.Username)
+	resp, err := ts.client.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]string{"password": creds.Password}).
+		Post(authURL + fmt.Sprintf(SecretStoreLoginPath, user))
+	if err != nil {
+		return "", newRequestError(err, "failed to log in to EdgeX as %s", creds.Username)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return "", newAuthError(resp, "failed to log in to EdgeX as %s", creds.Username)
+	}
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := json.Unmarshal(resp.Body(), &login); err != nil {
+		return "", err
+	}
+
+	resp, err = ts.client.R().SetContext(ctx).
+		SetAuthToken(login.Auth.ClientToken).
+		Get(authURL + fmt.Sprintf(SecretStoreTokenPath, user))
+	if err != nil {
+		return "", newRequestError(err, "failed to get the EdgeX token of %s", creds.Username)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return "", newAuthError(resp, "failed to get the EdgeX token of %s", creds.Username)
+	}
+	var token struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return "", err
+	}
+	if token.Data.Token == "" {
+		return "", clients.NewStatusError(clients.StatusReasonUnauthorized, resp.StatusCode(),
+			fmt.Sprintf("no EdgeX token is returned for %s", creds.Username), resp.Body())
+	}
+	return token.Data.Token, nil
+}
+
+// newAuthError converts the rejected login to the Unauthorized error,
+// the secret store answers the wrong credentials with various client errors
+func newAuthError(resp *resty.Response, format string, args ...interface{}) error {
+	err := newResponseError(resp, format, args...)
+	var statusErr *clients.StatusError
+	if errors.As(err, &statusErr) && resp.StatusCode() < http.StatusInternalServerError {
+		statusErr.Reason = clients.StatusReasonUnauthorized
+	}
+	return err
+}
+
+// jwtExpiry returns the expiry in the exp claim of the JWT, the signature is not verified
+// since the token is only checked by EdgeX
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// sentTokenKey is the key of the token sent with the request in the request context
+type sentTokenKey struct{}
+
+// withAuth attaches the token of ts to every request sent by the client,
+// the token rejected by EdgeX is dropped so that the next request renews it
+func withAuth(c *resty.Client, ts *tokenSource) *resty.Client {
+	return c.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		token, err := ts.Token(r.Context())
+		if err != nil {
+			return err
+		}
+		// the token is kept in the context, so that only the token which is rejected is invalidated,
+		// while another request may have renewed it meanwhile
+		r.SetAuthToken(token).SetContext(context.WithValue(r.Context(), sentTokenKey{}, token))
+		return nil
+	}).OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		if resp.StatusCode() == http.StatusUnauthorized {
+			if token, ok := resp.Request.Context().Value(sentTokenKey{}).(string); ok {
+				ts.invalidate(token)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	SecretStoreAddr  = "http://edgex-secret-store:8200"
+	SecretStoreLogin = `{"auth":{"client_token":"hvs.CAESIJ"}}`
+	AuthRejected     = `{"message":"unauthorized"}`
+)
+
+// testJWT returns an unsigned JWT which expires at exp
+func testJWT(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"edgex-user","exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJFUzM4NCJ9." + payload + ".c2lnbmF0dXJl"
+}
+
+func Test_StaticToken(t *testing.T) {
+	token := testJWT(time.Now().Add(time.Hour))
+	reads := 0
+	ts := newTokenSource(func(ctx context.Context) (*clients.Credentials, error) {
+		reads++
+		return &clients.Credentials{Token: token}, nil
+	})
+	serviceClient := NewEdgexDeviceServiceClient("edgex-core-metadata:59881")
+	withAuth(serviceClient.Client, ts)
+	httpmock.ActivateNonDefault(serviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/deviceservice/name/device-virtual",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "Bearer "+token {
+				return httpmock.NewStringResponse(401, AuthRejected), nil
+			}
+			return httpmock.NewStringResponse(200, DeviceServiceMetaData), nil
+		})
+	for i := 0; i < 2; i++ {
+		_, err := serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+		assert.Nil(t, err)
+	}
+	// the token is cached until it is due to be renewed
+	assert.Equal(t, 1, reads)
+
+	// the rejected token is dropped and read again by the next request
+	token = testJWT(time.Now().Add(2 * time.Hour))
+	ts.Lock()
+	rejected := ts.token
+	ts.Unlock()
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/deviceservice/name/device-virtual",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") == "Bearer "+rejected {
+				return httpmock.NewStringResponse(401, AuthRejected), nil
+			}
+			return httpmock.NewStringResponse(200, DeviceServiceMetaData), nil
+		})
+	_, err := serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.True(t, clients.IsUnauthorizedErr(err))
+	_, err = serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, reads)
+}
+
+func Test_TokenRenewal(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+	ts := newTokenSource(func(ctx context.Context) (*clients.Credentials, error) {
+		return &clients.Credentials{Username: "edgex-user", Password: "secret", AuthURL: SecretStoreAddr + "/"}, nil
+	})
+	ts.now = func() time.Time { return now }
+	httpmock.ActivateNonDefault(ts.client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	token := testJWT(now.Add(10 * time.Minute))
+	httpmock.RegisterResponder("POST", SecretStoreAddr+"/v1/auth/userpass/login/edgex-user",
+		httpmock.NewStringResponder(200, SecretStoreLogin))
+	httpmock.RegisterResponder("GET", SecretStoreAddr+"/v1/identity/oidc/token/edgex-user",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "Bearer hvs.CAESIJ" {
+				return httpmock.NewStringResponse(403, `{"errors":["permission denied"]}`), nil
+			}
+			return httpmock.NewStringResponse(200, fmt.Sprintf(`{"data":{"token":%q,"ttl":600}}`, token)), nil
+		})
+	got, err := ts.Token(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, token, got)
+	assert.Equal(t, now.Add(8*time.Minute), ts.renewAt)
+
+	// the token is renewed once most of its lifetime has elapsed
+	now = now.Add(9 * time.Minute)
+	renewed := testJWT(now.Add(10 * time.Minute))
+	token = renewed
+	got, err = ts.Token(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, renewed, got)
+
+	// the token is still used until it expires if it fails to be renewed
+	now = now.Add(9 * time.Minute)
+	httpmock.RegisterResponder("POST", SecretStoreAddr+"/v1/auth/userpass/login/edgex-user",
+		httpmock.NewStringResponder(400, `{"errors":["invalid username or password"]}`))
+	got, err = ts.Token(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, renewed, got)
+
+	now = now.Add(2 * time.Minute)
+	_, err = ts.Token(context.TODO())
+	assert.True(t, clients.IsUnauthorizedErr(err))
+}
+
+func Test_TokenCredentialsError(t *testing.T) {
+	ts := newTokenSource(func(ctx context.Context) (*clients.Credentials, error) {
+		return nil, errors.New("secrets \"edgex-auth\" not found")
+	})
+	profileClient := NewEdgexDeviceProfile("edgex-core-metadata:59881")
+	withAuth(profileClient.Client, ts)
+	httpmock.ActivateNonDefault(profileClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	// the request is not sent without the token
+	_, err := profileClient.Get(context.TODO(), "Random-Boolean-Device", clients.GetOptions{})
+	assert.True(t, clients.IsUnauthorizedErr(err))
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+
+	_, ok := jwtExpiry("not-a-jwt")
+	assert.False(t, ok)
+}
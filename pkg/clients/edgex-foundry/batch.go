@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
+	"github.com/go-resty/resty/v2"
+)
+
+// parseCreateBatchResponse converts the items of the EdgeX multi-status response to the results of a batch creation,
+// EdgeX responds the items in the same order as the objects in the request
+func parseCreateBatchResponse(resp *resty.Response, kind string, names []string) ([]clients.BatchResult, error) {
+	var edgexResps []*common.BaseWithIdResponse
+	if err := json.Unmarshal(resp.Body(), &edgexResps); err != nil {
+		return nil, err
+	}
+	if len(edgexResps) != len(names) {
+		return nil, fmt.Errorf("edgex BaseWithIdResponse count mismatch %s count, the response is : %s", kind, resp.Body())
+	}
+	results := make([]clients.BatchResult, len(names))
+	for i, item := range edgexResps {
+		results[i].Name = names[i]
+		if item.StatusCode == http.StatusCreated {
+			results[i].EdgeId = item.Id
+		} else {
+			results[i].Err = toCreateError(newItemError(item.BaseResponse, resp, "failed to create %s %s on edgex foundry", kind, names[i]))
+		}
+	}
+	return results, nil
+}
+
+// parseUpdateBatchResponse converts the items of the EdgeX multi-status response to the results of a batch update
+func parseUpdateBatchResponse(resp *resty.Response, kind string, names []string) ([]clients.BatchResult, error) {
+	var edgexResps []*common.BaseResponse
+	if err := json.Unmarshal(resp.Body(), &edgexResps); err != nil {
+		return nil, err
+	}
+	if len(edgexResps) != len(names) {
+		return nil, fmt.Errorf("edgex BaseResponse count mismatch %s count, the response is : %s", kind, resp.Body())
+	}
+	results := make([]clients.BatchResult, len(names))
+	for i, item := range edgexResps {
+		results[i].Name = names[i]
+		if item.StatusCode != http.StatusOK {
+			results[i].Err = newItemError(*item, resp, "failed to update %s %s on edgex foundry", kind, names[i])
+		}
+	}
+	return results, nil
+}
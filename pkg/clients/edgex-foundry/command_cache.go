@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// defaultCommandCacheTTL is how long the commands of a device are cached
+const defaultCommandCacheTTL = 5 * time.Minute
+
+var (
+	commandCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "edgex_command_cache_hits_total",
+		Help: "Total number of the device commands served from the cache instead of EdgeX core-command",
+	})
+	commandCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "edgex_command_cache_misses_total",
+		Help: "Total number of the device commands requested from EdgeX core-command because they are not cached",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(commandCacheHits, commandCacheMisses)
+}
+
+type commandCacheEntry struct {
+	profileName string
+	commands    []dtos.CoreCommand
+	expireAt    time.Time
+}
+
+// commandCache caches the core-command metadata of the devices by their names on EdgeX,
+// an entry is dropped once it expires, or the profile of the device or the profile itself is changed.
+// All the methods are safe to be called on a nil commandCache, which caches nothing.
+type commandCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	entries map[string]commandCacheEntry
+	// profileVersions records the last modified timestamp of the profiles seen on EdgeX
+	profileVersions map[string]int64
+	now             func() time.Time
+}
+
+func newCommandCache(ttl time.Duration) *commandCache {
+	return &commandCache{
+		ttl:             ttl,
+		entries:         map[string]commandCacheEntry{},
+		profileVersions: map[string]int64{},
+		now:             time.Now,
+	}
+}
+
+// get returns the cached commands of the device, the entry is ignored if it is cached for another profile
+func (c *commandCache) get(deviceName, profileName string) ([]dtos.CoreCommand, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[deviceName]
+	if !ok || c.now().After(entry.expireAt) || (profileName != "" && entry.profileName != profileName) {
+		delete(c.entries, deviceName)
+		commandCacheMisses.Inc()
+		return nil, false
+	}
+	commandCacheHits.Inc()
+	return entry.commands, true
+}
+
+func (c *commandCache) set(deviceName, profileName string, commands []dtos.CoreCommand) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.entries[deviceName] = commandCacheEntry{
+		profileName: profileName,
+		commands:    commands,
+		expireAt:    c.now().Add(c.ttl),
+	}
+}
+
+// invalidateDevice drops the commands of the device
+func (c *commandCache) invalidateDevice(deviceName string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, deviceName)
+}
+
+// invalidateProfile drops the commands of all the devices which belong to the profile
+func (c *commandCache) invalidateProfile(profileName string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.invalidateProfileLocked(profileName)
+}
+
+func (c *commandCache) invalidateProfileLocked(profileName string) {
+	for name, entry := range c.entries {
+		if entry.profileName == profileName {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// observeProfile records the last modified timestamp of the profile seen on EdgeX,
+// the commands of its devices are dropped if the profile has been modified since the last time
+func (c *commandCache) observeProfile(profileName string, modified int64) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if last, ok := c.profileVersions[profileName]; ok && last != modified {
+		c.invalidateProfileLocked(profileName)
+	}
+	c.profileVersions[profileName] = modified
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	edgex_resp "github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/responses"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CommandCache(t *testing.T) {
+	now := time.Now()
+	cache := newCommandCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	commands := []dtos.CoreCommand{{Name: "Float32", Get: true}}
+
+	cache.set("device-a", "profile-a", commands)
+	cache.set("device-b", "profile-b", commands)
+	got, ok := cache.get("device-a", "profile-a")
+	assert.True(t, ok)
+	assert.Equal(t, commands, got)
+
+	// the entry cached for another profile is not served
+	_, ok = cache.get("device-a", "profile-b")
+	assert.False(t, ok)
+
+	// the entry is dropped once it expires
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("device-b", "profile-b")
+	assert.False(t, ok)
+
+	// the entries are dropped once their profile is modified
+	cache.set("device-a", "profile-a", commands)
+	cache.observeProfile("profile-a", 1)
+	_, ok = cache.get("device-a", "profile-a")
+	assert.True(t, ok)
+	cache.observeProfile("profile-a", 2)
+	_, ok = cache.get("device-a", "profile-a")
+	assert.False(t, ok)
+
+	cache.set("device-a", "profile-a", commands)
+	cache.invalidateProfile("profile-a")
+	_, ok = cache.get("device-a", "profile-a")
+	assert.False(t, ok)
+
+	// a nil cache caches nothing
+	var nilCache *commandCache
+	nilCache.set("device-a", "profile-a", commands)
+	_, ok = nilCache.get("device-a", "profile-a")
+	assert.False(t, ok)
+}
+
+func Test_CachedCoreCommands(t *testing.T) {
+	cli := NewEdgexDeviceClient("edgex-core-metadata:59881", "edgex-core-command:59882")
+	httpmock.ActivateNonDefault(cli.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	commandsURL := "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device"
+	httpmock.RegisterResponder("GET", commandsURL,
+		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32",
+		httpmock.NewStringResponder(200, DeviceCommandResp))
+	httpmock.RegisterResponder("PUT", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32",
+		httpmock.NewStringResponder(200, DeviceUpdateProperty))
+	httpmock.RegisterResponder("DELETE", "http://edgex-core-metadata:59881/api/v2/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(200, DeviceDeleteSuccess))
+
+	var resp edgex_resp.DeviceResponse
+	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
+	assert.Nil(t, err)
+	device := toKubeDevice(resp.Device, "default")
+	device.Spec.DeviceProperties = map[string]devicev1alpha1.DesiredPropertyState{
+		"Float32": {Name: "Float32", DesiredValue: "66.66"},
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = cli.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{})
+		assert.Nil(t, err)
+		err = cli.UpdatePropertyState(context.TODO(), "Float32", &device, clients.UpdateOptions{})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET "+commandsURL])
+
+	// the commands are requested again once the profile of the device is changed
+	device.Spec.Profile = "Another-Float-Device"
+	_, err = cli.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["GET "+commandsURL])
+
+	// or the device is deleted
+	err = cli.Delete(context.TODO(), "Random-Float-Device", clients.DeleteOptions{})
+	assert.Nil(t, err)
+	_, err = cli.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, httpmock.GetCallCountInfo()["GET "+commandsURL])
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/publicsuffix"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// the names of the EdgeX services in the metrics of the requests,
+	// the requests to the other services, e.g. the gateway login, are counted as OtherService
+	CoreMetadataService = "core-metadata"
+	CoreCommandService  = "core-command"
+	CoreDataService     = "core-data"
+	OtherService        = "other"
+
+	// UserAgent identifies the controller in the requests sent to EdgeX
+	UserAgent = "yurt-device-controller"
+)
+
+var (
+	edgexRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "edgex_requests_total",
+		Help: "Total number of the requests sent to EdgeX by service, method and status code, the code is \"error\" if no response is received",
+	}, []string{"service", "method", "code"})
+	edgexRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "edgex_request_duration_seconds",
+		Help:    "Latency of the requests sent to EdgeX by service and method",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(edgexRequests, edgexRequestDuration)
+}
+
+// EdgeXConnection is the connection to the core services of EdgeX. It owns the HTTP transport, the timeout,
+// the headers, the token and the instrumentation of the requests, which are shared by the resource clients derived from it
+type EdgeXConnection struct {
+	// Client sends the requests of all the derived clients
+	*resty.Client
+	CoreMetadataAddr string
+	CoreCommandAddr  string
+	CoreDataAddr     string
+	// APIVersion is the EdgeX API version the derived clients speak
+	APIVersion string
+	// commands caches the core-command metadata of the devices, the entries are dropped
+	// by the derived deviceProfile clients once the profile of the commands changes
+	commands *commandCache
+	// breakers guard the endpoints of EdgeX requested through the connection
+	breakers *circuitBreakers
+}
+
+// NewEdgeXConnection connects to EdgeX with the config, the API version is detected through the connection
+// if it is APIVersionAuto, and the v2 API is spoken if it is empty
+func NewEdgeXConnection(cfg clients.EdgePlatformConfig) (*EdgeXConnection, error) {
+	transport, err := newSharedTransport(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS material of EdgeX: %v", err)
+	}
+	conn := newConnection(cfg.CoreMetadataAddr, cfg.CoreCommandAddr, cfg.CoreDataAddr, transport)
+	if cfg.RequestTimeout > 0 {
+		conn.SetTimeout(cfg.RequestTimeout)
+	}
+	conn.SetHeaders(cfg.Headers)
+	if cfg.Credentials != nil {
+		// the token is attached before the API version is detected, since the secure EdgeX rejects every request without it
+		// the logins pass the same gateway as the other requests, so they carry the same headers
+		ts := newTokenSource(cfg.Credentials)
+		ts.client.SetTransport(conn.GetClient().Transport).SetTimeout(conn.GetClient().Timeout).SetHeaders(cfg.Headers)
+		withAuth(conn.Client, ts)
+	}
+	switch cfg.APIVersion {
+	case "":
+	case clients.APIVersionAuto:
+		if conn.APIVersion, err = detectAPIVersionWithRetry(conn.Client, cfg.CoreMetadataAddr); err != nil {
+			return nil, fmt.Errorf("failed to detect the EdgeX API version: %v", err)
+		}
+	default:
+		conn.APIVersion = cfg.APIVersion
+	}
+	if err := ValidateAPIVersion(conn.APIVersion); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// newConnection returns the connection which sends the requests through the transport,
+// the requests are instrumented, retried, guarded by the circuit breakers and budgeted by the limiters of their contexts
+func newConnection(coreMetaAddr, coreCommandAddr, coreDataAddr string, transport http.RoundTripper) *EdgeXConnection {
+	cookieJar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	services := serviceBases{
+		{name: CoreMetadataService, addr: coreMetaAddr},
+		{name: CoreCommandService, addr: coreCommandAddr},
+		{name: CoreDataService, addr: coreDataAddr},
+	}
+	breakers := newCircuitBreakers()
+	client := withRequestLimiter(withResilience(resty.NewWithClient(&http.Client{
+		Jar:       cookieJar,
+		Timeout:   DefaultRequestTimeout,
+		Transport: &instrumentedTransport{next: transport, services: services},
+	}), breakers, services)).SetHeader("User-Agent", UserAgent)
+	return &EdgeXConnection{
+		Client:           client,
+		CoreMetadataAddr: coreMetaAddr,
+		CoreCommandAddr:  coreCommandAddr,
+		CoreDataAddr:     coreDataAddr,
+		APIVersion:       APIVersionV2,
+		commands:         newCommandCache(defaultCommandCacheTTL),
+		breakers:         breakers,
+	}
+}
+
+// NewDeviceClient derives the client which manages the devices from the connection
+func (conn *EdgeXConnection) NewDeviceClient() *EdgexDeviceClient {
+	return &EdgexDeviceClient{
+		Client:          conn.Client,
+		CoreMetaAddr:    conn.CoreMetadataAddr,
+		CoreCommandAddr: conn.CoreCommandAddr,
+		CoreDataAddr:    conn.CoreDataAddr,
+		Namespace:       DefaultNamespace,
+		APIVersion:      conn.APIVersion,
+		commands:        conn.commands,
+	}
+}
+
+// NewDeviceServiceClient derives the client which manages the deviceServices from the connection
+func (conn *EdgeXConnection) NewDeviceServiceClient() *EdgexDeviceServiceClient {
+	return &EdgexDeviceServiceClient{
+		Client:       conn.Client,
+		CoreMetaAddr: conn.CoreMetadataAddr,
+		Namespace:    DefaultNamespace,
+		APIVersion:   conn.APIVersion,
+	}
+}
+
+// NewDeviceProfileClient derives the client which manages the deviceProfiles from the connection
+func (conn *EdgeXConnection) NewDeviceProfileClient() *EdgexDeviceProfile {
+	return &EdgexDeviceProfile{
+		Client:       conn.Client,
+		CoreMetaAddr: conn.CoreMetadataAddr,
+		Namespace:    DefaultNamespace,
+		APIVersion:   conn.APIVersion,
+		commands:     conn.commands,
+	}
+}
+
+type serviceBase struct {
+	name string
+	addr string
+}
+
+type serviceBases []serviceBase
+
+// service returns the name and the base URL of the service whose address the request is sent to,
+// the services may share the host of the gateway and differ in the path prefix
+func (sb serviceBases) service(req *http.Request) (string, string) {
+	reqURL := req.URL.String()
+	for _, s := range sb {
+		if s.addr != "" && strings.HasPrefix(reqURL, baseURL(s.addr)+"/") {
+			return s.name, baseURL(s.addr)
+		}
+	}
+	return OtherService, ""
+}
+
+// instrumentedTransport records the number and the latency of the requests sent to each EdgeX service
+type instrumentedTransport struct {
+	next     http.RoundTripper
+	services serviceBases
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	service, _ := t.services.service(req)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	edgexRequests.WithLabelValues(service, req.Method, code).Inc()
+	edgexRequestDuration.WithLabelValues(service, req.Method).Observe(time.Since(start).Seconds())
+	return resp, err
+}
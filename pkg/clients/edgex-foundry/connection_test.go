@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// requestCount returns the number of the requests counted by the metrics, the metrics are registered once
+// for the whole package, so the tests compare the counts before and after the requests
+func requestCount(service, method, code string) float64 {
+	return testutil.ToFloat64(edgexRequests.WithLabelValues(service, method, code))
+}
+
+func Test_EdgeXConnection(t *testing.T) {
+	var userAgent, gatewayHeader, loginGatewayHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, gatewayHeader = r.Header.Get("User-Agent"), r.Header.Get("X-Gateway-Tenant")
+		switch r.URL.Path {
+		case "/core-metadata/api/v2/deviceservice/name/device-virtual":
+			_, _ = w.Write([]byte(DeviceServiceMetaData))
+		case "/secret-store/v1/auth/userpass/login/edgex-user":
+			loginGatewayHeader = gatewayHeader
+			_, _ = w.Write([]byte(SecretStoreLogin))
+		case "/secret-store/v1/identity/oidc/token/edgex-user":
+			_, _ = fmt.Fprintf(w, `{"data":{"token":%q,"ttl":3600}}`, testJWT(time.Now().Add(time.Hour)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// the core services are reached through the path prefixes of a gateway
+	conn, err := NewEdgeXConnection(clients.EdgePlatformConfig{
+		CoreMetadataAddr: server.URL + "/core-metadata",
+		CoreCommandAddr:  server.URL + "/core-command",
+		CoreDataAddr:     server.URL + "/core-data",
+		RequestTimeout:   3 * time.Second,
+		Headers:          map[string]string{"X-Gateway-Tenant": "nodepool-hangzhou"},
+		APIVersion:       APIVersionV3,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3*time.Second, conn.GetClient().Timeout)
+
+	deviceCli := conn.NewDeviceClient()
+	serviceCli := conn.NewDeviceServiceClient()
+	profileCli := conn.NewDeviceProfileClient()
+	assert.True(t, deviceCli.Client == serviceCli.Client && serviceCli.Client == profileCli.Client)
+	assert.True(t, deviceCli.commands == profileCli.commands)
+	assert.Equal(t, APIVersionV3, deviceCli.APIVersion)
+	assert.Equal(t, server.URL+"/core-data", deviceCli.CoreDataAddr)
+
+	serviceCli.APIVersion = APIVersionV2
+	metadataOK := requestCount(CoreMetadataService, http.MethodGet, "200")
+	_, err = serviceCli.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, UserAgent, userAgent)
+	assert.Equal(t, "nodepool-hangzhou", gatewayHeader)
+	assert.Equal(t, float64(1), requestCount(CoreMetadataService, http.MethodGet, "200")-metadataOK)
+
+	commandNotFound := requestCount(CoreCommandService, http.MethodGet, "404")
+	_, err = deviceCli.GetCommandResponseByName(context.TODO(), "Random-Boolean-Device")
+	assert.True(t, clients.IsNotFoundErr(err))
+	assert.Equal(t, float64(1), requestCount(CoreCommandService, http.MethodGet, "404")-commandNotFound)
+
+	// the logins to the secret store carry the headers as well
+	conn, err = NewEdgeXConnection(clients.EdgePlatformConfig{
+		CoreMetadataAddr: server.URL + "/core-metadata",
+		Headers:          map[string]string{"X-Gateway-Tenant": "nodepool-hangzhou"},
+		Credentials: func(ctx context.Context) (*clients.Credentials, error) {
+			return &clients.Credentials{Username: "edgex-user", Password: "secret", AuthURL: server.URL + "/secret-store"}, nil
+		},
+	})
+	assert.Nil(t, err)
+	_, err = conn.NewDeviceServiceClient().Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "nodepool-hangzhou", loginGatewayHeader)
+
+	_, err = NewEdgeXConnection(clients.EdgePlatformConfig{CoreMetadataAddr: server.URL, APIVersion: "v4"})
+	assert.NotNil(t, err)
+}
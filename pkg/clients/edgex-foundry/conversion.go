@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// The times of the EdgeX objects are kept in the annotations besides the ones of devicev1alpha1,
+// e.g. devicev1alpha1.EdgeXTypedAttributes, so that the objects are converted back to EdgeX without loss
+const (
+	// EdgeXCreated and EdgeXModified are the times (milliseconds) that the object was created and modified on EdgeX
+	EdgeXCreated  = "device-controller/edgex-created"
+	EdgeXModified = "device-controller/edgex-modified"
+)
+
+// setEdgeXAnnotation sets the annotation of the object, the annotation is not set if the value is empty
+func setEdgeXAnnotation(obj metav1.Object, key, value string) {
+	if value == "" {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	obj.SetAnnotations(annotations)
+}
+
+// setEdgeXTimestamps records the EdgeX timestamps of the object in its annotations
+func setEdgeXTimestamps(obj metav1.Object, ts dtos.DBTimestamp) {
+	if ts.Created != 0 {
+		setEdgeXAnnotation(obj, EdgeXCreated, strconv.FormatInt(ts.Created, 10))
+	}
+	if ts.Modified != 0 {
+		setEdgeXAnnotation(obj, EdgeXModified, strconv.FormatInt(ts.Modified, 10))
+	}
+}
+
+// getEdgeXTimestamps returns the EdgeX timestamps recorded in the annotations of the object
+func getEdgeXTimestamps(obj metav1.Object) dtos.DBTimestamp {
+	var ts dtos.DBTimestamp
+	ts.Created, _ = strconv.ParseInt(obj.GetAnnotations()[EdgeXCreated], 10, 64)
+	ts.Modified, _ = strconv.ParseInt(obj.GetAnnotations()[EdgeXModified], 10, 64)
+	return ts
+}
+
+// toKubeLocation converts the location of the EdgeX device to the string in the device spec,
+// the location which is not a string is encoded as JSON and isJSON is true
+func toKubeLocation(location interface{}) (loc string, isJSON bool) {
+	switch l := location.(type) {
+	case nil:
+		return "", false
+	case string:
+		return l, false
+	}
+	b, err := json.Marshal(location)
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to encode the location of device", "location", location)
+		return fmt.Sprint(location), false
+	}
+	return string(b), true
+}
+
+// toEdgeXLocation converts the location in the device spec to the location of the EdgeX device,
+// it is nil if the location is empty
+func toEdgeXLocation(obj metav1.Object, loc string) interface{} {
+	if loc == "" {
+		return nil
+	}
+	if obj.GetAnnotations()[devicev1alpha1.EdgeXLocationFormat] != devicev1alpha1.EdgeXLocationFormatJSON {
+		return loc
+	}
+	var location interface{}
+	if err := json.Unmarshal([]byte(loc), &location); err != nil {
+		klog.V(4).ErrorS(err, "fail to decode the location of device, it is sent as a string", "DeviceName", obj.GetName())
+		return loc
+	}
+	return location
+}
+
+// toKubeAttributes converts the attributes of the EdgeX deviceResource to strings,
+// the values which are not strings are encoded as JSON and their keys are returned in order
+func toKubeAttributes(attrs map[string]interface{}) (map[string]string, []string) {
+	if attrs == nil {
+		return nil, nil
+	}
+	ret := make(map[string]string, len(attrs))
+	var typed []string
+	for k, v := range attrs {
+		if s, ok := v.(string); ok {
+			ret[k] = s
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			klog.V(4).ErrorS(err, "fail to encode the attribute of deviceResource", "attribute", k)
+			ret[k] = fmt.Sprint(v)
+			continue
+		}
+		ret[k] = string(b)
+		typed = append(typed, k)
+	}
+	sort.Strings(typed)
+	return ret, typed
+}
+
+// toEdgeXAttributes converts the attributes in the deviceProfile spec to the attributes of the EdgeX deviceResource,
+// the values of the typed keys are decoded from JSON
+func toEdgeXAttributes(attrs map[string]string, typed []string) map[string]interface{} {
+	if attrs == nil {
+		return nil
+	}
+	ret := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		ret[k] = v
+	}
+	for _, k := range typed {
+		v, ok := attrs[k]
+		if !ok {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(v), &value); err != nil {
+			klog.V(4).ErrorS(err, "fail to decode the attribute of deviceResource, it is sent as a string", "attribute", k)
+			continue
+		}
+		ret[k] = value
+	}
+	return ret
+}
+
+// setTypedAttributes records the typed attributes of the deviceResources in the annotations of the deviceProfile
+func setTypedAttributes(obj metav1.Object, typed map[string][]string) {
+	if len(typed) == 0 {
+		return
+	}
+	b, err := json.Marshal(typed)
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to encode the typed attributes of deviceProfile", "DeviceProfileName", obj.GetName())
+		return
+	}
+	setEdgeXAnnotation(obj, devicev1alpha1.EdgeXTypedAttributes, string(b))
+}
+
+// getTypedAttributes returns the typed attributes of the deviceResources recorded in the annotations of the deviceProfile
+func getTypedAttributes(obj metav1.Object) map[string][]string {
+	v, ok := obj.GetAnnotations()[devicev1alpha1.EdgeXTypedAttributes]
+	if !ok {
+		return nil
+	}
+	var typed map[string][]string
+	if err := json.Unmarshal([]byte(v), &typed); err != nil {
+		klog.V(4).ErrorS(err, "fail to decode the typed attributes of deviceProfile", "DeviceProfileName", obj.GetName())
+		return nil
+	}
+	return typed
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	"github.com/stretchr/testify/assert"
+)
+
+const roundTripIterations = 200
+
+// randomGen generates the random EdgeX objects for the round-trip tests
+type randomGen struct {
+	*rand.Rand
+}
+
+func (g randomGen) str(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, g.Intn(1000))
+}
+
+func (g randomGen) strs(prefix string) []string {
+	if g.Intn(3) == 0 {
+		return nil
+	}
+	ret := make([]string, g.Intn(3))
+	for i := range ret {
+		ret[i] = g.str(prefix)
+	}
+	return ret
+}
+
+func (g randomGen) oneOf(values ...string) string {
+	return values[g.Intn(len(values))]
+}
+
+func (g randomGen) timestamp() dtos.DBTimestamp {
+	return dtos.DBTimestamp{Created: g.Int63n(1 << 41), Modified: g.Int63n(1 << 41)}
+}
+
+// value returns a random JSON value, depth limits the nesting of the objects and arrays
+func (g randomGen) value(depth int) interface{} {
+	n := 6
+	if depth > 0 {
+		n = 8
+	}
+	switch g.Intn(n) {
+	case 0:
+		return g.str("value")
+	case 1:
+		// the strings that look like the other types are kept as strings
+		return g.oneOf("1", "1.5", "true", "null", `{"a":1}`, "")
+	case 2:
+		return float64(g.Intn(70000))
+	case 3:
+		return g.NormFloat64() * 1e6
+	case 4:
+		return g.Intn(2) == 0
+	case 5:
+		return nil
+	case 6:
+		m := map[string]interface{}{}
+		for i := g.Intn(3); i > 0; i-- {
+			m[g.str("key")] = g.value(depth - 1)
+		}
+		return m
+	default:
+		a := make([]interface{}, g.Intn(3))
+		for i := range a {
+			a[i] = g.value(depth - 1)
+		}
+		return a
+	}
+}
+
+func (g randomGen) device() dtos.Device {
+	d := dtos.Device{
+		DBTimestamp:    g.timestamp(),
+		Id:             g.str("id"),
+		Name:           g.str("device"),
+		Description:    g.str("description"),
+		AdminState:     g.oneOf("LOCKED", "UNLOCKED"),
+		OperatingState: g.oneOf("UP", "DOWN", "UNKNOWN"),
+		LastConnected:  g.Int63n(1 << 41),
+		LastReported:   g.Int63n(1 << 41),
+		Labels:         g.strs("label"),
+		ServiceName:    g.str("service"),
+		ProfileName:    g.str("profile"),
+		Protocols: map[string]dtos.ProtocolProperties{
+			"modbus-tcp": {"Address": g.str("address"), "Port": "502"},
+		},
+	}
+	if loc := g.value(2); loc != "" {
+		d.Location = loc
+	}
+	for i := g.Intn(3); i > 0; i-- {
+		d.AutoEvents = append(d.AutoEvents, dtos.AutoEvent{
+			Interval:   g.oneOf("1s", "30s", "5m"),
+			OnChange:   g.Intn(2) == 0,
+			SourceName: g.str("source"),
+		})
+	}
+	return d
+}
+
+func (g randomGen) deviceProfile() dtos.DeviceProfile {
+	dp := dtos.DeviceProfile{
+		DBTimestamp:  g.timestamp(),
+		Id:           g.str("id"),
+		Name:         g.str("profile"),
+		Manufacturer: g.str("manufacturer"),
+		Description:  g.str("description"),
+		Model:        g.str("model"),
+		Labels:       g.strs("label"),
+	}
+	for i := 1 + g.Intn(3); i > 0; i-- {
+		dr := dtos.DeviceResource{
+			Description: g.str("description"),
+			Name:        fmt.Sprintf("resource-%d", i),
+			IsHidden:    g.Intn(2) == 0,
+			Tag:         g.str("tag"),
+			Properties: dtos.ResourceProperties{
+				ValueType:    g.oneOf("Int16", "Float32", "Bool", "String", "Binary", "Object"),
+				ReadWrite:    g.oneOf("R", "W", "RW"),
+				Units:        g.str("units"),
+				Minimum:      g.str("minimum"),
+				Maximum:      g.str("maximum"),
+				DefaultValue: g.str("default"),
+				Mask:         g.str("mask"),
+				Shift:        g.str("shift"),
+				Scale:        g.str("scale"),
+				Offset:       g.str("offset"),
+				Base:         g.str("base"),
+				Assertion:    g.str("assertion"),
+				MediaType:    g.str("media"),
+			},
+		}
+		if g.Intn(4) != 0 {
+			dr.Attributes = map[string]interface{}{}
+			for j := g.Intn(4); j > 0; j-- {
+				dr.Attributes[g.str("attribute")] = g.value(2)
+			}
+		}
+		dp.DeviceResources = append(dp.DeviceResources, dr)
+	}
+	dp.DeviceCommands = []dtos.DeviceCommand{}
+	for i := g.Intn(3); i > 0; i-- {
+		ro := dtos.ResourceOperation{
+			DeviceResource: dp.DeviceResources[0].Name,
+			DefaultValue:   g.str("default"),
+		}
+		if g.Intn(2) == 0 {
+			ro.Mappings = map[string]string{g.str("from"): g.str("to")}
+		}
+		dp.DeviceCommands = append(dp.DeviceCommands, dtos.DeviceCommand{
+			Name:               g.str("command"),
+			IsHidden:           g.Intn(2) == 0,
+			ReadWrite:          g.oneOf("R", "W", "RW"),
+			ResourceOperations: []dtos.ResourceOperation{ro},
+		})
+	}
+	return dp
+}
+
+func (g randomGen) deviceService() dtos.DeviceService {
+	return dtos.DeviceService{
+		DBTimestamp:   g.timestamp(),
+		Id:            g.str("id"),
+		Name:          g.str("service"),
+		Description:   g.str("description"),
+		LastConnected: g.Int63n(1 << 41),
+		LastReported:  g.Int63n(1 << 41),
+		Labels:        g.strs("label"),
+		BaseAddress:   fmt.Sprintf("http://%s:59900", g.str("host")),
+		AdminState:    g.oneOf("LOCKED", "UNLOCKED"),
+	}
+}
+
+// fromEdgeX passes the object through JSON, so that it has the shape of the objects read from EdgeX
+func fromEdgeX(t *testing.T, in, out interface{}) {
+	b, err := json.Marshal(in)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, out))
+}
+
+func Test_DeviceRoundTrip(t *testing.T) {
+	g := randomGen{rand.New(rand.NewSource(1))}
+	for i := 0; i < roundTripIterations; i++ {
+		var ed dtos.Device
+		fromEdgeX(t, g.device(), &ed)
+		kd := toKubeDevice(ed, "edge")
+		assert.Equal(t, "edge", kd.Namespace)
+		assert.Equal(t, ed, toEdgeXDevice(&kd))
+	}
+}
+
+func Test_DeviceProfileRoundTrip(t *testing.T) {
+	g := randomGen{rand.New(rand.NewSource(1))}
+	for i := 0; i < roundTripIterations; i++ {
+		var edp dtos.DeviceProfile
+		fromEdgeX(t, g.deviceProfile(), &edp)
+		kdp := toKubeDeviceProfile(&edp, "edge")
+		assert.Equal(t, "edge", kdp.Namespace)
+		assert.Equal(t, edp, toEdgeXDeviceProfile(&kdp))
+	}
+}
+
+func Test_DeviceServiceRoundTrip(t *testing.T) {
+	g := randomGen{rand.New(rand.NewSource(1))}
+	for i := 0; i < roundTripIterations; i++ {
+		var eds dtos.DeviceService
+		fromEdgeX(t, g.deviceService(), &eds)
+		kds := toKubeDeviceService(eds, "edge")
+		assert.Equal(t, "edge", kds.Namespace)
+		assert.Equal(t, eds, toEdgexDeviceService(&kds))
+	}
+}
+
+func Test_TypedValues(t *testing.T) {
+	var edp dtos.DeviceProfile
+	assert.NoError(t, json.Unmarshal([]byte(`{"name":"Modbus-Device","deviceResources":[
+		{"name":"Temperature","attributes":{"primaryTable":"HOLDING_REGISTERS","startingAddress":1,"rawType":"1","scale":0.1,"enabled":true}},
+		{"name":"Humidity","attributes":{"primaryTable":"INPUT_REGISTERS"}}]}`), &edp))
+	kdp := toKubeDeviceProfile(&edp, DefaultNamespace)
+	assert.Equal(t, map[string]string{
+		"primaryTable": "HOLDING_REGISTERS", "startingAddress": "1", "rawType": "1", "scale": "0.1", "enabled": "true",
+	}, kdp.Spec.DeviceResources[0].Attributes)
+	assert.Equal(t, `{"Temperature":["enabled","scale","startingAddress"]}`, kdp.Annotations[devicev1alpha1.EdgeXTypedAttributes])
+	attrs := toEdgeXDeviceProfile(&kdp).DeviceResources[0].Attributes
+	assert.Equal(t, float64(1), attrs["startingAddress"])
+	assert.Equal(t, "1", attrs["rawType"])
+
+	var ed dtos.Device
+	assert.NoError(t, json.Unmarshal([]byte(`{"name":"Modbus-01","location":{"building":"A","floor":3}}`), &ed))
+	kd := toKubeDevice(ed, DefaultNamespace)
+	assert.Equal(t, `{"building":"A","floor":3}`, kd.Spec.Location)
+	assert.Equal(t, devicev1alpha1.EdgeXLocationFormatJSON, kd.Annotations[devicev1alpha1.EdgeXLocationFormat])
+	assert.Equal(t, ed.Location, toEdgeXDevice(&kd).Location)
+
+	// the location which is not marked as JSON is sent as a string
+	delete(kd.Annotations, devicev1alpha1.EdgeXLocationFormat)
+	assert.Equal(t, kd.Spec.Location, toEdgeXDevice(&kd).Location)
+	// the location is removed on EdgeX when it is cleared
+	kd.Spec.Location = ""
+	assert.Equal(t, "", toEdgeXUpdateDevice(&kd, []string{"location"}).Location)
+
+	// Notify is not returned by EdgeX, it is only sent if the user sets it
+	assert.False(t, kd.Spec.Notify)
+	assert.Nil(t, toEdgeXUpdateDevice(&kd, nil).Notify)
+	kd.Spec.Notify = true
+	assert.Equal(t, true, *toEdgeXUpdateDevice(&kd, nil).Notify)
+}
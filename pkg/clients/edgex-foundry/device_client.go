@@ -18,22 +18,23 @@ package edgex_foundry
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"net/http/cookiejar"
+	"net/url"
 	"strings"
-	"time"
 
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/pkg/clients"
 
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
-	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
 	edgex_resp "github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/responses"
+	"github.com/fxamacker/cbor/v2"
 	"github.com/go-resty/resty/v2"
-	"golang.org/x/net/publicsuffix"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 )
 
@@ -41,144 +42,212 @@ type EdgexDeviceClient struct {
 	*resty.Client
 	CoreMetaAddr    string
 	CoreCommandAddr string
+	CoreDataAddr    string
+	// PropertySource is where the actual values of the device properties are read from,
+	// the devices are read through core-command if it is empty
+	PropertySource clients.PropertySource
+	// Namespace is the namespace of the devices converted from EdgeX
+	Namespace string
+	// APIVersion is the EdgeX API version the client speaks, e.g. v2 or v3
+	APIVersion string
+	// commands caches the core-command metadata of the devices
+	commands *commandCache
 }
 
+// NewEdgexDeviceClient creates the device client on a connection of its own,
+// the clients which share a connection are derived from the EdgeXConnection instead
 func NewEdgexDeviceClient(coreMetaAddr, coreCommandAddr string) *EdgexDeviceClient {
-	cookieJar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
-	instance := resty.NewWithClient(&http.Client{
-		Jar:     cookieJar,
-		Timeout: 10 * time.Second,
-	})
-	return &EdgexDeviceClient{
-		Client:          instance,
-		CoreMetaAddr:    coreMetaAddr,
-		CoreCommandAddr: coreCommandAddr,
-	}
+	return newConnection(coreMetaAddr, coreCommandAddr, "", http.DefaultTransport).NewDeviceClient()
 }
 
 // Create function sends a POST request to EdgeX to add a new device
 func (efc *EdgexDeviceClient) Create(ctx context.Context, device *devicev1alpha1.Device, options clients.CreateOptions) (*devicev1alpha1.Device, error) {
-	devs := []*devicev1alpha1.Device{device}
-	req := makeEdgeXDeviceRequest(devs)
-	klog.V(5).Infof("will add the Device: %s", device.Name)
+	results, err := efc.CreateBatch(ctx, []*devicev1alpha1.Device{device}, options)
+	if err != nil {
+		return nil, err
+	} else if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	createdDevice := device.DeepCopy()
+	createdDevice.Status.EdgeId = results[0].EdgeId
+	createdDevice.Status.Synced = true
+	return createdDevice, nil
+}
+
+// CreateBatch function sends a request to EdgeX to add the devices
+func (efc *EdgexDeviceClient) CreateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options clients.CreateOptions) ([]clients.BatchResult, error) {
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(devices))
+	for _, d := range devices {
+		names = append(names, getEdgeXName(d))
+	}
+	req := makeDeviceRequest(efc.APIVersion, devices)
+	klog.V(5).Infof("will add the Devices: %v", names)
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	postPath := fmt.Sprintf("http://%s%s", efc.CoreMetaAddr, DevicePath)
-	resp, err := efc.R().
+	postPath := fmt.Sprintf("%s%s", baseURL(efc.CoreMetaAddr), apiPath(efc.APIVersion, DevicePath))
+	resp, err := efc.R().SetContext(ctx).
 		SetBody(reqBody).Post(postPath)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to create devices %v on edgex foundry", names)
 	} else if resp.StatusCode() != http.StatusMultiStatus {
-		return nil, fmt.Errorf("create device on edgex foundry failed, the response is : %s", resp.Body())
-	}
-
-	var edgexResps []*common.BaseWithIdResponse
-	if err = json.Unmarshal(resp.Body(), &edgexResps); err != nil {
-		return nil, err
-	}
-	createdDevice := device.DeepCopy()
-	if len(edgexResps) == 1 {
-		if edgexResps[0].StatusCode == http.StatusCreated {
-			createdDevice.Status.EdgeId = edgexResps[0].Id
-			createdDevice.Status.Synced = true
-		} else {
-			return nil, fmt.Errorf("create device on edgex foundry failed, the response is : %s", resp.Body())
-		}
-	} else {
-		return nil, fmt.Errorf("edgex BaseWithIdResponse count mismatch device cound, the response is : %s", resp.Body())
+		return nil, toCreateError(newResponseError(resp, "failed to create devices %v on edgex foundry", names))
 	}
-	return createdDevice, err
+	return parseCreateBatchResponse(resp, "device", names)
 }
 
 // Delete function sends a request to EdgeX to delete a device
 func (efc *EdgexDeviceClient) Delete(ctx context.Context, name string, options clients.DeleteOptions) error {
 	klog.V(5).Infof("will delete the Device: %s", name)
-	delURL := fmt.Sprintf("http://%s%s/name/%s", efc.CoreMetaAddr, DevicePath, name)
-	resp, err := efc.R().Delete(delURL)
+	delURL := fmt.Sprintf("%s%s/name/%s", baseURL(efc.CoreMetaAddr), apiPath(efc.APIVersion, DevicePath), url.PathEscape(name))
+	resp, err := efc.R().SetContext(ctx).Delete(delURL)
 	if err != nil {
-		return err
+		return newRequestError(err, "failed to delete device %s", name)
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return errors.New(string(resp.Body()))
+		return newResponseError(resp, "failed to delete device %s", name)
 	}
+	efc.commands.invalidateDevice(name)
 	return nil
 }
 
-// Update is used to set the admin or operating state of the device by unique name of the device.
-// TODO support to update other fields
+// Update is used to patch the fields of the device by unique name of the device,
+// only the fields listed in options.UpdateFields are patched if it is not empty
 func (efc *EdgexDeviceClient) Update(ctx context.Context, device *devicev1alpha1.Device, options clients.UpdateOptions) (*devicev1alpha1.Device, error) {
-	actualDeviceName := getEdgeXName(device)
-	patchURL := fmt.Sprintf("http://%s%s", efc.CoreMetaAddr, DevicePath)
 	if device == nil {
 		return nil, nil
 	}
-	devs := []*devicev1alpha1.Device{device}
-	req := makeEdgeXDeviceUpdateRequest(devs)
+	results, err := efc.UpdateBatch(ctx, []*devicev1alpha1.Device{device}, options)
+	if err != nil {
+		return nil, err
+	} else if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	return device, nil
+}
+
+// UpdateBatch is used to update the devices with a single request, only the fields in options are updated
+func (efc *EdgexDeviceClient) UpdateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options clients.UpdateOptions) ([]clients.BatchResult, error) {
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(devices))
+	for _, d := range devices {
+		names = append(names, getEdgeXName(d))
+	}
+	patchURL := fmt.Sprintf("%s%s", baseURL(efc.CoreMetaAddr), apiPath(efc.APIVersion, DevicePath))
+	req := makeDeviceUpdateRequest(efc.APIVersion, devices, options.UpdateFields)
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	rep, err := efc.R().
+	rep, err := efc.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(reqBody).
 		Patch(patchURL)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to update devices: %v", names)
 	} else if rep.StatusCode() != http.StatusMultiStatus {
-		return nil, fmt.Errorf("failed to update device: %s, get response: %s", actualDeviceName, string(rep.Body()))
+		return nil, newResponseError(rep, "failed to update devices: %v", names)
 	}
-	return device, nil
+	results, err := parseUpdateBatchResponse(rep, "device", names)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		if res.Err == nil {
+			efc.commands.invalidateDevice(res.Name)
+		}
+	}
+	return results, nil
 }
 
 // Get is used to query the device information corresponding to the device name
 func (efc *EdgexDeviceClient) Get(ctx context.Context, deviceName string, options clients.GetOptions) (*devicev1alpha1.Device, error) {
 	klog.V(5).Infof("will get Devices: %s", deviceName)
-	var dResp edgex_resp.DeviceResponse
-	getURL := fmt.Sprintf("http://%s%s/name/%s", efc.CoreMetaAddr, DevicePath, deviceName)
-	resp, err := efc.R().Get(getURL)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(efc.CoreMetaAddr), apiPath(efc.APIVersion, DevicePath), url.PathEscape(deviceName))
+	resp, err := efc.R().SetContext(ctx).Get(getURL)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to get device %s", deviceName)
 	}
-	if resp.StatusCode() == http.StatusNotFound {
-		return nil, fmt.Errorf("Device %s not found", deviceName)
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get device %s", deviceName)
 	}
-	err = json.Unmarshal(resp.Body(), &dResp)
+	ed, fields, err := decodeDevice(efc.APIVersion, resp.Body())
 	if err != nil {
 		return nil, err
 	}
-	device := toKubeDevice(dResp.Device)
-	return &device, err
+	device := toKubeDevice(ed, efc.Namespace)
+	setV3Fields(&device, fields)
+	return &device, nil
 }
 
-// List is used to get all device objects on edge platform
-// TODO:support label filtering according to options
+// List is used to get the device objects on edge platform which match the selectors of options
 func (efc *EdgexDeviceClient) List(ctx context.Context, options clients.ListOptions) ([]devicev1alpha1.Device, error) {
-	lp := fmt.Sprintf("http://%s%s/all?limit=-1", efc.CoreMetaAddr, DevicePath)
-	resp, err := efc.R().EnableTrace().Get(lp)
+	devices, _, _, err := efc.listPage(ctx, options)
+	return devices, err
+}
+
+// ListPages is used to get the device objects on edge platform which match the selectors of options page by page
+func (efc *EdgexDeviceClient) ListPages(ctx context.Context, options clients.ListOptions, fn func(devices []devicev1alpha1.Device) error) error {
+	return listPages(options, func(opts clients.ListOptions) (int, uint32, error) {
+		devices, received, totalCount, err := efc.listPage(ctx, opts)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(devices) != 0 {
+			if err := fn(devices); err != nil {
+				return 0, 0, err
+			}
+		}
+		return received, totalCount, nil
+	})
+}
+
+// listPage returns the device objects in the page selected by options, the number of objects in the page before
+// they are filtered on the client side, and the total count reported by EdgeX
+func (efc *EdgexDeviceClient) listPage(ctx context.Context, options clients.ListOptions) ([]devicev1alpha1.Device, int, uint32, error) {
+	lp, err := getListDeviceURL(efc.CoreMetaAddr, efc.APIVersion, options)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
-	var mdResp edgex_resp.MultiDevicesResponse
-	if err := json.Unmarshal(resp.Body(), &mdResp); err != nil {
-		return nil, err
+	resp, err := efc.R().SetContext(ctx).EnableTrace().Get(lp)
+	if err != nil {
+		return nil, 0, 0, newRequestError(err, "failed to list devices")
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, 0, 0, newResponseError(resp, "failed to list devices")
+	}
+	edgeDevices, fields, totalCount, err := decodeMultiDevices(efc.APIVersion, resp.Body())
+	if err != nil {
+		return nil, 0, 0, err
 	}
 	var res []devicev1alpha1.Device
-	for _, dp := range mdResp.Devices {
-		res = append(res, toKubeDevice(dp))
+	for i, dp := range edgeDevices {
+		if !clients.LabelsMatch(dp.Labels, options.LabelSelector) || !clients.FieldsMatch(deviceFields(dp), options.FieldSelector) {
+			continue
+		}
+		device := toKubeDevice(dp, efc.Namespace)
+		setV3Fields(&device, fields[i])
+		res = append(res, device)
 	}
-	return res, nil
+	return res, len(edgeDevices), totalCount, nil
 }
 
 func (efc *EdgexDeviceClient) GetPropertyState(ctx context.Context, propertyName string, d *devicev1alpha1.Device, options clients.GetOptions) (*devicev1alpha1.ActualPropertyState, error) {
-	actualDeviceName := getEdgeXName(d)
+	if efc.PropertySource == clients.PropertySourceReadings {
+		return efc.getPropertyStateFromReadings(ctx, propertyName, d)
+	}
 	// get the old property from status
 	oldAps, exist := d.Status.DeviceProperties[propertyName]
 	propertyGetURL := ""
 	// 1. query the Get URL of a property
 	if !exist || (exist && oldAps.GetURL == "") {
-		coreCommands, err := efc.GetCommandResponseByName(actualDeviceName)
+		coreCommands, err := efc.getCoreCommands(ctx, d)
 		if err != nil {
 			return &devicev1alpha1.ActualPropertyState{}, err
 		}
@@ -189,7 +258,7 @@ func (efc *EdgexDeviceClient) GetPropertyState(ctx context.Context, propertyName
 			}
 		}
 		if propertyGetURL == "" {
-			return nil, &clients.NotFoundError{}
+			return nil, clients.NewNotFoundError(fmt.Sprintf("the read command of property %s is not found", propertyName))
 		}
 	} else {
 		propertyGetURL = oldAps.GetURL
@@ -199,44 +268,48 @@ func (efc *EdgexDeviceClient) GetPropertyState(ctx context.Context, propertyName
 		Name:   propertyName,
 		GetURL: propertyGetURL,
 	}
-	if resp, err := efc.getPropertyState(propertyGetURL); err != nil {
+	if resp, err := efc.getPropertyState(ctx, propertyGetURL, options.ReadOptions); err != nil {
 		return nil, err
+	} else if !returnsEvent(options.ReadOptions) {
+		// the reading is not returned, so the actual value is unknown
+		actualPropertyState.Unknown = true
 	} else {
-		var eResp edgex_resp.EventResponse
-		if err := json.Unmarshal(resp.Body(), &eResp); err != nil {
+		event, err := efc.decodeEvent(resp)
+		if err != nil {
 			return nil, err
 		}
-		actualPropertyState.ActualValue = getPropertyValueFromEvent(propertyName, eResp.Event)
+		setActualValue(&actualPropertyState, propertyName, event.Readings)
 	}
 	return &actualPropertyState, nil
 }
 
-// getPropertyState returns different error messages according to the status code
-func (efc *EdgexDeviceClient) getPropertyState(getURL string) (*resty.Response, error) {
-	resp, err := efc.R().Get(getURL)
+// getPropertyState returns the typed error according to the status code,
+// e.g. Locked means the device is locked (AdminState) or down (OperatingState)
+func (efc *EdgexDeviceClient) getPropertyState(ctx context.Context, getURL string, options clients.PropertyReadOptions) (*resty.Response, error) {
+	req := efc.R().SetContext(ctx)
+	if options.PushEvent != nil {
+		req.SetQueryParam(common.PushEvent, readOptionValue(efc.APIVersion, *options.PushEvent))
+	}
+	if options.ReturnEvent != nil {
+		req.SetQueryParam(common.ReturnEvent, readOptionValue(efc.APIVersion, *options.ReturnEvent))
+	}
+	resp, err := req.Get(getURL)
 	if err != nil {
-		return resp, err
-	}
-	if resp.StatusCode() == 400 {
-		err = errors.New("request is in an invalid state")
-	} else if resp.StatusCode() == 404 {
-		err = errors.New("the requested resource does not exist")
-	} else if resp.StatusCode() == 423 {
-		err = errors.New("the device is locked (AdminState) or down (OperatingState)")
-	} else if resp.StatusCode() == 500 {
-		err = errors.New("an unexpected error occurred on the server")
-	}
-	return resp, err
+		return resp, newRequestError(err, "failed to get the property state from %s", getURL)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return resp, newResponseError(resp, "failed to get the property state from %s", getURL)
+	}
+	return resp, nil
 }
 
 func (efc *EdgexDeviceClient) UpdatePropertyState(ctx context.Context, propertyName string, d *devicev1alpha1.Device, options clients.UpdateOptions) error {
-	// Get the actual device name
-	acturalDeviceName := getEdgeXName(d)
-
 	dps := d.Spec.DeviceProperties[propertyName]
 	parameterName := dps.Name
+	// the parameters of a command which sets several resources are named by the user
+	multiParameters := len(dps.DesiredValues) != 0
 	if dps.PutURL == "" {
-		putCmd, err := efc.getPropertyPut(acturalDeviceName, dps.Name)
+		putCmd, err := efc.getPropertyPut(ctx, d, dps.Name)
 		if err != nil {
 			return err
 		}
@@ -245,32 +318,40 @@ func (efc *EdgexDeviceClient) UpdatePropertyState(ctx context.Context, propertyN
 			parameterName = putCmd.Parameters[0].ResourceName
 		}
 	}
-	// set the device property to desired state
+	// set the device property to desired state, all the parameters are sent in one request so that they are set atomically
 	bodyMap := make(map[string]string)
-	bodyMap[parameterName] = dps.DesiredValue
+	if multiParameters {
+		for name, value := range dps.DesiredValues {
+			bodyMap[name] = value
+		}
+	} else {
+		bodyMap[parameterName] = dps.DesiredValue
+	}
 	body, _ := json.Marshal(bodyMap)
 	klog.V(5).Infof("setting the property to desired value", "propertyName", parameterName, "desiredValue", string(body))
-	rep, err := efc.R().
+	rep, err := efc.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(body).
 		Put(dps.PutURL)
 	if err != nil {
-		return err
+		return newRequestError(err, "failed to set property: %s", dps.Name)
 	} else if rep.StatusCode() != http.StatusOK {
-		return fmt.Errorf("failed to set property: %s, get response: %s", dps.Name, string(rep.Body()))
+		return newResponseError(rep, "failed to set property: %s", dps.Name)
 	} else if rep.Body() != nil {
 		// If the parameters are illegal, such as out of range, the 200 status code is also returned, but the description appears in the body
 		a := string(rep.Body())
 		if strings.Contains(a, "execWriteCmd") {
-			return fmt.Errorf("failed to set property: %s, get response: %s", dps.Name, string(rep.Body()))
+			return clients.NewStatusError(clients.StatusReasonInvalidRequest, rep.StatusCode(),
+				fmt.Sprintf("failed to set property: %s, get response: %s", dps.Name, a), rep.Body())
 		}
 	}
 	return nil
 }
 
 // Gets the models.Put from edgex foundry which is used to set the device property's value
-func (efc *EdgexDeviceClient) getPropertyPut(deviceName, cmdName string) (dtos.CoreCommand, error) {
-	coreCommands, err := efc.GetCommandResponseByName(deviceName)
+func (efc *EdgexDeviceClient) getPropertyPut(ctx context.Context, d *devicev1alpha1.Device, cmdName string) (dtos.CoreCommand, error) {
+	deviceName := getEdgeXName(d)
+	coreCommands, err := efc.getCoreCommands(ctx, d)
 	if err != nil {
 		return dtos.CoreCommand{}, err
 	}
@@ -279,7 +360,7 @@ func (efc *EdgexDeviceClient) getPropertyPut(deviceName, cmdName string) (dtos.C
 			return c, nil
 		}
 	}
-	return dtos.CoreCommand{}, errors.New("corresponding command is not found")
+	return dtos.CoreCommand{}, clients.NewNotFoundError(fmt.Sprintf("the set command %s of device %s is not found", cmdName, deviceName))
 }
 
 // ListPropertiesState gets all the actual property information about a device
@@ -288,7 +369,7 @@ func (efc *EdgexDeviceClient) ListPropertiesState(ctx context.Context, device *d
 
 	dpsm := map[string]devicev1alpha1.DesiredPropertyState{}
 	apsm := map[string]devicev1alpha1.ActualPropertyState{}
-	coreCommands, err := efc.GetCommandResponseByName(actualDeviceName)
+	coreCommands, err := efc.getCoreCommands(ctx, device)
 	if err != nil {
 		return dpsm, apsm, err
 	}
@@ -304,24 +385,31 @@ func (efc *EdgexDeviceClient) ListPropertiesState(ctx context.Context, device *d
 				aps = devicev1alpha1.ActualPropertyState{Name: c.Name, GetURL: getURL}
 			}
 			apsm[c.Name] = aps
-			resp, err := efc.getPropertyState(getURL)
+			if efc.PropertySource == clients.PropertySourceReadings {
+				if err := efc.getCommandStateFromReadings(ctx, actualDeviceName, c, &aps); err != nil {
+					klog.V(5).ErrorS(err, "getCommandStateFromReadings failed", "propertyName", c.Name, "deviceName", actualDeviceName)
+					continue
+				}
+				apsm[c.Name] = aps
+				continue
+			}
+			readOptions := options.ReadOptionsOf(c.Name)
+			resp, err := efc.getPropertyState(ctx, getURL, readOptions)
 			if err != nil {
 				klog.V(5).ErrorS(err, "getPropertyState failed", "propertyName", c.Name, "deviceName", actualDeviceName)
+			} else if !returnsEvent(readOptions) {
+				// the reading is not returned, so the actual value is unknown
+				aps.Unknown = true
+				apsm[c.Name] = aps
 			} else {
-				var eResp edgex_resp.EventResponse
-				if err := json.Unmarshal(resp.Body(), &eResp); err != nil {
+				event, err := efc.decodeEvent(resp)
+				if err != nil {
 					klog.V(5).ErrorS(err, "failed to decode the response ", "response", resp)
 					continue
 				}
-				event := eResp.Event
-				readingName := c.Name
-				expectParams := c.Parameters
-				if len(expectParams) == 1 {
-					readingName = expectParams[0].ResourceName
-				}
+				readingName := getReadingName(c)
 				klog.V(5).Infof("get reading name %s for command %s of device %s", readingName, c.Name, device.Name)
-				actualValue := getPropertyValueFromEvent(readingName, event)
-				aps.ActualValue = actualValue
+				setActualValue(&aps, readingName, event.Readings)
 				apsm[c.Name] = aps
 			}
 		}
@@ -329,43 +417,244 @@ func (efc *EdgexDeviceClient) ListPropertiesState(ctx context.Context, device *d
 	return dpsm, apsm, nil
 }
 
-// The actual property value is resolved from the returned event
-func getPropertyValueFromEvent(resName string, event dtos.Event) string {
-	actualValue := ""
-	for _, r := range event.Readings {
+// returnsEvent returns whether the reading is returned by the read request
+func returnsEvent(options clients.PropertyReadOptions) bool {
+	return options.ReturnEvent == nil || *options.ReturnEvent
+}
+
+func yesOrNo(b bool) string {
+	if b {
+		return common.ValueYes
+	}
+	return common.ValueNo
+}
+
+// decodeEvent decodes the event returned by a read command in the API version of the client
+func (efc *EdgexDeviceClient) decodeEvent(resp *resty.Response) (dtos.Event, error) {
+	if efc.APIVersion == APIVersionV1 {
+		return decodeV1Event(resp)
+	}
+	return decodeEvent(resp)
+}
+
+// decodeEvent decodes the event returned by a read command, the events carrying binary readings are encoded in CBOR
+func decodeEvent(resp *resty.Response) (dtos.Event, error) {
+	var eResp edgex_resp.EventResponse
+	var err error
+	if strings.HasPrefix(resp.Header().Get(common.ContentType), common.ContentTypeCBOR) {
+		err = cbor.Unmarshal(resp.Body(), &eResp)
+	} else {
+		err = json.Unmarshal(resp.Body(), &eResp)
+	}
+	return eResp.Event, err
+}
+
+// The actual property value is resolved from the returned readings, the value of a command which reads
+// several resources is the JSON object of the reading values keyed by the resource names, and its timestamp
+// is the one of the oldest reading
+func setActualValue(aps *devicev1alpha1.ActualPropertyState, resName string, readings []dtos.BaseReading) {
+	for _, r := range readings {
 		if resName == r.ResourceName {
-			if r.SimpleReading.Value != "" {
-				actualValue = r.SimpleReading.Value
-			} else if len(r.BinaryReading.BinaryValue) != 0 {
-				// TODO: how to demonstrate binary data
-				actualValue = fmt.Sprintf("%s:%s", r.BinaryReading.MediaType, "blob value")
-			} else if r.ObjectReading.ObjectValue != nil {
-				serializedBytes, _ := json.Marshal(r.ObjectReading.ObjectValue)
-				actualValue = string(serializedBytes)
+			setReading(aps, r)
+			return
+		}
+	}
+	if len(readings) > 1 {
+		values := make(map[string]string, len(readings))
+		var timestamp int64
+		for _, r := range readings {
+			values[r.ResourceName] = getReadingValue(r)
+			if timestamp == 0 || r.Origin < timestamp {
+				timestamp = r.Origin
 			}
-			break
 		}
+		serializedBytes, _ := json.Marshal(values)
+		aps.ActualValue = string(serializedBytes)
+		aps.Timestamp = timestamp
+	}
+}
+
+// setReading fills the actual state of the property with the reading, an object value is kept as structured JSON,
+// and a binary value is described by its hash and size, its data is handed over to the caller to be stored
+func setReading(aps *devicev1alpha1.ActualPropertyState, r dtos.BaseReading) {
+	aps.ActualValue = getReadingValue(r)
+	aps.Timestamp = r.Origin
+	aps.ValueType = r.ValueType
+	aps.MediaType = r.MediaType
+	aps.ObjectValue = nil
+	aps.BinaryValue = nil
+	if r.ObjectValue != nil {
+		aps.ObjectValue = &runtime.RawExtension{Raw: []byte(aps.ActualValue)}
+	}
+	if len(r.BinaryValue) != 0 {
+		sum := sha256.Sum256(r.BinaryValue)
+		aps.BinaryValue = &devicev1alpha1.BinaryValue{
+			SHA256: hex.EncodeToString(sum[:]),
+			Size:   int64(len(r.BinaryValue)),
+			Data:   r.BinaryValue,
+		}
+	}
+}
+
+// getReadingValue returns the value of the reading as a string, a binary value is represented by its hash
+func getReadingValue(r dtos.BaseReading) string {
+	actualValue := ""
+	if r.SimpleReading.Value != "" {
+		actualValue = r.SimpleReading.Value
+	} else if len(r.BinaryReading.BinaryValue) != 0 {
+		sum := sha256.Sum256(r.BinaryReading.BinaryValue)
+		actualValue = "sha256:" + hex.EncodeToString(sum[:])
+	} else if r.ObjectReading.ObjectValue != nil {
+		serializedBytes, _ := json.Marshal(r.ObjectReading.ObjectValue)
+		actualValue = string(serializedBytes)
 	}
 	return actualValue
 }
 
+// getReadingName returns the name of the reading which carries the value of the command,
+// a command that reads a single resource is named after the command itself if it is not the resource
+func getReadingName(c dtos.CoreCommand) string {
+	if len(c.Parameters) == 1 {
+		return c.Parameters[0].ResourceName
+	}
+	return c.Name
+}
+
+// getPropertyStateFromReadings gets the actual value of the property from the latest reading in core-data,
+// the command is still looked up to tell the reading of the property and to keep the GetURL in the status
+func (efc *EdgexDeviceClient) getPropertyStateFromReadings(ctx context.Context, propertyName string, d *devicev1alpha1.Device) (*devicev1alpha1.ActualPropertyState, error) {
+	deviceName := getEdgeXName(d)
+	coreCommands, err := efc.getCoreCommands(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range coreCommands {
+		if c.Name != propertyName || !c.Get {
+			continue
+		}
+		aps := devicev1alpha1.ActualPropertyState{
+			Name:   propertyName,
+			GetURL: fmt.Sprintf("%s%s", c.Url, c.Path),
+		}
+		if err := efc.getCommandStateFromReadings(ctx, deviceName, c, &aps); err != nil {
+			return nil, err
+		}
+		return &aps, nil
+	}
+	return nil, clients.NewNotFoundError(fmt.Sprintf("the read command of property %s is not found", propertyName))
+}
+
+// getCommandStateFromReadings fills the actual state of the command with the latest readings of its resources,
+// the value of a command which reads several resources is composed like setActualValue does
+func (efc *EdgexDeviceClient) getCommandStateFromReadings(ctx context.Context, deviceName string, c dtos.CoreCommand, aps *devicev1alpha1.ActualPropertyState) error {
+	resourceNames := []string{getReadingName(c)}
+	if len(c.Parameters) > 1 {
+		resourceNames = resourceNames[:0]
+		for _, p := range c.Parameters {
+			resourceNames = append(resourceNames, p.ResourceName)
+		}
+	}
+	readings := make([]dtos.BaseReading, 0, len(resourceNames))
+	for _, name := range resourceNames {
+		reading, err := efc.getLatestReading(ctx, deviceName, name)
+		if err != nil {
+			return err
+		}
+		readings = append(readings, reading)
+	}
+	setActualValue(aps, getReadingName(c), readings)
+	return nil
+}
+
+// getLatestReading gets the latest reading of the resource reported by the device from core-data
+func (efc *EdgexDeviceClient) getLatestReading(ctx context.Context, deviceName, resourceName string) (dtos.BaseReading, error) {
+	if efc.APIVersion == APIVersionV1 {
+		return efc.getLatestV1Reading(ctx, deviceName, resourceName)
+	}
+	getURL := fmt.Sprintf("%s%s/device/name/%s/resourceName/%s?offset=0&limit=1",
+		baseURL(efc.CoreDataAddr), apiPath(efc.APIVersion, ReadingPath), url.PathEscape(deviceName), url.PathEscape(resourceName))
+	resp, err := efc.R().SetContext(ctx).Get(getURL)
+	if err != nil {
+		return dtos.BaseReading{}, newRequestError(err, "failed to get the readings of %s from device %s", resourceName, deviceName)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return dtos.BaseReading{}, newResponseError(resp, "failed to get the readings of %s from device %s", resourceName, deviceName)
+	}
+	var mrResp edgex_resp.MultiReadingsResponse
+	if err := json.Unmarshal(resp.Body(), &mrResp); err != nil {
+		return dtos.BaseReading{}, err
+	}
+	// the readings are sorted by their origin in descending order
+	if len(mrResp.Readings) == 0 {
+		return dtos.BaseReading{}, clients.NewNotFoundError(fmt.Sprintf("no reading of %s is reported by device %s", resourceName, deviceName))
+	}
+	return mrResp.Readings[0], nil
+}
+
+// getLatestV1Reading gets the latest reading of the resource from v1 core-data, which takes the limit in the path
+func (efc *EdgexDeviceClient) getLatestV1Reading(ctx context.Context, deviceName, resourceName string) (dtos.BaseReading, error) {
+	getURL := fmt.Sprintf("%s%s/name/%s/device/%s/1",
+		baseURL(efc.CoreDataAddr), apiPath(APIVersionV1, ReadingPath), url.PathEscape(resourceName), url.PathEscape(deviceName))
+	resp, err := efc.R().SetContext(ctx).Get(getURL)
+	if err != nil {
+		return dtos.BaseReading{}, newRequestError(err, "failed to get the readings of %s from device %s", resourceName, deviceName)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return dtos.BaseReading{}, newResponseError(resp, "failed to get the readings of %s from device %s", resourceName, deviceName)
+	}
+	var readings []v1Reading
+	if err := json.Unmarshal(resp.Body(), &readings); err != nil {
+		return dtos.BaseReading{}, err
+	}
+	// the readings are sorted by their creation in descending order
+	if len(readings) == 0 {
+		return dtos.BaseReading{}, clients.NewNotFoundError(fmt.Sprintf("no reading of %s is reported by device %s", resourceName, deviceName))
+	}
+	return readings[0].toBaseReading(), nil
+}
+
+// getCoreCommands gets all commands supported by the device, the commands are served from the cache if possible
+func (efc *EdgexDeviceClient) getCoreCommands(ctx context.Context, d *devicev1alpha1.Device) ([]dtos.CoreCommand, error) {
+	deviceName := getEdgeXName(d)
+	if commands, ok := efc.commands.get(deviceName, d.Spec.Profile); ok {
+		return commands, nil
+	}
+	commands, err := efc.GetCommandResponseByName(ctx, deviceName)
+	if err != nil {
+		return nil, err
+	}
+	efc.commands.set(deviceName, d.Spec.Profile, commands)
+	return commands, nil
+}
+
 // GetCommandResponseByName gets all commands supported by the device
-func (efc *EdgexDeviceClient) GetCommandResponseByName(deviceName string) ([]dtos.CoreCommand, error) {
+func (efc *EdgexDeviceClient) GetCommandResponseByName(ctx context.Context, deviceName string) ([]dtos.CoreCommand, error) {
 	klog.V(5).Infof("will get CommandResponses of device: %s", deviceName)
 
 	var dcr edgex_resp.DeviceCoreCommandResponse
-	getURL := fmt.Sprintf("http://%s%s/name/%s", efc.CoreCommandAddr, CommandResponsePath, deviceName)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(efc.CoreCommandAddr), apiPath(efc.APIVersion, CommandResponsePath), url.PathEscape(deviceName))
 
-	resp, err := efc.R().Get(getURL)
+	resp, err := efc.R().SetContext(ctx).Get(getURL)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to get the commands of device %s", deviceName)
 	}
-	if resp.StatusCode() == http.StatusNotFound {
-		return nil, errors.New("Item not found")
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get the commands of device %s", deviceName)
 	}
-	err = json.Unmarshal(resp.Body(), &dcr)
-	if err != nil {
-		return nil, err
+	var commands []dtos.CoreCommand
+	if efc.APIVersion == APIVersionV1 {
+		if commands, err = decodeV1CoreCommands(resp.Body()); err != nil {
+			return nil, err
+		}
+	} else {
+		if err = json.Unmarshal(resp.Body(), &dcr); err != nil {
+			return nil, err
+		}
+		commands = dcr.DeviceCoreCommand.CoreCommands
+	}
+	// core-command returns its own URL, which is reached the same way as the configured address
+	for i := range commands {
+		commands[i].Url = rebaseURL(commands[i].Url, efc.CoreCommandAddr)
 	}
-	return dcr.DeviceCoreCommand.CoreCommands, nil
+	return commands, nil
 }
@@ -18,13 +18,20 @@ package edgex_foundry
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 
 	"github.com/openyurtio/device-controller/pkg/clients"
 
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
 	edgex_resp "github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/responses"
+	"github.com/fxamacker/cbor/v2"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
 )
@@ -42,6 +49,10 @@ const (
 	DeviceCoreCommands = `{"apiVersion":"v2","statusCode":200,"deviceCoreCommand":{"deviceName":"Random-Float-Device","profileName":"Random-Float-Device","coreCommands":[{"name":"WriteFloat32ArrayValue","set":true,"path":"/api/v2/device/name/Random-Float-Device/WriteFloat32ArrayValue","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float32Array","valueType":"Float32Array"},{"resourceName":"EnableRandomization_Float32Array","valueType":"Bool"}]},{"name":"WriteFloat64ArrayValue","set":true,"path":"/api/v2/device/name/Random-Float-Device/WriteFloat64ArrayValue","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float64Array","valueType":"Float64Array"},{"resourceName":"EnableRandomization_Float64Array","valueType":"Bool"}]},{"name":"Float32","get":true,"set":true,"path":"/api/v2/device/name/Random-Float-Device/Float32","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float32","valueType":"Float32"}]},{"name":"Float64","get":true,"set":true,"path":"/api/v2/device/name/Random-Float-Device/Float64","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float64","valueType":"Float64"}]},{"name":"Float32Array","get":true,"set":true,"path":"/api/v2/device/name/Random-Float-Device/Float32Array","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float32Array","valueType":"Float32Array"}]},{"name":"Float64Array","get":true,"set":true,"path":"/api/v2/device/name/Random-Float-Device/Float64Array","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float64Array","valueType":"Float64Array"}]},{"name":"WriteFloat32Value","set":true,"path":"/api/v2/device/name/Random-Float-Device/WriteFloat32Value","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float32","valueType":"Float32"},{"resourceName":"EnableRandomization_Float32","valueType":"Bool"}]},{"name":"WriteFloat64Value","set":true,"path":"/api/v2/device/name/Random-Float-Device/WriteFloat64Value","url":"http://edgex-core-command:59882","parameters":[{"resourceName":"Float64","valueType":"Float64"},{"resourceName":"EnableRandomization_Float64","valueType":"Bool"}]}]}}`
 	DeviceCommandResp  = `{"apiVersion":"v2","statusCode":200,"event":{"apiVersion":"v2","id":"095090e4-de39-45a1-a0fa-18bc340104e6","deviceName":"Random-Float-Device","profileName":"Random-Float-Device","sourceName":"Float32","origin":1661851070562067780,"readings":[{"id":"972bf6be-3b01-49fc-b211-a43ed51d207d","origin":1661851070562067780,"deviceName":"Random-Float-Device","resourceName":"Float32","profileName":"Random-Float-Device","valueType":"Float32","value":"-2.038811e+38"}]}}`
 
+	DeviceReadings      = `{"apiVersion":"v2","statusCode":200,"totalCount":42,"readings":[{"id":"972bf6be-3b01-49fc-b211-a43ed51d207d","origin":1661851070562067780,"deviceName":"Random-Float-Device","resourceName":"Float32","profileName":"Random-Float-Device","valueType":"Float32","value":"1.5e+01"}]}`
+	DeviceNoReadings    = `{"apiVersion":"v2","statusCode":200,"totalCount":0,"readings":[]}`
+	DeviceCommandLocked = `{"apiVersion":"v2","message":"device Random-Float-Device is locked","statusCode":423}`
+
 	DeviceUpdateSuccess = `[{"apiVersion":"v2","statusCode":200}] `
 
 	DeviceUpdateProperty = `{"apiVersion":"v2","statusCode":200}`
@@ -59,6 +70,36 @@ func Test_Get(t *testing.T) {
 	assert.Nil(t, err)
 
 	assert.Equal(t, "Random-Float-Device", device.Spec.Profile)
+	assert.Equal(t, 2, len(device.Spec.AutoEvents))
+	assert.Equal(t, "30s", device.Spec.AutoEvents[0].Interval)
+	assert.Equal(t, "Float32", device.Spec.AutoEvents[0].SourceName)
+
+	edgeDevice := toEdgeXDevice(device)
+	assert.Equal(t, 2, len(edgeDevice.AutoEvents))
+	assert.Equal(t, "Float64", edgeDevice.AutoEvents[1].SourceName)
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/name/Floor%201%2FSensor",
+		httpmock.NewStringResponder(200, DeviceMetadata))
+	_, err = deviceClient.Get(context.TODO(), "Floor 1/Sensor", clients.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func Test_GetDeadlineExceeded(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+	// the responder does not respond until the request is canceled
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/name/Random-Float-Device",
+		func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	_, err := deviceClient.Get(ctx, "Random-Float-Device", clients.GetOptions{})
+	assert.True(t, clients.IsUnavailableErr(err))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
 }
 
 func Test_List(t *testing.T) {
@@ -73,6 +114,116 @@ func Test_List(t *testing.T) {
 	assert.Nil(t, err)
 
 	assert.Equal(t, len(devices), 5)
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/all?limit=-1&labels=device-virtual-example",
+		httpmock.NewStringResponder(200, DeviceListMetadata))
+	devices, err = deviceClient.List(context.TODO(), clients.ListOptions{
+		LabelSelector: map[string]string{"device-virtual-example": ""},
+		FieldSelector: map[string]string{FieldAdminState: "UNLOCKED", FieldName: "Random-Float-Device"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "random-float-device", devices[0].Name)
+
+	// the devices are filtered by service on EdgeX, and then by profile on the client side
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/service/name/device-virtual?limit=-1",
+		httpmock.NewStringResponder(200, DeviceListMetadata))
+	devices, err = deviceClient.List(context.TODO(), clients.ListOptions{
+		FieldSelector: map[string]string{FieldServiceName: "device-virtual", FieldProfileName: "Random-Boolean-Device"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+
+	_, err = deviceClient.List(context.TODO(), clients.ListOptions{FieldSelector: map[string]string{"description": ""}})
+	assert.True(t, clients.IsInvalidRequestErr(err))
+}
+
+func Test_ListPages(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	var all edgex_resp.MultiDevicesResponse
+	err := json.Unmarshal([]byte(DeviceListMetadata), &all)
+	assert.Nil(t, err)
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/all",
+		func(req *http.Request) (*http.Response, error) {
+			offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+			page := all
+			page.Devices = all.Devices[offset:]
+			if len(page.Devices) > limit {
+				page.Devices = page.Devices[:limit]
+			}
+			return httpmock.NewJsonResponse(200, page)
+		})
+
+	var pageSizes []int
+	var names []string
+	err = deviceClient.ListPages(context.TODO(), clients.ListOptions{Limit: 2}, func(devices []devicev1alpha1.Device) error {
+		pageSizes = append(pageSizes, len(devices))
+		for _, d := range devices {
+			names = append(names, d.Name)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 2, 1}, pageSizes)
+	assert.Equal(t, 5, len(names))
+
+	// listing stops once fn returns an error
+	stopErr := errors.New("stop")
+	calls := 0
+	err = deviceClient.ListPages(context.TODO(), clients.ListOptions{Limit: 2}, func(devices []devicev1alpha1.Device) error {
+		calls++
+		return stopErr
+	})
+	assert.Equal(t, stopErr, err)
+	assert.Equal(t, 1, calls)
+
+	devices, err := deviceClient.List(context.TODO(), clients.ListOptions{Offset: 4, Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+}
+
+func Test_ListPagesWithoutTotalCount(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	var all edgex_resp.MultiDevicesResponse
+	err := json.Unmarshal([]byte(DeviceListMetadata), &all)
+	assert.Nil(t, err)
+	totalCount := uint32(0)
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/all",
+		func(req *http.Request) (*http.Response, error) {
+			offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+			page := all
+			page.TotalCount = totalCount
+			page.Devices = all.Devices[offset:]
+			if len(page.Devices) > limit {
+				page.Devices = page.Devices[:limit]
+			}
+			return httpmock.NewJsonResponse(200, page)
+		})
+
+	// EdgeX 2.0 reports no total count, the pages are fetched until a short one
+	var names []string
+	err = deviceClient.ListPages(context.TODO(), clients.ListOptions{Limit: 2}, func(devices []devicev1alpha1.Device) error {
+		for _, d := range devices {
+			names = append(names, d.Name)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(names))
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+
+	// the objects are deleted between the pages, the listing may have skipped some of them
+	totalCount = 7
+	err = deviceClient.ListPages(context.TODO(), clients.ListOptions{Limit: 2}, func(devices []devicev1alpha1.Device) error {
+		return nil
+	})
+	assert.Equal(t, clients.ErrIncompleteList, err)
 }
 
 func Test_Create(t *testing.T) {
@@ -87,7 +238,7 @@ func Test_Create(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
 	assert.Nil(t, err)
 
-	device := toKubeDevice(resp.Device)
+	device := toKubeDevice(resp.Device, "default")
 	device.Name = "test-Random-Float-Device"
 
 	create, err := deviceClient.Create(context.TODO(), &device, clients.CreateOptions{})
@@ -101,6 +252,45 @@ func Test_Create(t *testing.T) {
 	create, err = deviceClient.Create(context.TODO(), &device, clients.CreateOptions{})
 	assert.NotNil(t, err)
 	assert.Nil(t, create)
+	assert.True(t, clients.IsAlreadyExistsErr(err))
+}
+
+func Test_CreateBatch(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	var resp edgex_resp.DeviceResponse
+	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
+	assert.Nil(t, err)
+	first, second := toKubeDevice(resp.Device, "default"), toKubeDevice(resp.Device, "default")
+	first.Labels[EdgeXObjectName] = "test-Random-Float-Device-1"
+	second.Labels = map[string]string{EdgeXObjectName: "test-Random-Float-Device-2"}
+
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:59881/api/v2/device",
+		httpmock.NewStringResponder(207, `[{"apiVersion":"v2","statusCode":201,"id":"2fff4f1a-7110-442f-b347-9f896338ba57"},`+
+			`{"apiVersion":"v2","message":"device name test-Random-Float-Device-2 already exists","statusCode":409}]`))
+	results, err := deviceClient.CreateBatch(context.TODO(), []*devicev1alpha1.Device{&first, &second}, clients.CreateOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, "test-Random-Float-Device-1", results[0].Name)
+	assert.Equal(t, "2fff4f1a-7110-442f-b347-9f896338ba57", results[0].EdgeId)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, "test-Random-Float-Device-2", results[1].Name)
+	assert.True(t, clients.IsAlreadyExistsErr(results[1].Err))
+
+	// the count of items in the response must match the count of devices
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:59881/api/v2/device",
+		httpmock.NewStringResponder(207, DeviceCreateSuccess))
+	_, err = deviceClient.CreateBatch(context.TODO(), []*devicev1alpha1.Device{&first, &second}, clients.CreateOptions{})
+	assert.NotNil(t, err)
+
+	httpmock.RegisterResponder("PATCH", "http://edgex-core-metadata:59881/api/v2/device",
+		httpmock.NewStringResponder(207, `[{"apiVersion":"v2","statusCode":200},`+
+			`{"apiVersion":"v2","message":"device test-Random-Float-Device-2 doesn't exist","statusCode":404}]`))
+	results, err = deviceClient.UpdateBatch(context.TODO(), []*devicev1alpha1.Device{&first, &second}, clients.UpdateOptions{UpdateFields: []string{"labels"}})
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	assert.True(t, clients.IsNotFoundErr(results[1].Err))
 }
 
 func Test_Delete(t *testing.T) {
@@ -117,6 +307,17 @@ func Test_Delete(t *testing.T) {
 
 	err = deviceClient.Delete(context.TODO(), "test-Random-Float-Device", clients.DeleteOptions{})
 	assert.NotNil(t, err)
+	var statusErr *clients.StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, clients.StatusReasonNotFound, statusErr.Reason)
+	assert.Equal(t, 404, statusErr.StatusCode)
+	assert.Equal(t, DeviceDeleteFail, string(statusErr.Response))
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("DELETE", "http://edgex-core-metadata:59881/api/v2/device/name/Floor%201%2FSensor",
+		httpmock.NewStringResponder(200, DeviceDeleteSuccess))
+	err = deviceClient.Delete(context.TODO(), "Floor 1/Sensor", clients.DeleteOptions{})
+	assert.Nil(t, err)
 }
 
 func Test_GetPropertyState(t *testing.T) {
@@ -134,10 +335,29 @@ func Test_GetPropertyState(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
 	assert.Nil(t, err)
 
-	device := toKubeDevice(resp.Device)
+	device := toKubeDevice(resp.Device, "default")
 
 	_, err = deviceClient.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{})
 	assert.Nil(t, err)
+
+	_, err = deviceClient.GetPropertyState(context.TODO(), "WriteFloat32Value", &device, clients.GetOptions{})
+	assert.True(t, clients.IsNotFoundErr(err))
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32",
+		httpmock.NewStringResponder(423, DeviceCommandLocked))
+	_, err = deviceClient.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{})
+	assert.True(t, clients.IsLockedErr(err))
+
+	// the name is escaped in the path of the commands lookup, the read is sent to the path of the command
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Floor%201%2FSensor",
+		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float64",
+		httpmock.NewStringResponder(200, DeviceCommandResp))
+	escaped := devicev1alpha1.Device{}
+	escaped.Name = "Floor 1/Sensor"
+	_, err = deviceClient.GetPropertyState(context.TODO(), "Float64", &escaped, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET http://edgex-core-command:59882/api/v2/device/name/Floor%201%2FSensor"])
 }
 
 func Test_ListPropertiesState(t *testing.T) {
@@ -146,13 +366,15 @@ func Test_ListPropertiesState(t *testing.T) {
 
 	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device",
 		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	// the properties without a responder can not be read, they are skipped by ListPropertiesState
+	httpmock.RegisterNoResponder(httpmock.NewStringResponder(404, DeviceDeleteFail))
 
 	var resp edgex_resp.DeviceResponse
 
 	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
 	assert.Nil(t, err)
 
-	device := toKubeDevice(resp.Device)
+	device := toKubeDevice(resp.Device, "default")
 
 	_, _, err = deviceClient.ListPropertiesState(context.TODO(), &device, clients.ListOptions{})
 	assert.Nil(t, err)
@@ -170,11 +392,22 @@ func Test_UpdateDevice(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
 	assert.Nil(t, err)
 
-	device := toKubeDevice(resp.Device)
+	device := toKubeDevice(resp.Device, "default")
 	device.Spec.AdminState = "LOCKED"
 
 	_, err = deviceClient.Update(context.TODO(), &device, clients.UpdateOptions{})
 	assert.Nil(t, err)
+
+	_, err = deviceClient.Update(context.TODO(), &device, clients.UpdateOptions{UpdateFields: []string{"adminState"}})
+	assert.Nil(t, err)
+
+	partial := toEdgeXUpdateDevice(&device, []string{"adminState", "labels"})
+	assert.Equal(t, "LOCKED", *partial.AdminState)
+	assert.Equal(t, "Random-Float-Device", *partial.Name)
+	assert.Nil(t, partial.Description)
+	assert.Nil(t, partial.Protocols)
+	assert.Nil(t, partial.AutoEvents)
+	assert.Equal(t, []string{"device-virtual-example"}, partial.Labels)
 }
 
 func Test_UpdatePropertyState(t *testing.T) {
@@ -190,7 +423,7 @@ func Test_UpdatePropertyState(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
 	assert.Nil(t, err)
 
-	device := toKubeDevice(resp.Device)
+	device := toKubeDevice(resp.Device, "default")
 	device.Spec.DeviceProperties = map[string]devicev1alpha1.DesiredPropertyState{
 		"Float32": devicev1alpha1.DesiredPropertyState{
 			Name:         "Float32",
@@ -201,3 +434,158 @@ func Test_UpdatePropertyState(t *testing.T) {
 	err = deviceClient.UpdatePropertyState(context.TODO(), "Float32", &device, clients.UpdateOptions{})
 	assert.Nil(t, err)
 }
+
+func Test_GetPropertyStateFromReadings(t *testing.T) {
+	cli := NewEdgexDeviceClient("edgex-core-metadata:59881", "edgex-core-command:59882")
+	cli.CoreDataAddr = "edgex-core-data:59880"
+	cli.PropertySource = clients.PropertySourceReadings
+	httpmock.ActivateNonDefault(cli.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	httpmock.RegisterResponder("GET", "http://edgex-core-data:59880/api/v2/reading/device/name/Random-Float-Device/resourceName/Float32",
+		httpmock.NewStringResponder(200, DeviceReadings))
+	httpmock.RegisterNoResponder(httpmock.NewStringResponder(200, DeviceNoReadings))
+
+	var resp edgex_resp.DeviceResponse
+	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
+	assert.Nil(t, err)
+	device := toKubeDevice(resp.Device, "default")
+
+	aps, err := cli.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5e+01", aps.ActualValue)
+	assert.Equal(t, int64(1661851070562067780), aps.Timestamp)
+	assert.Equal(t, "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32", aps.GetURL)
+
+	_, err = cli.GetPropertyState(context.TODO(), "Float64", &device, clients.GetOptions{})
+	assert.True(t, clients.IsNotFoundErr(err))
+
+	_, apsm, err := cli.ListPropertiesState(context.TODO(), &device, clients.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5e+01", apsm["Float32"].ActualValue)
+	assert.Equal(t, "", apsm["Float64"].ActualValue)
+
+	// the device itself is never read
+	for call := range httpmock.GetCallCountInfo() {
+		assert.NotContains(t, call, "/Random-Float-Device/Float")
+	}
+}
+
+func Test_UpdatePropertyStateMultiParameters(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	var body map[string]string
+	httpmock.RegisterResponder("PUT", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/WriteFloat32Value",
+		func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(200, DeviceUpdateProperty), nil
+		})
+
+	var resp edgex_resp.DeviceResponse
+	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
+	assert.Nil(t, err)
+
+	device := toKubeDevice(resp.Device, "default")
+	device.Spec.DeviceProperties = map[string]devicev1alpha1.DesiredPropertyState{
+		"WriteFloat32Value": {
+			Name:          "WriteFloat32Value",
+			DesiredValues: map[string]string{"Float32": "66.66", "EnableRandomization_Float32": "false"},
+		},
+	}
+
+	err = deviceClient.UpdatePropertyState(context.TODO(), "WriteFloat32Value", &device, clients.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Float32": "66.66", "EnableRandomization_Float32": "false"}, body)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["PUT http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/WriteFloat32Value"])
+}
+
+func Test_GetPropertyStateReadOptions(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	getURL := "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32"
+	httpmock.RegisterResponderWithQuery("GET", getURL, "ds-pushevent=yes",
+		httpmock.NewStringResponder(200, DeviceCommandResp))
+	httpmock.RegisterResponderWithQuery("GET", getURL, "ds-pushevent=no&ds-returnevent=no",
+		httpmock.NewStringResponder(200, DeviceUpdateProperty))
+
+	var resp edgex_resp.DeviceResponse
+	err := json.Unmarshal([]byte(DeviceMetadata), &resp)
+	assert.Nil(t, err)
+	device := toKubeDevice(resp.Device, "default")
+	device.Status.DeviceProperties = map[string]devicev1alpha1.ActualPropertyState{
+		"Float32": {Name: "Float32", GetURL: getURL, ActualValue: "1.5"},
+	}
+
+	yes, no := true, false
+	aps, err := deviceClient.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{
+		ReadOptions: clients.PropertyReadOptions{PushEvent: &yes},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "-2.038811e+38", aps.ActualValue)
+
+	// the actual value is unknown if the reading is not returned
+	aps, err = deviceClient.GetPropertyState(context.TODO(), "Float32", &device, clients.GetOptions{
+		ReadOptions: clients.PropertyReadOptions{PushEvent: &no, ReturnEvent: &no},
+	})
+	assert.Nil(t, err)
+	assert.True(t, aps.Unknown)
+	assert.Equal(t, "", aps.ActualValue)
+}
+
+func Test_GetPropertyStateStructuredValues(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	snapshot := []byte{0xff, 0xd8, 0xff, 0xe0}
+	event := edgex_resp.EventResponse{Event: dtos.Event{
+		DeviceName: "Random-Float-Device",
+		Readings: []dtos.BaseReading{{
+			Origin:        1661851070562067780,
+			ResourceName:  "Snapshot",
+			ValueType:     common.ValueTypeBinary,
+			BinaryReading: dtos.BinaryReading{BinaryValue: snapshot, MediaType: "image/jpeg"},
+		}},
+	}}
+	body, err := cbor.Marshal(event)
+	assert.Nil(t, err)
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Snapshot",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewBytesResponse(200, body)
+			resp.Header.Set(common.ContentType, common.ContentTypeCBOR)
+			return resp, nil
+		})
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Config",
+		httpmock.NewStringResponder(200, `{"apiVersion":"v2","statusCode":200,"event":{"deviceName":"Random-Float-Device","readings":[{"origin":1661851070562067780,"resourceName":"Config","valueType":"Object","objectValue":{"mode":"auto","threshold":3}}]}}`))
+
+	var resp edgex_resp.DeviceResponse
+	err = json.Unmarshal([]byte(DeviceMetadata), &resp)
+	assert.Nil(t, err)
+	device := toKubeDevice(resp.Device, "default")
+	device.Status.DeviceProperties = map[string]devicev1alpha1.ActualPropertyState{
+		"Snapshot": {Name: "Snapshot", GetURL: "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Snapshot"},
+		"Config":   {Name: "Config", GetURL: "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Config"},
+	}
+
+	aps, err := deviceClient.GetPropertyState(context.TODO(), "Snapshot", &device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, common.ValueTypeBinary, aps.ValueType)
+	assert.Equal(t, "image/jpeg", aps.MediaType)
+	assert.Equal(t, int64(1661851070562067780), aps.Timestamp)
+	assert.Equal(t, int64(len(snapshot)), aps.BinaryValue.Size)
+	assert.Equal(t, snapshot, aps.BinaryValue.Data)
+	assert.Equal(t, "sha256:"+aps.BinaryValue.SHA256, aps.ActualValue)
+
+	aps, err = deviceClient.GetPropertyState(context.TODO(), "Config", &device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, common.ValueTypeObject, aps.ValueType)
+	assert.JSONEq(t, `{"mode":"auto","threshold":3}`, string(aps.ObjectValue.Raw))
+	assert.Nil(t, aps.BinaryValue)
+}
@@ -21,13 +21,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	devcli "github.com/openyurtio/device-controller/pkg/clients"
 
-	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
-	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/responses"
-
 	"github.com/go-resty/resty/v2"
 	"k8s.io/klog/v2"
 )
@@ -35,108 +33,193 @@ import (
 type EdgexDeviceProfile struct {
 	*resty.Client
 	CoreMetaAddr string
+	// Namespace is the namespace of the deviceProfiles converted from EdgeX
+	Namespace string
+	// APIVersion is the EdgeX API version the client speaks, e.g. v2 or v3
+	APIVersion string
+	// commands is the cache of the device commands which is shared with the device client,
+	// the commands of the devices are dropped once their profile is changed
+	commands *commandCache
 }
 
+// NewEdgexDeviceProfile creates the deviceProfile client on a connection of its own,
+// the clients which share a connection are derived from the EdgeXConnection instead
 func NewEdgexDeviceProfile(coreMetaAddr string) *EdgexDeviceProfile {
-	return &EdgexDeviceProfile{
-		Client:       resty.New(),
-		CoreMetaAddr: coreMetaAddr,
-	}
+	return newConnection(coreMetaAddr, "", "", http.DefaultTransport).NewDeviceProfileClient()
 }
 
-// TODO: support label filtering
-func getListDeviceProfileURL(address string, opts devcli.ListOptions) (string, error) {
-	url := fmt.Sprintf("http://%s%s/all?limit=-1", address, DeviceProfilePath)
-	return url, nil
+func (cdc *EdgexDeviceProfile) List(ctx context.Context, opts devcli.ListOptions) ([]v1alpha1.DeviceProfile, error) {
+	deviceProfiles, _, _, err := cdc.listPage(ctx, opts)
+	return deviceProfiles, err
 }
 
-func (cdc *EdgexDeviceProfile) List(ctx context.Context, opts devcli.ListOptions) ([]v1alpha1.DeviceProfile, error) {
+// ListPages is used to get the deviceProfile objects on edge platform which match the selectors of options page by page
+func (cdc *EdgexDeviceProfile) ListPages(ctx context.Context, opts devcli.ListOptions, fn func(deviceProfiles []v1alpha1.DeviceProfile) error) error {
+	return listPages(opts, func(opts devcli.ListOptions) (int, uint32, error) {
+		deviceProfiles, received, totalCount, err := cdc.listPage(ctx, opts)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(deviceProfiles) != 0 {
+			if err := fn(deviceProfiles); err != nil {
+				return 0, 0, err
+			}
+		}
+		return received, totalCount, nil
+	})
+}
+
+// listPage returns the deviceProfile objects in the page selected by options, the number of objects in the page before
+// they are filtered on the client side, and the total count reported by EdgeX
+func (cdc *EdgexDeviceProfile) listPage(ctx context.Context, opts devcli.ListOptions) ([]v1alpha1.DeviceProfile, int, uint32, error) {
 	klog.V(5).Info("will list DeviceProfiles")
-	lp, err := getListDeviceProfileURL(cdc.CoreMetaAddr, opts)
+	lp, err := getListDeviceProfileURL(cdc.CoreMetaAddr, cdc.APIVersion, opts)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
-	resp, err := cdc.R().EnableTrace().Get(lp)
+	resp, err := cdc.R().SetContext(ctx).EnableTrace().Get(lp)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, newRequestError(err, "failed to list deviceProfiles")
 	}
-	var mdpResp responses.MultiDeviceProfilesResponse
-	if err := json.Unmarshal(resp.Body(), &mdpResp); err != nil {
-		return nil, err
+	if resp.StatusCode() != http.StatusOK {
+		return nil, 0, 0, newResponseError(resp, "failed to list deviceProfiles")
+	}
+	edgeProfiles, fields, totalCount, err := decodeMultiDeviceProfiles(cdc.APIVersion, resp.Body())
+	if err != nil {
+		return nil, 0, 0, err
 	}
 	var deviceProfiles []v1alpha1.DeviceProfile
-	for _, dp := range mdpResp.Profiles {
-		deviceProfiles = append(deviceProfiles, toKubeDeviceProfile(&dp))
+	for i, dp := range edgeProfiles {
+		cdc.commands.observeProfile(dp.Name, dp.Modified)
+		if !devcli.LabelsMatch(dp.Labels, opts.LabelSelector) || !devcli.FieldsMatch(deviceProfileFields(dp), opts.FieldSelector) {
+			continue
+		}
+		kdp := toKubeDeviceProfile(&edgeProfiles[i], cdc.Namespace)
+		setV3Fields(&kdp, fields[i])
+		deviceProfiles = append(deviceProfiles, kdp)
 	}
-	return deviceProfiles, nil
+	return deviceProfiles, len(edgeProfiles), totalCount, nil
 }
 
 func (cdc *EdgexDeviceProfile) Get(ctx context.Context, name string, opts devcli.GetOptions) (*v1alpha1.DeviceProfile, error) {
 	klog.V(5).Infof("will get DeviceProfiles: %s", name)
-	var dpResp responses.DeviceProfileResponse
-	getURL := fmt.Sprintf("http://%s%s/name/%s", cdc.CoreMetaAddr, DeviceProfilePath, name)
-	resp, err := cdc.R().Get(getURL)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(cdc.CoreMetaAddr), apiPath(cdc.APIVersion, DeviceProfilePath), url.PathEscape(name))
+	resp, err := cdc.R().SetContext(ctx).Get(getURL)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to get DeviceProfile %s", name)
 	}
-	if resp.StatusCode() == http.StatusNotFound {
-		return nil, fmt.Errorf("DeviceProfile %s not found", name)
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get DeviceProfile %s", name)
 	}
-	if err = json.Unmarshal(resp.Body(), &dpResp); err != nil {
+	edp, fields, err := decodeDeviceProfile(cdc.APIVersion, resp.Body())
+	if err != nil {
 		return nil, err
 	}
-	kubedp := toKubeDeviceProfile(&dpResp.Profile)
+	cdc.commands.observeProfile(edp.Name, edp.Modified)
+	kubedp := toKubeDeviceProfile(&edp, cdc.Namespace)
+	setV3Fields(&kubedp, fields)
 	return &kubedp, nil
 }
 
 func (cdc *EdgexDeviceProfile) Create(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile, opts devcli.CreateOptions) (*v1alpha1.DeviceProfile, error) {
-	dps := []*v1alpha1.DeviceProfile{deviceProfile}
-	req := makeEdgeXDeviceProfilesRequest(dps)
-	klog.V(5).Infof("will add the DeviceProfile: %s", deviceProfile.Name)
+	results, err := cdc.CreateBatch(ctx, []*v1alpha1.DeviceProfile{deviceProfile}, opts)
+	if err != nil {
+		return nil, err
+	} else if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	createdDeviceProfile := deviceProfile.DeepCopy()
+	createdDeviceProfile.Status.EdgeId = results[0].EdgeId
+	createdDeviceProfile.Status.Synced = true
+	return createdDeviceProfile, nil
+}
+
+// CreateBatch sends a request to EdgeX to add the deviceProfiles
+func (cdc *EdgexDeviceProfile) CreateBatch(ctx context.Context, deviceProfiles []*v1alpha1.DeviceProfile, opts devcli.CreateOptions) ([]devcli.BatchResult, error) {
+	if len(deviceProfiles) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(deviceProfiles))
+	for _, dp := range deviceProfiles {
+		names = append(names, getEdgeXName(dp))
+	}
+	req := makeDeviceProfilesRequest(cdc.APIVersion, deviceProfiles)
+	klog.V(5).Infof("will add the DeviceProfiles: %v", names)
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	postURL := fmt.Sprintf("http://%s%s", cdc.CoreMetaAddr, DeviceProfilePath)
-	resp, err := cdc.R().SetBody(reqBody).Post(postURL)
+	postURL := fmt.Sprintf("%s%s", baseURL(cdc.CoreMetaAddr), apiPath(cdc.APIVersion, DeviceProfilePath))
+	resp, err := cdc.R().SetContext(ctx).SetBody(reqBody).Post(postURL)
+	if err != nil {
+		return nil, newRequestError(err, "failed to create edgex deviceProfiles %v", names)
+	}
+	if resp.StatusCode() != http.StatusMultiStatus {
+		return nil, toCreateError(newResponseError(resp, "failed to create edgex deviceProfiles %v", names))
+	}
+	return parseCreateBatchResponse(resp, "deviceProfile", names)
+}
+
+// Update is used to replace the deviceProfile on edge platform with the given deviceProfile,
+// the deviceProfile on edge platform is located by its name
+func (cdc *EdgexDeviceProfile) Update(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile, opts devcli.UpdateOptions) (*v1alpha1.DeviceProfile, error) {
+	if deviceProfile == nil {
+		return nil, nil
+	}
+	results, err := cdc.UpdateBatch(ctx, []*v1alpha1.DeviceProfile{deviceProfile}, opts)
+	if err != nil {
+		return nil, err
+	} else if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	return deviceProfile.DeepCopy(), nil
+}
+
+// UpdateBatch replaces the deviceProfiles on edge platform with a single request
+func (cdc *EdgexDeviceProfile) UpdateBatch(ctx context.Context, deviceProfiles []*v1alpha1.DeviceProfile, opts devcli.UpdateOptions) ([]devcli.BatchResult, error) {
+	if len(deviceProfiles) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(deviceProfiles))
+	for _, dp := range deviceProfiles {
+		names = append(names, getEdgeXName(dp))
+	}
+	req := makeDeviceProfilesRequest(cdc.APIVersion, deviceProfiles)
+	klog.V(5).Infof("will update the DeviceProfiles: %v", names)
+	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
+	putURL := fmt.Sprintf("%s%s", baseURL(cdc.CoreMetaAddr), apiPath(cdc.APIVersion, DeviceProfilePath))
+	resp, err := cdc.R().SetContext(ctx).SetBody(reqBody).Put(putURL)
+	if err != nil {
+		return nil, newRequestError(err, "failed to update edgex deviceProfiles %v", names)
+	}
 	if resp.StatusCode() != http.StatusMultiStatus {
-		return nil, fmt.Errorf("create edgex deviceProfile err: %s", string(resp.Body())) // 假定 resp.Body() 存了 msg 信息
+		return nil, newResponseError(resp, "failed to update edgex deviceProfiles %v", names)
 	}
-	var edgexResps []*common.BaseWithIdResponse
-	if err = json.Unmarshal(resp.Body(), &edgexResps); err != nil {
+	results, err := parseUpdateBatchResponse(resp, "deviceProfile", names)
+	if err != nil {
 		return nil, err
 	}
-	createdDeviceProfile := deviceProfile.DeepCopy()
-	if len(edgexResps) == 1 {
-		if edgexResps[0].StatusCode == http.StatusCreated {
-			createdDeviceProfile.Status.EdgeId = edgexResps[0].Id
-			createdDeviceProfile.Status.Synced = true
-		} else {
-			return nil, fmt.Errorf("create deviceprofile on edgex foundry failed, the response is : %s", resp.Body())
+	for _, res := range results {
+		if res.Err == nil {
+			cdc.commands.invalidateProfile(res.Name)
 		}
-	} else {
-		return nil, fmt.Errorf("edgex BaseWithIdResponse count mismatch DeviceProfile count, the response is : %s", resp.Body())
 	}
-	return createdDeviceProfile, err
-}
-
-// TODO: edgex does not support update DeviceProfile
-func (cdc *EdgexDeviceProfile) Update(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile, opts devcli.UpdateOptions) (*v1alpha1.DeviceProfile, error) {
-	return nil, nil
+	return results, nil
 }
 
 func (cdc *EdgexDeviceProfile) Delete(ctx context.Context, name string, opts devcli.DeleteOptions) error {
 	klog.V(5).Infof("will delete the DeviceProfile: %s", name)
-	delURL := fmt.Sprintf("http://%s%s/name/%s", cdc.CoreMetaAddr, DeviceProfilePath, name)
-	resp, err := cdc.R().Delete(delURL)
+	delURL := fmt.Sprintf("%s%s/name/%s", baseURL(cdc.CoreMetaAddr), apiPath(cdc.APIVersion, DeviceProfilePath), url.PathEscape(name))
+	resp, err := cdc.R().SetContext(ctx).Delete(delURL)
 	if err != nil {
-		return err
+		return newRequestError(err, "failed to delete edgex deviceProfile %s", name)
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("delete edgex deviceProfile err: %s", string(resp.Body())) // 假定 resp.Body() 存了 msg 信息
+		return newResponseError(resp, "failed to delete edgex deviceProfile %s", name)
 	}
+	cdc.commands.invalidateProfile(name)
 	return nil
 }
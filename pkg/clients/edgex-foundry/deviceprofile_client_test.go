@@ -34,6 +34,9 @@ const (
 	ProfileCreateSuccess = `[{"apiVersion":"v2","statusCode":201,"id":"a583b97d-7c4d-4b7c-8b93-51da9e68518c"}]`
 	ProfileCreateFail    = `[{"apiVersion":"v2","message":"device profile name test-Random-Boolean-Device exists","statusCode":409}]`
 
+	ProfileUpdateSuccess = `[{"apiVersion":"v2","statusCode":200}]`
+	ProfileUpdateFail    = `[{"apiVersion":"v2","message":"fail to query device profile by name test-Random-Boolean-Device","statusCode":404}]`
+
 	ProfileDeleteSuccess = `{"apiVersion":"v2","statusCode":200}`
 	ProfileDeleteFail    = `{"apiVersion":"v2","message":"fail to delete the device profile with name test-Random-Boolean-Device","statusCode":404}`
 )
@@ -51,6 +54,24 @@ func Test_ListProfile(t *testing.T) {
 	assert.Nil(t, err)
 
 	assert.Equal(t, 5, len(profiles))
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/deviceprofile/manufacturer/IOTech/model/Device-Virtual-01?limit=-1",
+		httpmock.NewStringResponder(200, DeviceProfileListMetaData))
+	profiles, err = profileClient.List(context.TODO(), clients.ListOptions{
+		LabelSelector: map[string]string{"device-virtual-example": ""},
+		FieldSelector: map[string]string{FieldManufacturer: "IOTech", FieldModel: "Device-Virtual-01"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(profiles))
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/deviceprofile/all?limit=-1&labels=floor%3D1",
+		httpmock.NewStringResponder(200, DeviceProfileListMetaData))
+	profiles, err = profileClient.List(context.TODO(), clients.ListOptions{
+		LabelSelector: map[string]string{"floor": "1"},
+	})
+	// EdgeX returns the deviceProfiles without the label
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(profiles))
 }
 
 func Test_GetProfile(T *testing.T) {
@@ -62,6 +83,12 @@ func Test_GetProfile(T *testing.T) {
 
 	_, err := profileClient.Get(context.TODO(), "Random-Boolean-Device", clients.GetOptions{})
 	assert.Nil(T, err)
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/deviceprofile/name/Random%20Boolean%2FDevice",
+		httpmock.NewStringResponder(200, DeviceProfileMetaData))
+	_, err = profileClient.Get(context.TODO(), "Random Boolean/Device", clients.GetOptions{})
+	assert.Nil(T, err)
 }
 
 func Test_CreateProfile(t *testing.T) {
@@ -76,7 +103,7 @@ func Test_CreateProfile(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceProfileMetaData), &resp)
 	assert.Nil(t, err)
 
-	profile := toKubeDeviceProfile(&resp.Profile)
+	profile := toKubeDeviceProfile(&resp.Profile, "default")
 	profile.Name = "test-Random-Boolean-Device"
 
 	_, err = profileClient.Create(context.TODO(), &profile, clients.CreateOptions{})
@@ -86,7 +113,7 @@ func Test_CreateProfile(t *testing.T) {
 		httpmock.NewStringResponder(207, ProfileCreateFail))
 
 	_, err = profileClient.Create(context.TODO(), &profile, clients.CreateOptions{})
-	assert.NotNil(t, err)
+	assert.True(t, clients.IsAlreadyExistsErr(err))
 }
 
 func Test_DeleteProfile(t *testing.T) {
@@ -103,5 +130,38 @@ func Test_DeleteProfile(t *testing.T) {
 		httpmock.NewStringResponder(404, ProfileDeleteFail))
 
 	err = profileClient.Delete(context.TODO(), "test-Random-Boolean-Device", clients.DeleteOptions{})
+	assert.True(t, clients.IsNotFoundErr(err))
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("DELETE", "http://edgex-core-metadata:59881/api/v2/deviceprofile/name/Random%20Boolean%2FDevice",
+		httpmock.NewStringResponder(200, ProfileDeleteSuccess))
+	err = profileClient.Delete(context.TODO(), "Random Boolean/Device", clients.DeleteOptions{})
+	assert.Nil(t, err)
+}
+
+func Test_UpdateProfile(t *testing.T) {
+	httpmock.ActivateNonDefault(profileClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:59881/api/v2/deviceprofile",
+		httpmock.NewStringResponder(207, ProfileUpdateSuccess))
+
+	var resp edgex_resp.DeviceProfileResponse
+
+	err := json.Unmarshal([]byte(DeviceProfileMetaData), &resp)
+	assert.Nil(t, err)
+
+	profile := toKubeDeviceProfile(&resp.Profile, "default")
+	profile.Name = "test-Random-Boolean-Device"
+	profile.Spec.Description = "updated by OpenYurt"
+
+	updated, err := profileClient.Update(context.TODO(), &profile, clients.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "updated by OpenYurt", updated.Spec.Description)
+
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:59881/api/v2/deviceprofile",
+		httpmock.NewStringResponder(207, ProfileUpdateFail))
+
+	_, err = profileClient.Update(context.TODO(), &profile, clients.UpdateOptions{})
 	assert.NotNil(t, err)
 }
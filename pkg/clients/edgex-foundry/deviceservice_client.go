@@ -21,13 +21,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	edgeCli "github.com/openyurtio/device-controller/pkg/clients"
 
-	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
-	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/responses"
-
 	"github.com/go-resty/resty/v2"
 	"k8s.io/klog/v2"
 )
@@ -35,133 +33,187 @@ import (
 type EdgexDeviceServiceClient struct {
 	*resty.Client
 	CoreMetaAddr string
+	// Namespace is the namespace of the deviceServices converted from EdgeX
+	Namespace string
+	// APIVersion is the EdgeX API version the client speaks, e.g. v2 or v3
+	APIVersion string
 }
 
+// NewEdgexDeviceServiceClient creates the deviceService client on a connection of its own,
+// the clients which share a connection are derived from the EdgeXConnection instead
 func NewEdgexDeviceServiceClient(coreMetaAddr string) *EdgexDeviceServiceClient {
-	return &EdgexDeviceServiceClient{
-		Client:       resty.New(),
-		CoreMetaAddr: coreMetaAddr,
-	}
+	return newConnection(coreMetaAddr, "", "", http.DefaultTransport).NewDeviceServiceClient()
 }
 
 // Create function sends a POST request to EdgeX to add a new deviceService
 func (eds *EdgexDeviceServiceClient) Create(ctx context.Context, deviceService *v1alpha1.DeviceService, options edgeCli.CreateOptions) (*v1alpha1.DeviceService, error) {
-	dss := []*v1alpha1.DeviceService{deviceService}
-	req := makeEdgeXDeviceService(dss)
-	klog.V(5).InfoS("will add the DeviceServices", "DeviceService", deviceService.Name)
+	results, err := eds.CreateBatch(ctx, []*v1alpha1.DeviceService{deviceService}, options)
+	if err != nil {
+		return nil, err
+	} else if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	createdDeviceService := deviceService.DeepCopy()
+	createdDeviceService.Status.EdgeId = results[0].EdgeId
+	createdDeviceService.Status.Synced = true
+	return createdDeviceService, nil
+}
+
+// CreateBatch function sends a request to EdgeX to add the deviceServices
+func (eds *EdgexDeviceServiceClient) CreateBatch(ctx context.Context, deviceServices []*v1alpha1.DeviceService, options edgeCli.CreateOptions) ([]edgeCli.BatchResult, error) {
+	if len(deviceServices) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(deviceServices))
+	for _, ds := range deviceServices {
+		names = append(names, getEdgeXName(ds))
+	}
+	req := makeDeviceServiceRequest(eds.APIVersion, deviceServices)
+	klog.V(5).InfoS("will add the DeviceServices", "DeviceServices", names)
 	jsonBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	postPath := fmt.Sprintf("http://%s%s", eds.CoreMetaAddr, DeviceServicePath)
-	resp, err := eds.R().
+	postPath := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), apiPath(eds.APIVersion, DeviceServicePath))
+	resp, err := eds.R().SetContext(ctx).
 		SetBody(jsonBody).Post(postPath)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to create DeviceServices %v on edgex foundry", names)
 	} else if resp.StatusCode() != http.StatusMultiStatus {
-		return nil, fmt.Errorf("create DeviceService on edgex foundry failed, the response is : %s", resp.Body())
-	}
-
-	var edgexResps []*common.BaseWithIdResponse
-	if err = json.Unmarshal(resp.Body(), &edgexResps); err != nil {
-		return nil, err
-	}
-	createdDeviceService := deviceService.DeepCopy()
-	if len(edgexResps) == 1 {
-		if edgexResps[0].StatusCode == http.StatusCreated {
-			createdDeviceService.Status.EdgeId = edgexResps[0].Id
-			createdDeviceService.Status.Synced = true
-		} else {
-			return nil, fmt.Errorf("create DeviceService on edgex foundry failed, the response is : %s", resp.Body())
-		}
-	} else {
-		return nil, fmt.Errorf("edgex BaseWithIdResponse count mismatch DeviceService count, the response is : %s", resp.Body())
+		return nil, toCreateError(newResponseError(resp, "failed to create DeviceServices %v on edgex foundry", names))
 	}
-	return createdDeviceService, err
+	return parseCreateBatchResponse(resp, "DeviceService", names)
 }
 
 // Delete function sends a request to EdgeX to delete a deviceService
 func (eds *EdgexDeviceServiceClient) Delete(ctx context.Context, name string, option edgeCli.DeleteOptions) error {
 	klog.V(5).InfoS("will delete the DeviceService", "DeviceService", name)
-	delURL := fmt.Sprintf("http://%s%s/name/%s", eds.CoreMetaAddr, DeviceServicePath, name)
-	resp, err := eds.R().Delete(delURL)
+	delURL := fmt.Sprintf("%s%s/name/%s", baseURL(eds.CoreMetaAddr), apiPath(eds.APIVersion, DeviceServicePath), url.PathEscape(name))
+	resp, err := eds.R().SetContext(ctx).Delete(delURL)
 	if err != nil {
-		return err
+		return newRequestError(err, "failed to delete deviceservice %s", name)
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("delete edgex deviceservice err: %s", string(resp.Body()))
+		return newResponseError(resp, "failed to delete deviceservice %s", name)
 	}
 	return nil
 }
 
-// Update is used to set the admin or operating state of the deviceService by unique name of the deviceService.
-// TODO support to update other fields
+// Update is used to patch the fields of the deviceService by unique name of the deviceService,
+// only the fields listed in options.UpdateFields are patched if it is not empty
 func (eds *EdgexDeviceServiceClient) Update(ctx context.Context, ds *v1alpha1.DeviceService, options edgeCli.UpdateOptions) (*v1alpha1.DeviceService, error) {
-	patchURL := fmt.Sprintf("http://%s%s", eds.CoreMetaAddr, DeviceServicePath)
 	if ds == nil {
 		return nil, nil
 	}
+	results, err := eds.UpdateBatch(ctx, []*v1alpha1.DeviceService{ds}, options)
+	if err != nil {
+		return nil, err
+	} else if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	return ds, nil
+}
 
-	if ds.Status.EdgeId == "" {
-		return nil, fmt.Errorf("failed to update deviceservice %s with empty edgex id", ds.Name)
+// UpdateBatch is used to patch the deviceServices with a single request, only the fields in options are patched
+func (eds *EdgexDeviceServiceClient) UpdateBatch(ctx context.Context, deviceServices []*v1alpha1.DeviceService, options edgeCli.UpdateOptions) ([]edgeCli.BatchResult, error) {
+	if len(deviceServices) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(deviceServices))
+	for _, ds := range deviceServices {
+		names = append(names, getEdgeXName(ds))
 	}
-	edgeDs := toEdgexDeviceService(ds)
-	edgeDs.Id = ds.Status.EdgeId
-	dsJson, err := json.Marshal(&edgeDs)
+	patchURL := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), apiPath(eds.APIVersion, DeviceServicePath))
+	req := makeDeviceServiceUpdateRequest(eds.APIVersion, deviceServices, options.UpdateFields)
+	klog.V(5).InfoS("will update the DeviceServices", "DeviceServices", names, "fields", options.UpdateFields)
+	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := eds.R().
-		SetBody(dsJson).Patch(patchURL)
+	resp, err := eds.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(reqBody).
+		Patch(patchURL)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to update deviceservices: %v", names)
 	}
-
-	if resp.StatusCode() == http.StatusOK || resp.StatusCode() == http.StatusMultiStatus {
-		return ds, nil
-	} else {
-		return nil, fmt.Errorf("request to patch deviceservice failed, errcode:%d", resp.StatusCode())
+	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusMultiStatus {
+		return nil, newResponseError(resp, "failed to update deviceservices: %v", names)
 	}
+	return parseUpdateBatchResponse(resp, "deviceservice", names)
 }
 
 // Get is used to query the deviceService information corresponding to the deviceService name
 func (eds *EdgexDeviceServiceClient) Get(ctx context.Context, name string, options edgeCli.GetOptions) (*v1alpha1.DeviceService, error) {
 	klog.V(5).InfoS("will get DeviceServices", "DeviceService", name)
-	var dsResp responses.DeviceServiceResponse
-	getURL := fmt.Sprintf("http://%s%s/name/%s", eds.CoreMetaAddr, DeviceServicePath, name)
-	resp, err := eds.R().Get(getURL)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(eds.CoreMetaAddr), apiPath(eds.APIVersion, DeviceServicePath), url.PathEscape(name))
+	resp, err := eds.R().SetContext(ctx).Get(getURL)
 	if err != nil {
-		return nil, err
+		return nil, newRequestError(err, "failed to get deviceservice %s", name)
 	}
-	if resp.StatusCode() == http.StatusNotFound {
-		return nil, fmt.Errorf("deviceservice %s not found", name)
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get deviceservice %s", name)
 	}
-	err = json.Unmarshal(resp.Body(), &dsResp)
+	edgeService, fields, err := decodeDeviceService(eds.APIVersion, resp.Body())
 	if err != nil {
 		return nil, err
 	}
-	ds := toKubeDeviceService(dsResp.Service)
+	ds := toKubeDeviceService(edgeService, eds.Namespace)
+	setV3Fields(&ds, fields)
 	return &ds, nil
 }
 
-// List is used to get all deviceService objects on edge platform
-// The Hanoi version currently supports only a single label and does not support other filters
+// List is used to get the deviceService objects on edge platform which match the selectors of options
 func (eds *EdgexDeviceServiceClient) List(ctx context.Context, options edgeCli.ListOptions) ([]v1alpha1.DeviceService, error) {
+	deviceServices, _, _, err := eds.listPage(ctx, options)
+	return deviceServices, err
+}
+
+// ListPages is used to get the deviceService objects on edge platform which match the selectors of options page by page
+func (eds *EdgexDeviceServiceClient) ListPages(ctx context.Context, options edgeCli.ListOptions, fn func(deviceServices []v1alpha1.DeviceService) error) error {
+	return listPages(options, func(opts edgeCli.ListOptions) (int, uint32, error) {
+		deviceServices, received, totalCount, err := eds.listPage(ctx, opts)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(deviceServices) != 0 {
+			if err := fn(deviceServices); err != nil {
+				return 0, 0, err
+			}
+		}
+		return received, totalCount, nil
+	})
+}
+
+// listPage returns the deviceService objects in the page selected by options, the number of objects in the page before
+// they are filtered on the client side, and the total count reported by EdgeX
+func (eds *EdgexDeviceServiceClient) listPage(ctx context.Context, options edgeCli.ListOptions) ([]v1alpha1.DeviceService, int, uint32, error) {
 	klog.V(5).Info("will list DeviceServices")
-	lp := fmt.Sprintf("http://%s%s/all?limit=-1", eds.CoreMetaAddr, DeviceServicePath)
-	resp, err := eds.R().
+	lp, err := getListDeviceServiceURL(eds.CoreMetaAddr, eds.APIVersion, options)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	resp, err := eds.R().SetContext(ctx).
 		EnableTrace().
 		Get(lp)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, newRequestError(err, "failed to list deviceservices")
 	}
-	var mdsResponse responses.MultiDeviceServicesResponse
-	if err := json.Unmarshal(resp.Body(), &mdsResponse); err != nil {
-		return nil, err
+	if resp.StatusCode() != http.StatusOK {
+		return nil, 0, 0, newResponseError(resp, "failed to list deviceservices")
+	}
+	edgeServices, fields, totalCount, err := decodeMultiDeviceServices(eds.APIVersion, resp.Body())
+	if err != nil {
+		return nil, 0, 0, err
 	}
 	var res []v1alpha1.DeviceService
-	for _, ds := range mdsResponse.Services {
-		res = append(res, toKubeDeviceService(ds))
+	for i, ds := range edgeServices {
+		if !edgeCli.LabelsMatch(ds.Labels, options.LabelSelector) || !edgeCli.FieldsMatch(deviceServiceFields(ds), options.FieldSelector) {
+			continue
+		}
+		kds := toKubeDeviceService(ds, eds.Namespace)
+		setV3Fields(&kds, fields[i])
+		res = append(res, kds)
 	}
-	return res, nil
+	return res, len(edgeServices), totalCount, nil
 }
@@ -51,6 +51,12 @@ func Test_GetService(t *testing.T) {
 
 	_, err := serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
 	assert.Nil(t, err)
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/deviceservice/name/device%20virtual%2F01",
+		httpmock.NewStringResponder(200, DeviceServiceMetaData))
+	_, err = serviceClient.Get(context.TODO(), "device virtual/01", clients.GetOptions{})
+	assert.Nil(t, err)
 }
 
 func Test_ListService(t *testing.T) {
@@ -77,7 +83,7 @@ func Test_CreateService(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceServiceMetaData), &resp)
 	assert.Nil(t, err)
 
-	service := toKubeDeviceService(resp.Service)
+	service := toKubeDeviceService(resp.Service, "default")
 	service.Name = "test-device-virtual"
 
 	_, err = serviceClient.Create(context.TODO(), &service, clients.CreateOptions{})
@@ -85,6 +91,9 @@ func Test_CreateService(t *testing.T) {
 
 	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:59881/api/v2/deviceservice",
 		httpmock.NewStringResponder(207, ServiceCreateFail))
+
+	_, err = serviceClient.Create(context.TODO(), &service, clients.CreateOptions{})
+	assert.True(t, clients.IsAlreadyExistsErr(err))
 }
 
 func Test_DeleteService(t *testing.T) {
@@ -101,7 +110,13 @@ func Test_DeleteService(t *testing.T) {
 		httpmock.NewStringResponder(404, ServiceDeleteFail))
 
 	err = serviceClient.Delete(context.TODO(), "test-device-virtual", clients.DeleteOptions{})
-	assert.NotNil(t, err)
+	assert.True(t, clients.IsNotFoundErr(err))
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("DELETE", "http://edgex-core-metadata:59881/api/v2/deviceservice/name/device%20virtual%2F01",
+		httpmock.NewStringResponder(200, ServiceDeleteSuccess))
+	err = serviceClient.Delete(context.TODO(), "device virtual/01", clients.DeleteOptions{})
+	assert.Nil(t, err)
 }
 
 func Test_UpdateService(t *testing.T) {
@@ -114,7 +129,7 @@ func Test_UpdateService(t *testing.T) {
 	err := json.Unmarshal([]byte(DeviceServiceMetaData), &resp)
 	assert.Nil(t, err)
 
-	service := toKubeDeviceService(resp.Service)
+	service := toKubeDeviceService(resp.Service, "default")
 	_, err = serviceClient.Update(context.TODO(), &service, clients.UpdateOptions{})
 	assert.Nil(t, err)
 
@@ -124,3 +139,27 @@ func Test_UpdateService(t *testing.T) {
 	_, err = serviceClient.Update(context.TODO(), &service, clients.UpdateOptions{})
 	assert.NotNil(t, err)
 }
+
+func Test_UpdateServicePartialFields(t *testing.T) {
+	httpmock.ActivateNonDefault(serviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("PATCH", "http://edgex-core-metadata:59881/api/v2/deviceservice",
+		httpmock.NewStringResponder(207, ServiceUpdateFail))
+	var resp edgex_resp.DeviceServiceResponse
+
+	err := json.Unmarshal([]byte(DeviceServiceMetaData), &resp)
+	assert.Nil(t, err)
+
+	service := toKubeDeviceService(resp.Service, "default")
+
+	_, err = serviceClient.Update(context.TODO(), &service, clients.UpdateOptions{UpdateFields: []string{"baseAddress"}})
+	assert.NotNil(t, err)
+
+	service.Spec.BaseAddress = "http://edgex-device-virtual-new:59900"
+	partial := toEdgeXUpdateDeviceService(&service, []string{"baseAddress"})
+	assert.Equal(t, "http://edgex-device-virtual-new:59900", *partial.BaseAddress)
+	assert.Equal(t, "device-virtual", *partial.Name)
+	assert.Nil(t, partial.Description)
+	assert.Nil(t, partial.AdminState)
+	assert.Nil(t, partial.Labels)
+}
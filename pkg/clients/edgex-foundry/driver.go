@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+)
+
+// preservedAnnotations are the annotations kept by the clients to convert the objects back to EdgeX without loss
+var preservedAnnotations = []string{
+	devicev1alpha1.EdgeXLocationFormat,
+	devicev1alpha1.EdgeXTypedAttributes,
+	devicev1alpha1.EdgeXV3Fields,
+	devicev1alpha1.EdgeXV1Fields,
+}
+
+// DriverName is the name of the EdgeX Foundry driver in the edge platform driver registry
+const DriverName = "edgex"
+
+func init() {
+	clients.RegisterDriver(DriverName, NewEdgexClients)
+}
+
+// NewEdgexClients creates the clients which manage the objects on EdgeX Foundry, they are derived from
+// a single EdgeXConnection, so that they share its transport, token and instrumentation.
+// The clients speak the v2 API unless another version is configured or detected. The objects on EdgeX 1.x
+// are managed by the v1 clients, which wrap the clients of the later versions
+func NewEdgexClients(cfg clients.EdgePlatformConfig) (*clients.EdgePlatformClients, error) {
+	conn, err := NewEdgeXConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+	deviceCli := conn.NewDeviceClient()
+	deviceCli.PropertySource = cfg.PropertySource
+	deviceServiceCli := conn.NewDeviceServiceClient()
+	deviceProfileCli := conn.NewDeviceProfileClient()
+	if cfg.Namespace != "" {
+		deviceCli.Namespace = cfg.Namespace
+		deviceServiceCli.Namespace = cfg.Namespace
+		deviceProfileCli.Namespace = cfg.Namespace
+	}
+	if conn.APIVersion == APIVersionV1 {
+		return &clients.EdgePlatformClients{
+			DeviceCli:            &EdgexV1DeviceClient{EdgexDeviceClient: deviceCli},
+			DeviceServiceCli:     &EdgexV1DeviceServiceClient{EdgexDeviceServiceClient: deviceServiceCli},
+			DeviceProfileCli:     &EdgexV1DeviceProfile{EdgexDeviceProfile: deviceProfileCli},
+			PreservedAnnotations: preservedAnnotations,
+		}, nil
+	}
+	return &clients.EdgePlatformClients{
+		DeviceCli:            deviceCli,
+		DeviceServiceCli:     deviceServiceCli,
+		DeviceProfileCli:     deviceProfileCli,
+		PreservedAnnotations: preservedAnnotations,
+	}, nil
+}
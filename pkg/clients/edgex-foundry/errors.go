@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
+	"github.com/go-resty/resty/v2"
+)
+
+// newRequestError returns the error when the request can not reach EdgeX,
+// the remaining open time of the circuit breaker is suggested as the delay to retry.
+// The reason of the typed error which stops the request before it is sent, e.g. the token can't be got, is kept
+func newRequestError(err error, format string, args ...interface{}) error {
+	msg := fmt.Sprintf("%s: %v", fmt.Sprintf(format, args...), err)
+	var cause *clients.StatusError
+	if errors.As(err, &cause) {
+		return &clients.StatusError{Reason: cause.Reason, StatusCode: cause.StatusCode, Message: msg, Response: cause.Response, Err: err}
+	}
+	statusErr := clients.NewUnavailableError(msg, err)
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		statusErr.RetryAfter = openErr.RetryAfter
+	}
+	return statusErr
+}
+
+// newResponseError converts the unexpected response of EdgeX to the typed error,
+// the message of the EdgeX response is appended to the error message
+func newResponseError(resp *resty.Response, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	var baseResp common.BaseResponse
+	if err := json.Unmarshal(resp.Body(), &baseResp); err == nil && baseResp.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, baseResp.Message)
+	} else if len(resp.Body()) != 0 {
+		msg = fmt.Sprintf("%s, get response: %s", msg, resp.Body())
+	}
+	return clients.NewStatusError(clients.ReasonForStatusCode(resp.StatusCode()), resp.StatusCode(), msg, resp.Body())
+}
+
+// newItemError converts the failed item of the EdgeX multi-status response to the typed error
+func newItemError(item common.BaseResponse, resp *resty.Response, format string, args ...interface{}) error {
+	msg := fmt.Sprintf("%s: %s", fmt.Sprintf(format, args...), item.Message)
+	return clients.NewStatusError(clients.ReasonForStatusCode(item.StatusCode), item.StatusCode, msg, resp.Body())
+}
+
+// toCreateError marks the conflict of creating an object as AlreadyExists,
+// since EdgeX responds the conflict when an object with the same name exists
+func toCreateError(err error) error {
+	var statusErr *clients.StatusError
+	if errors.As(err, &statusErr) && statusErr.Reason == clients.StatusReasonConflict {
+		statusErr.Reason = clients.StatusReasonAlreadyExists
+	}
+	return err
+}
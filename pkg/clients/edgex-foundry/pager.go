@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"github.com/openyurtio/device-controller/pkg/clients"
+)
+
+// listPageFunc fetches the page of objects selected by the options, and returns the number of objects
+// in the page before they are filtered on the client side, as well as the total count reported by EdgeX
+type listPageFunc func(opts clients.ListOptions) (received int, totalCount uint32, err error)
+
+// listPages fetches the pages one by one from the offset of the options, until the last page is fetched.
+// The total count is only trusted when EdgeX reports it, otherwise the pages are fetched until a short one.
+// ErrIncompleteList is returned if the listing ends before the reported total count is reached,
+// which means the objects have changed between the pages and some of them may have been skipped.
+func listPages(options clients.ListOptions, listPage listPageFunc) error {
+	if options.Limit <= 0 {
+		options.Limit = clients.DefaultPageSize
+	}
+	for {
+		received, totalCount, err := listPage(options)
+		if err != nil {
+			return err
+		}
+		if totalCount > 0 && options.Offset+received >= int(totalCount) {
+			return nil
+		}
+		// a short page means there are no more objects
+		if received < options.Limit {
+			if totalCount > 0 {
+				return clients.ErrIncompleteList
+			}
+			return nil
+		}
+		options.Offset += received
+	}
+}
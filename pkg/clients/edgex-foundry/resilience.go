@@ -0,0 +1,261 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+)
+
+const (
+	// the idempotent requests are retried with exponential backoff when EdgeX is briefly unavailable
+	defaultRetryCount       = 3
+	defaultRetryWaitTime    = 200 * time.Millisecond
+	defaultRetryMaxWaitTime = 2 * time.Second
+
+	// the circuit breaker of an endpoint opens after consecutive failures,
+	// and lets a trial request through once the open duration elapses
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenDuration     = 30 * time.Second
+
+	// the endpoint of a request is identified by the first segments of its path, e.g. "api/v2/device"
+	endpointPathSegments = 3
+)
+
+// CircuitOpenError is returned without sending the request while the circuit breaker of the endpoint is open
+type CircuitOpenError struct {
+	Endpoint string
+	// RetryAfter is the remaining time before the circuit breaker lets a request through
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker of %s is open, retry after %v", e.Endpoint, e.RetryAfter)
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker fails the requests to an endpoint fast after the endpoint fails consecutively
+type circuitBreaker struct {
+	sync.Mutex
+	endpoint         string
+	failureThreshold int
+	openDuration     time.Duration
+	state            breakerState
+	failures         int
+	openedAt         time.Time
+	// now is replaceable for testing
+	now func() time.Time
+}
+
+func newCircuitBreaker(endpoint string) *circuitBreaker {
+	return &circuitBreaker{
+		endpoint:         endpoint,
+		failureThreshold: defaultBreakerFailureThreshold,
+		openDuration:     defaultBreakerOpenDuration,
+		now:              time.Now,
+	}
+}
+
+// allow returns a CircuitOpenError if the request should not be sent,
+// only one trial request is let through when the breaker is half-open
+func (cb *circuitBreaker) allow() error {
+	cb.Lock()
+	defer cb.Unlock()
+	switch cb.state {
+	case breakerOpen:
+		if elapsed := cb.now().Sub(cb.openedAt); elapsed < cb.openDuration {
+			return &CircuitOpenError{Endpoint: cb.endpoint, RetryAfter: cb.openDuration - elapsed}
+		}
+		klog.V(4).Infof("circuit breaker of %s is half-open, letting a trial request through", cb.endpoint)
+		cb.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		return &CircuitOpenError{Endpoint: cb.endpoint, RetryAfter: cb.openDuration}
+	}
+	return nil
+}
+
+// record updates the state of the breaker with the result of a request which is let through
+func (cb *circuitBreaker) record(success bool) {
+	cb.Lock()
+	defer cb.Unlock()
+	if success {
+		if cb.state != breakerClosed {
+			klog.V(3).Infof("circuit breaker of %s is closed", cb.endpoint)
+		}
+		cb.state = breakerClosed
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.state == breakerHalfOpen || cb.failures >= cb.failureThreshold {
+		klog.V(3).Infof("circuit breaker of %s is open after %d consecutive failures", cb.endpoint, cb.failures)
+		cb.state = breakerOpen
+		cb.openedAt = cb.now()
+	}
+}
+
+// abandon releases the trial request whose result is unknown, e.g. it is canceled by the caller
+func (cb *circuitBreaker) abandon() {
+	cb.Lock()
+	defer cb.Unlock()
+	if cb.state == breakerHalfOpen {
+		cb.state = breakerOpen
+	}
+}
+
+// circuitBreakers holds the circuit breaker of each endpoint, they are shared by the clients derived from a connection
+type circuitBreakers struct {
+	sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{breakers: map[string]*circuitBreaker{}}
+}
+
+func (b *circuitBreakers) breakerFor(endpoint string) *circuitBreaker {
+	b.Lock()
+	defer b.Unlock()
+	cb, exists := b.breakers[endpoint]
+	if !exists {
+		cb = newCircuitBreaker(endpoint)
+		b.breakers[endpoint] = cb
+	}
+	return cb
+}
+
+// breakerTransport guards the requests with the circuit breaker of the requested endpoint. An endpoint is
+// a kind of resources of an EdgeX service, e.g. the devices of core-metadata, so that the services behind
+// one gateway and the kinds of resources of one service fail independently
+type breakerTransport struct {
+	next     http.RoundTripper
+	breakers *circuitBreakers
+	services serviceBases
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	service, endpoint := t.endpoint(req)
+	cb := t.breakers.breakerFor(endpoint)
+	if err := cb.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// the request is canceled by the caller, which says nothing about the endpoint
+		cb.abandon()
+		return resp, err
+	}
+	if err == nil && service == CoreCommandService {
+		// core-command answers with the failure of the device or its device service,
+		// which says nothing about core-command itself and the other devices
+		cb.record(true)
+		return resp, err
+	}
+	cb.record(err == nil && !isServerUnavailable(resp.StatusCode))
+	return resp, err
+}
+
+// endpoint returns the service the request is sent to and the endpoint of the request, which is
+// the service followed by the path prefix of the resources, e.g. "core-metadata/api/v2/device"
+func (t *breakerTransport) endpoint(req *http.Request) (string, string) {
+	service, base := t.services.service(req)
+	if service == OtherService {
+		service, base = req.URL.Host, req.URL.Scheme+"://"+req.URL.Host
+	}
+	path := strings.TrimPrefix(req.URL.String(), base)
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", endpointPathSegments+1)
+	if len(segments) > endpointPathSegments {
+		segments = segments[:endpointPathSegments]
+	}
+	return service, service + "/" + strings.Join(segments, "/")
+}
+
+// withResilience retries the idempotent requests with exponential backoff,
+// and guards all the requests with the per-endpoint circuit breakers
+func withResilience(c *resty.Client, breakers *circuitBreakers, services serviceBases) *resty.Client {
+	next := c.GetClient().Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return c.SetTransport(&breakerTransport{next: next, breakers: breakers, services: services}).
+		SetRetryCount(defaultRetryCount).
+		SetRetryWaitTime(defaultRetryWaitTime).
+		SetRetryMaxWaitTime(defaultRetryMaxWaitTime).
+		AddRetryCondition(shouldRetry)
+}
+
+// withRequestLimiter makes every request sent by the client wait for the RequestLimiter of its context,
+// the hook runs before each attempt so that the retries take their tokens too
+func withRequestLimiter(c *resty.Client) *resty.Client {
+	return c.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		if limiter := clients.RequestLimiterFrom(r.Context()); limiter != nil {
+			return limiter.Wait(r.Context())
+		}
+		return nil
+	})
+}
+
+// shouldRetry retries the idempotent requests which fail to reach EdgeX or are answered as unavailable,
+// the requests rejected by the open circuit breaker are not retried
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil || !isIdempotent(resp.Request.Method) {
+		return false
+	}
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return isServerUnavailable(resp.StatusCode())
+}
+
+// isIdempotent returns whether the request can be sent again safely, PUT is left out since core-command
+// sets the device with it, and a request that times out after the device has acted must not set it twice
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isServerUnavailable(statusCode int) bool {
+	return statusCode == http.StatusBadGateway || statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
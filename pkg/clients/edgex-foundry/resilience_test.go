@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker("edgex-core-metadata:59881")
+	cb.now = func() time.Time { return now }
+
+	for i := 0; i < defaultBreakerFailureThreshold; i++ {
+		assert.Nil(t, cb.allow())
+		cb.record(false)
+	}
+	var openErr *CircuitOpenError
+	assert.True(t, errors.As(cb.allow(), &openErr))
+	assert.Equal(t, defaultBreakerOpenDuration, openErr.RetryAfter)
+
+	// a single trial request is let through after the open duration
+	now = now.Add(defaultBreakerOpenDuration)
+	assert.Nil(t, cb.allow())
+	assert.NotNil(t, cb.allow())
+
+	// the failed trial opens the breaker again
+	cb.record(false)
+	assert.NotNil(t, cb.allow())
+
+	now = now.Add(defaultBreakerOpenDuration)
+	assert.Nil(t, cb.allow())
+	cb.record(true)
+	assert.Nil(t, cb.allow())
+	assert.Nil(t, cb.allow())
+}
+
+func Test_BreakerTransport(t *testing.T) {
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://breaker-test:59881/api/v2/ping",
+		httpmock.NewStringResponder(503, ""))
+	cli := &http.Client{Transport: &breakerTransport{next: transport, breakers: newCircuitBreakers()}}
+
+	for i := 0; i < defaultBreakerFailureThreshold; i++ {
+		resp, err := cli.Get("http://breaker-test:59881/api/v2/ping")
+		assert.Nil(t, err)
+		assert.Equal(t, 503, resp.StatusCode)
+	}
+	// the endpoint is not requested while the breaker is open
+	_, err := cli.Get("http://breaker-test:59881/api/v2/ping")
+	var openErr *CircuitOpenError
+	assert.True(t, errors.As(err, &openErr))
+	assert.Equal(t, defaultBreakerFailureThreshold, transport.GetTotalCallCount())
+
+	statusErr := newRequestError(err, "failed to ping")
+	assert.True(t, clients.IsUnavailableErr(statusErr))
+	retryAfter, ok := clients.SuggestsRetryAfter(statusErr)
+	assert.True(t, ok)
+	assert.True(t, retryAfter > 0)
+}
+
+func Test_BreakerEndpoints(t *testing.T) {
+	// the services share the host of the gateway
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://edgex-gateway:8000/core-metadata/api/v2/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(503, ""))
+	transport.RegisterResponder("GET", "http://edgex-gateway:8000/core-metadata/api/v2/deviceprofile/name/Random-Float-Device",
+		httpmock.NewStringResponder(200, ""))
+	transport.RegisterResponder("GET", "http://edgex-gateway:8000/core-command/api/v2/device/name/Random-Float-Device/Float32",
+		httpmock.NewStringResponder(503, ""))
+	transport.RegisterResponder("GET", "http://edgex-gateway:8000/core-command/api/v2/device/name/Random-Boolean-Device/Bool",
+		httpmock.NewStringResponder(200, ""))
+	bt := &breakerTransport{next: transport, breakers: newCircuitBreakers(), services: serviceBases{
+		{name: CoreMetadataService, addr: "http://edgex-gateway:8000/core-metadata"},
+		{name: CoreCommandService, addr: "http://edgex-gateway:8000/core-command"},
+	}}
+	cli := &http.Client{Transport: bt}
+
+	req, _ := http.NewRequest("GET", "http://edgex-gateway:8000/core-metadata/api/v2/device/name/Random-Float-Device?offset=0", nil)
+	_, endpoint := bt.endpoint(req)
+	assert.Equal(t, "core-metadata/api/v2/device", endpoint)
+
+	for i := 0; i < defaultBreakerFailureThreshold; i++ {
+		_, err := cli.Get("http://edgex-gateway:8000/core-metadata/api/v2/device/name/Random-Float-Device")
+		assert.Nil(t, err)
+	}
+	_, err := cli.Get("http://edgex-gateway:8000/core-metadata/api/v2/device/name/Random-Float-Device")
+	var openErr *CircuitOpenError
+	assert.True(t, errors.As(err, &openErr))
+	// the other kinds of resources and the other services behind the gateway are still requested
+	resp, err := cli.Get("http://edgex-gateway:8000/core-metadata/api/v2/deviceprofile/name/Random-Float-Device")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// the failures of a device answered by core-command don't stop the commands of the other devices
+	for i := 0; i < defaultBreakerFailureThreshold+1; i++ {
+		resp, err = cli.Get("http://edgex-gateway:8000/core-command/api/v2/device/name/Random-Float-Device/Float32")
+		assert.Nil(t, err)
+		assert.Equal(t, 503, resp.StatusCode)
+	}
+	resp, err = cli.Get("http://edgex-gateway:8000/core-command/api/v2/device/name/Random-Boolean-Device/Bool")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// the breakers of another connection are not affected
+	conn := newConnection("http://edgex-gateway:8000/core-metadata", "", "", transport)
+	assert.Nil(t, conn.breakers.breakerFor("core-metadata/api/v2/device").allow())
+}
+
+func Test_Retry(t *testing.T) {
+	httpmock.ActivateNonDefault(deviceClient.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	// the idempotent request is retried until EdgeX is available
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/device/name/Random-Float-Device",
+		httpmock.ResponderFromMultipleResponses([]*http.Response{
+			httpmock.NewStringResponse(503, ""),
+			httpmock.NewStringResponse(503, ""),
+			httpmock.NewStringResponse(200, DeviceMetadata),
+		}))
+	device, err := deviceClient.Get(context.TODO(), "Random-Float-Device", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Random-Float-Device", device.Spec.Profile)
+
+	// the non-idempotent request is not retried
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:59881/api/v2/device",
+		httpmock.NewStringResponder(503, ""))
+	_, err = deviceClient.Create(context.TODO(), device, clients.CreateOptions{})
+	assert.True(t, clients.IsUnavailableErr(err))
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST http://edgex-core-metadata:59881/api/v2/device"])
+
+	// the command which sets the device is not retried, the device may have acted on it already
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	httpmock.RegisterResponder("PUT", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32",
+		httpmock.NewStringResponder(503, ""))
+	device.Spec.DeviceProperties = map[string]devicev1alpha1.DesiredPropertyState{
+		"Float32": {Name: "Float32", DesiredValue: "66.66"},
+	}
+	err = deviceClient.UpdatePropertyState(context.TODO(), "Float32", device, clients.UpdateOptions{})
+	assert.True(t, clients.IsUnavailableErr(err))
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["PUT http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32"])
+}
+
+// countingLimiter counts the tokens taken, and rejects the requests once it is closed
+type countingLimiter struct {
+	waits  int
+	closed bool
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	if l.closed {
+		return errors.New("the budget is exhausted")
+	}
+	l.waits++
+	return nil
+}
+
+func Test_RequestLimiter(t *testing.T) {
+	cli := NewEdgexDeviceClient("edgex-core-metadata:59881", "edgex-core-command:59882")
+	httpmock.ActivateNonDefault(cli.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(200, DeviceCoreCommands))
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32",
+		httpmock.ResponderFromMultipleResponses([]*http.Response{
+			httpmock.NewStringResponse(200, DeviceCommandResp),
+			httpmock.NewStringResponse(503, ""),
+			httpmock.NewStringResponse(200, DeviceCommandResp),
+		}))
+	device := &devicev1alpha1.Device{}
+	device.Name = "Random-Float-Device"
+	limiter := &countingLimiter{}
+	ctx := clients.WithRequestLimiter(context.TODO(), limiter)
+
+	// the lookup of the commands and the read take a token each
+	_, err := cli.GetPropertyState(ctx, "Float32", device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, limiter.waits)
+
+	// the commands are cached, while the retry of the read takes a token of its own
+	limiter.waits = 0
+	_, err = cli.GetPropertyState(ctx, "Float32", device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, limiter.waits)
+
+	// the request is not sent once the budget rejects it
+	limiter.closed = true
+	_, err = cli.GetPropertyState(ctx, "Float32", device, clients.GetOptions{})
+	assert.True(t, clients.IsUnavailableErr(err))
+	assert.Equal(t, 3, httpmock.GetCallCountInfo()["GET http://edgex-core-command:59882/api/v2/device/name/Random-Float-Device/Float32"])
+
+}
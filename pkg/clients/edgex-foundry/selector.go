@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+)
+
+// The fields that can be used in the FieldSelector of ListOptions
+const (
+	FieldName           = "name"
+	FieldServiceName    = "serviceName"
+	FieldProfileName    = "profileName"
+	FieldAdminState     = "adminState"
+	FieldOperatingState = "operatingState"
+	FieldManufacturer   = "manufacturer"
+	FieldModel          = "model"
+)
+
+var (
+	deviceSelectableFields        = []string{FieldName, FieldServiceName, FieldProfileName, FieldAdminState, FieldOperatingState}
+	deviceServiceSelectableFields = []string{FieldName, FieldAdminState}
+	deviceProfileSelectableFields = []string{FieldName, FieldManufacturer, FieldModel}
+)
+
+func deviceFields(d dtos.Device) map[string]string {
+	return map[string]string{
+		FieldName:           d.Name,
+		FieldServiceName:    d.ServiceName,
+		FieldProfileName:    d.ProfileName,
+		FieldAdminState:     d.AdminState,
+		FieldOperatingState: d.OperatingState,
+	}
+}
+
+func deviceServiceFields(ds dtos.DeviceService) map[string]string {
+	return map[string]string{
+		FieldName:       ds.Name,
+		FieldAdminState: ds.AdminState,
+	}
+}
+
+func deviceProfileFields(dp dtos.DeviceProfile) map[string]string {
+	return map[string]string{
+		FieldName:         dp.Name,
+		FieldManufacturer: dp.Manufacturer,
+		FieldModel:        dp.Model,
+	}
+}
+
+// validateFieldSelector checks that all the fields of the selector can be selected for the kind of object
+func validateFieldSelector(kind string, selector map[string]string, selectable []string) error {
+	for field := range selector {
+		supported := false
+		for _, f := range selectable {
+			if f == field {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return clients.NewStatusError(clients.StatusReasonInvalidRequest, 0,
+				fmt.Sprintf("field %q is not supported in the field selector of %s, supported fields: %v", field, kind, selectable), nil)
+		}
+	}
+	return nil
+}
+
+// pageQuery returns the query of the list URL which selects the page of objects,
+// all the objects are selected if the limit is not specified
+func pageQuery(opts clients.ListOptions) string {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	if opts.Offset > 0 {
+		return fmt.Sprintf("?offset=%d&limit=%d", opts.Offset, limit)
+	}
+	return fmt.Sprintf("?limit=%d", limit)
+}
+
+// withLabelsQuery appends the labels required by the label selector to the list URL,
+// EdgeX may return the objects that own only some of the labels, so the result still needs to be filtered
+func withLabelsQuery(listURL string, selector map[string]string) string {
+	labels := clients.SelectorLabels(selector)
+	if len(labels) == 0 {
+		return listURL
+	}
+	for i := range labels {
+		labels[i] = url.QueryEscape(labels[i])
+	}
+	return fmt.Sprintf("%s&labels=%s", listURL, strings.Join(labels, ","))
+}
+
+// getListDeviceURL returns the URL which filters the devices on EdgeX as much as possible,
+// EdgeX can filter devices either by labels, by service or by profile
+func getListDeviceURL(address, version string, opts clients.ListOptions) (string, error) {
+	if err := validateFieldSelector("device", opts.FieldSelector, deviceSelectableFields); err != nil {
+		return "", err
+	}
+	if service, ok := opts.FieldSelector[FieldServiceName]; ok {
+		return fmt.Sprintf("%s%s/service/name/%s%s", baseURL(address), apiPath(version, DevicePath), url.PathEscape(service), pageQuery(opts)), nil
+	}
+	if profile, ok := opts.FieldSelector[FieldProfileName]; ok {
+		return fmt.Sprintf("%s%s/profile/name/%s%s", baseURL(address), apiPath(version, DevicePath), url.PathEscape(profile), pageQuery(opts)), nil
+	}
+	return withLabelsQuery(fmt.Sprintf("%s%s/all%s", baseURL(address), apiPath(version, DevicePath), pageQuery(opts)), opts.LabelSelector), nil
+}
+
+// getListDeviceServiceURL returns the URL which filters the deviceServices on EdgeX by labels
+func getListDeviceServiceURL(address, version string, opts clients.ListOptions) (string, error) {
+	if err := validateFieldSelector("deviceService", opts.FieldSelector, deviceServiceSelectableFields); err != nil {
+		return "", err
+	}
+	return withLabelsQuery(fmt.Sprintf("%s%s/all%s", baseURL(address), apiPath(version, DeviceServicePath), pageQuery(opts)), opts.LabelSelector), nil
+}
+
+// getListDeviceProfileURL returns the URL which filters the deviceProfiles on EdgeX as much as possible,
+// EdgeX can filter deviceProfiles either by labels or by manufacturer and model
+func getListDeviceProfileURL(address, version string, opts clients.ListOptions) (string, error) {
+	if err := validateFieldSelector("deviceProfile", opts.FieldSelector, deviceProfileSelectableFields); err != nil {
+		return "", err
+	}
+	manufacturer, byManufacturer := opts.FieldSelector[FieldManufacturer]
+	model, byModel := opts.FieldSelector[FieldModel]
+	switch {
+	case byManufacturer && byModel:
+		return fmt.Sprintf("%s%s/manufacturer/%s/model/%s%s",
+			baseURL(address), apiPath(version, DeviceProfilePath), url.PathEscape(manufacturer), url.PathEscape(model), pageQuery(opts)), nil
+	case byManufacturer:
+		return fmt.Sprintf("%s%s/manufacturer/%s%s", baseURL(address), apiPath(version, DeviceProfilePath), url.PathEscape(manufacturer), pageQuery(opts)), nil
+	case byModel:
+		return fmt.Sprintf("%s%s/model/%s%s", baseURL(address), apiPath(version, DeviceProfilePath), url.PathEscape(model), pageQuery(opts)), nil
+	}
+	return withLabelsQuery(fmt.Sprintf("%s%s/all%s", baseURL(address), apiPath(version, DeviceProfilePath), pageQuery(opts)), opts.LabelSelector), nil
+}
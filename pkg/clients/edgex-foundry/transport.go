@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultTLSReloadPeriod is how often the TLS material is checked for rotation
+	defaultTLSReloadPeriod = 30 * time.Second
+	// tlsReloadTimeout bounds the time spent on loading the TLS material, e.g. reading the Secret
+	tlsReloadTimeout = 10 * time.Second
+)
+
+// sharedTransport is the HTTP transport shared by the EdgeX clients. The TLS material is checked periodically,
+// and once it rotates the requests are sent through a new transport built with it, the idle connections
+// of the old transport are closed at once and the busy ones after their idle timeout
+type sharedTransport struct {
+	tls    clients.TLSFunc
+	period time.Duration
+	// now is replaceable for testing
+	now func() time.Time
+
+	// current is the *http.Transport built with the current TLS material, which the requests load without locking
+	current atomic.Value
+	// mu guards the fields below and the swap of current, it is never held while the TLS material is loaded
+	mu        sync.Mutex
+	digest    [sha256.Size]byte
+	checkedAt time.Time
+}
+
+// newSharedTransport returns the transport with the TLS material returned by tlsFunc,
+// the transport uses the system CA bundle if tlsFunc is nil
+func newSharedTransport(tlsFunc clients.TLSFunc) (*sharedTransport, error) {
+	t := &sharedTransport{
+		tls:    tlsFunc,
+		period: defaultTLSReloadPeriod,
+		now:    time.Now,
+	}
+	if tlsFunc == nil {
+		t.current.Store(newHTTPTransport(nil))
+		return t, nil
+	}
+	t.checkedAt = t.now()
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *sharedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport().RoundTrip(req)
+}
+
+// transport returns the transport built with the current TLS material. The request which finds the reload period
+// elapsed reloads the TLS material, while the other requests go on with the current transport, and a failed reload
+// keeps the current transport
+func (t *sharedTransport) transport() *http.Transport {
+	if t.tls != nil && t.reloadDue() {
+		if err := t.reload(); err != nil {
+			klog.ErrorS(err, "failed to reload the TLS material of EdgeX, keep using the previous one")
+		}
+	}
+	return t.current.Load().(*http.Transport)
+}
+
+// reloadDue returns whether the reload period has elapsed, the period restarts at once
+// so that a single request reloads the TLS material
+func (t *sharedTransport) reloadDue() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if now.Sub(t.checkedAt) < t.period {
+		return false
+	}
+	t.checkedAt = now
+	return true
+}
+
+// reload rebuilds the transport if the TLS material has changed since the last reload. The material is loaded
+// with a context of its own, so that the reload is not canceled along with the request which happens to trigger it
+func (t *sharedTransport) reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), tlsReloadTimeout)
+	defer cancel()
+	m, err := t.tls(ctx)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(bytes.Join([][]byte{m.CA, m.Cert, m.Key}, []byte{0}))
+	t.mu.Lock()
+	previous, _ := t.current.Load().(*http.Transport)
+	unchanged := previous != nil && digest == t.digest
+	t.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+	cfg, err := newTLSConfig(m)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	previous, _ = t.current.Load().(*http.Transport)
+	t.current.Store(newHTTPTransport(cfg))
+	t.digest = digest
+	t.mu.Unlock()
+	if previous != nil {
+		klog.V(3).Info("the TLS material of EdgeX has rotated, reconnect with it")
+		previous.CloseIdleConnections()
+	}
+	return nil
+}
+
+// newHTTPTransport returns a transport with the settings of the default transport and the TLS config
+func newHTTPTransport(cfg *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return transport
+}
+
+func newTLSConfig(m *clients.TLSMaterial) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(m.CA) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(m.CA) {
+			return nil, errors.New("no certificate is found in the CA bundle of EdgeX")
+		}
+		cfg.RootCAs = pool
+	}
+	if len(m.Cert) != 0 || len(m.Key) != 0 {
+		cert, err := tls.X509KeyPair(m.Cert, m.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
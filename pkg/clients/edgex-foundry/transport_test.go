@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SharedTransportReload(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(DeviceServiceMetaData))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "edgex-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// the CA bundle doesn't verify the server until it rotates
+	caFile := filepath.Join(dir, "ca.crt")
+	assert.Nil(t, ioutil.WriteFile(caFile, selfSignedCA(t), 0600))
+	transport, err := newSharedTransport(clients.FileTLS(caFile, "", ""))
+	assert.Nil(t, err)
+	now := time.Now()
+	transport.now = func() time.Time { return now }
+
+	serviceClient := newConnection(server.URL, "", "", transport).NewDeviceServiceClient()
+	serviceClient.SetRetryCount(0)
+	_, err = serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.True(t, clients.IsUnavailableErr(err))
+
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.Nil(t, ioutil.WriteFile(caFile, serverCA, 0600))
+	_, err = serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.NotNil(t, err, "the rotated CA bundle is not loaded before the reload period elapses")
+
+	now = now.Add(defaultTLSReloadPeriod)
+	ds, err := serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "device-virtual", ds.Name)
+
+	// the broken CA bundle is not loaded, the previous one is kept
+	assert.Nil(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0600))
+	now = now.Add(defaultTLSReloadPeriod)
+	_, err = serviceClient.Get(context.TODO(), "device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+
+	_, err = newSharedTransport(clients.FileTLS(caFile, "", ""))
+	assert.NotNil(t, err)
+}
+
+type tlsTestKey struct{}
+
+func Test_SharedTransportMutualTLSRotation(t *testing.T) {
+	trustedCA, trustedKey, trustedPEM := newTestCA(t, "edgex-client-ca")
+	untrustedCA, untrustedKey, _ := newTestCA(t, "other-ca")
+	pool := x509.NewCertPool()
+	assert.True(t, pool.AppendCertsFromPEM(trustedPEM))
+	var clientName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientName = r.TLS.PeerCertificates[0].Subject.CommonName
+		_, _ = w.Write([]byte(DeviceServiceMetaData))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "edgex-mtls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// the client certificate is not issued by the CA the server trusts until it rotates
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert := func(ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string) {
+		cert, key := issueClientCert(t, ca, caKey, name)
+		assert.Nil(t, ioutil.WriteFile(certFile, cert, 0600))
+		assert.Nil(t, ioutil.WriteFile(keyFile, key, 0600))
+	}
+	assert.Nil(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	writeCert(untrustedCA, untrustedKey, "edgex-client")
+
+	// the TLS material is loaded with a bounded context of its own, and the load can be held up by the test
+	var hold chan struct{}
+	loading := make(chan struct{}, 1)
+	fileTLS := clients.FileTLS(caFile, certFile, keyFile)
+	transport, err := newSharedTransport(func(ctx context.Context) (*clients.TLSMaterial, error) {
+		if _, ok := ctx.Deadline(); !ok || ctx.Value(tlsTestKey{}) != nil {
+			t.Errorf("expected the TLS material to be loaded with a bounded context of its own")
+		}
+		if hold != nil {
+			loading <- struct{}{}
+			<-hold
+		}
+		return fileTLS(ctx)
+	})
+	assert.Nil(t, err)
+	now := time.Now()
+	transport.now = func() time.Time { return now }
+
+	serviceClient := newConnection(server.URL, "", "", transport).NewDeviceServiceClient()
+	serviceClient.SetRetryCount(0)
+	ctx := context.WithValue(context.TODO(), tlsTestKey{}, "request")
+	_, err = serviceClient.Get(ctx, "device-virtual", clients.GetOptions{})
+	assert.NotNil(t, err, "the server rejects the client certificate of the other CA")
+
+	writeCert(trustedCA, trustedKey, "rotated-client")
+	now = now.Add(defaultTLSReloadPeriod)
+	ds, err := serviceClient.Get(ctx, "device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "device-virtual", ds.Name)
+	assert.Equal(t, "rotated-client", clientName)
+
+	// the other requests go on with the current transport while the TLS material is being loaded
+	hold = make(chan struct{})
+	now = now.Add(defaultTLSReloadPeriod)
+	done := make(chan *http.Transport)
+	go func() { done <- transport.transport() }()
+	<-loading
+	current := make(chan *http.Transport)
+	go func() { current <- transport.transport() }()
+	select {
+	case rt := <-current:
+		assert.NotNil(t, rt)
+	case <-time.After(time.Second):
+		t.Fatal("expected the request not to wait for the TLS material being loaded")
+	}
+	close(hold)
+	assert.NotNil(t, <-done)
+}
+
+func Test_BaseURL(t *testing.T) {
+	assert.Equal(t, "http://edgex-core-metadata:59881", baseURL("edgex-core-metadata:59881"))
+	assert.Equal(t, "https://edgex-nginx:8443/core-metadata", baseURL("https://edgex-nginx:8443/core-metadata/"))
+
+	commandURL := "http://edgex-core-command:59882"
+	assert.Equal(t, commandURL, rebaseURL(commandURL, "edgex-core-command:59882"))
+	assert.Equal(t, "https://edgex-nginx:8443/core-command", rebaseURL(commandURL, "https://edgex-nginx:8443/core-command"))
+	assert.Equal(t, "https://edgex-core-command:48082/api/v1/device/0e6ff6b0/command/c2b6bbde",
+		rebaseURL("http://edgex-core-command:48082/api/v1/device/0e6ff6b0/command/c2b6bbde", "https://edgex-core-command:48082"))
+}
+
+// selfSignedCA returns a CA which doesn't sign the certificate of the test server
+func selfSignedCA(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "edgex-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// newTestCA returns a CA which issues the test certificates, along with its key and its PEM encoding
+func newTestCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	ca, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return ca, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// issueClientCert returns the PEM encoded client certificate issued by the CA and its key
+func issueClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
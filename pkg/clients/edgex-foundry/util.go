@@ -17,8 +17,9 @@ limitations under the License.
 package edgex_foundry
 
 import (
-	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
@@ -35,10 +36,50 @@ const (
 	DeviceProfilePath   = "/api/v2/deviceprofile"
 	DevicePath          = "/api/v2/device"
 	CommandResponsePath = "/api/v2/device"
+	ReadingPath         = "/api/v2/reading"
 
 	APIVersionV2 = "v2"
+
+	// DefaultRequestTimeout is the timeout of a request to EdgeX if it is not specified
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultNamespace is the namespace of the objects converted from EdgeX if it is not specified
+	DefaultNamespace = "default"
 )
 
+// baseURL returns the base URL of the EdgeX service at the address,
+// the address without a scheme, e.g. "edgex-core-metadata:59881", is served over http
+func baseURL(addr string) string {
+	if hasScheme(addr) {
+		return strings.TrimSuffix(addr, "/")
+	}
+	return "http://" + addr
+}
+
+func hasScheme(addr string) bool {
+	return strings.Contains(addr, "://")
+}
+
+// rebaseURL moves the URL returned by EdgeX, e.g. the URL of a core command, onto the address given with a scheme,
+// so that it is reached the same way as the address, e.g. over https or through the API gateway.
+// The URL is kept if the address has no scheme
+func rebaseURL(rawURL, addr string) string {
+	if !hasScheme(addr) || rawURL == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	base, err := url.Parse(addr)
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme, u.Host = base.Scheme, base.Host
+	u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+	u.RawPath = ""
+	return u.String()
+}
+
 type ClientURL struct {
 	Host string
 	Port int
@@ -56,6 +97,8 @@ func getEdgeXName(provider metav1.Object) string {
 
 func toEdgexDeviceService(ds *devicev1alpha1.DeviceService) dtos.DeviceService {
 	return dtos.DeviceService{
+		DBTimestamp:   getEdgeXTimestamps(ds),
+		Id:            ds.Status.EdgeId,
 		Description:   ds.Spec.Description,
 		Name:          getEdgeXName(ds),
 		LastConnected: ds.Status.LastConnected,
@@ -66,26 +109,54 @@ func toEdgexDeviceService(ds *devicev1alpha1.DeviceService) dtos.DeviceService {
 	}
 }
 
-func toEdgeXDeviceResourceSlice(drs []devicev1alpha1.DeviceResource) []dtos.DeviceResource {
-	var ret []dtos.DeviceResource
-	for _, dr := range drs {
-		ret = append(ret, toEdgeXDeviceResource(dr))
+// toEdgeXUpdateDeviceService converts the deviceService to the EdgeX UpdateDeviceService which only carries the given fields,
+// the fields that are not carried remain unchanged on EdgeX, and all fields are carried if no field is given
+func toEdgeXUpdateDeviceService(ds *devicev1alpha1.DeviceService, fields []string) dtos.UpdateDeviceService {
+	name := getEdgeXName(ds)
+	uds := dtos.UpdateDeviceService{
+		Name: &name,
 	}
-	return ret
+	if ds.Status.EdgeId != "" {
+		uds.Id = &ds.Status.EdgeId
+	}
+	if isFieldUpdated(fields, "description") {
+		uds.Description = &ds.Spec.Description
+	}
+	if isFieldUpdated(fields, "baseAddress") {
+		uds.BaseAddress = &ds.Spec.BaseAddress
+	}
+	if isFieldUpdated(fields, "labels") {
+		// an empty but non-nil list is required to remove all the labels on EdgeX
+		uds.Labels = append([]string{}, ds.Spec.Labels...)
+	}
+	if isFieldUpdated(fields, "adminState") {
+		adminState := string(toEdgeXAdminState(ds.Spec.AdminState))
+		uds.AdminState = &adminState
+	}
+	return uds
 }
 
-func toEdgeXDeviceResource(dr devicev1alpha1.DeviceResource) dtos.DeviceResource {
-	genericAttrs := make(map[string]interface{})
-	for k, v := range dr.Attributes {
-		genericAttrs[k] = v
+// toEdgeXDeviceResourceSlice converts the deviceResources, typed lists the attributes of each deviceResource
+// whose values are encoded as JSON
+func toEdgeXDeviceResourceSlice(drs []devicev1alpha1.DeviceResource, typed map[string][]string) []dtos.DeviceResource {
+	if drs == nil {
+		return nil
+	}
+	ret := make([]dtos.DeviceResource, 0, len(drs))
+	for _, dr := range drs {
+		ret = append(ret, toEdgeXDeviceResource(dr, typed[dr.Name]))
 	}
+	return ret
+}
 
+func toEdgeXDeviceResource(dr devicev1alpha1.DeviceResource, typedAttrs []string) dtos.DeviceResource {
 	return dtos.DeviceResource{
 		Description: dr.Description,
 		Name:        dr.Name,
+		IsHidden:    dr.IsHidden,
 		Tag:         dr.Tag,
 		Properties:  toEdgeXProfileProperty(dr.Properties),
-		Attributes:  genericAttrs,
+		Attributes:  toEdgeXAttributes(dr.Attributes, typedAttrs),
 	}
 }
 
@@ -107,11 +178,11 @@ func toEdgeXProfileProperty(pp devicev1alpha1.ResourceProperties) dtos.ResourceP
 	}
 }
 
-func toKubeDeviceService(ds dtos.DeviceService) devicev1alpha1.DeviceService {
-	return devicev1alpha1.DeviceService{
+func toKubeDeviceService(ds dtos.DeviceService, namespace string) devicev1alpha1.DeviceService {
+	kds := devicev1alpha1.DeviceService{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      toKubeName(ds.Name),
-			Namespace: "default",
+			Namespace: namespace,
 			Labels: map[string]string{
 				EdgeXObjectName: ds.Name,
 			},
@@ -129,10 +200,13 @@ func toKubeDeviceService(ds dtos.DeviceService) devicev1alpha1.DeviceService {
 			AdminState:    devicev1alpha1.AdminState(ds.AdminState),
 		},
 	}
+	setEdgeXTimestamps(&kds, ds.DBTimestamp)
+	return kds
 }
 
 func toEdgeXDevice(d *devicev1alpha1.Device) dtos.Device {
 	md := dtos.Device{
+		DBTimestamp:    getEdgeXTimestamps(d),
 		Description:    d.Spec.Description,
 		Name:           getEdgeXName(d),
 		AdminState:     string(toEdgeXAdminState(d.Spec.AdminState)),
@@ -141,9 +215,10 @@ func toEdgeXDevice(d *devicev1alpha1.Device) dtos.Device {
 		LastConnected:  d.Status.LastConnected,
 		LastReported:   d.Status.LastReported,
 		Labels:         d.Spec.Labels,
-		Location:       d.Spec.Location,
+		Location:       toEdgeXLocation(d, d.Spec.Location),
 		ServiceName:    d.Spec.Service,
 		ProfileName:    d.Spec.Profile,
+		AutoEvents:     toEdgeXAutoEvents(d.Spec.AutoEvents),
 	}
 	if d.Status.EdgeId != "" {
 		md.Id = d.Status.EdgeId
@@ -151,29 +226,73 @@ func toEdgeXDevice(d *devicev1alpha1.Device) dtos.Device {
 	return md
 }
 
-func toEdgeXUpdateDevice(d *devicev1alpha1.Device) dtos.UpdateDevice {
-	adminState := string(toEdgeXAdminState(d.Spec.AdminState))
-	operationState := string(toEdgeXOperatingState(d.Spec.OperatingState))
+// toEdgeXUpdateDevice converts the device to the EdgeX UpdateDevice which only carries the given fields,
+// the fields that are not carried remain unchanged on EdgeX, and all fields are carried if no field is given
+func toEdgeXUpdateDevice(d *devicev1alpha1.Device, fields []string) dtos.UpdateDevice {
+	name := getEdgeXName(d)
 	md := dtos.UpdateDevice{
-		Description:    &d.Spec.Description,
-		Name:           &d.Name,
-		AdminState:     &adminState,
-		OperatingState: &operationState,
-		Protocols:      toEdgeXProtocols(d.Spec.Protocols),
-		LastConnected:  &d.Status.LastConnected,
-		LastReported:   &d.Status.LastReported,
-		Labels:         d.Spec.Labels,
-		Location:       d.Spec.Location,
-		ServiceName:    &d.Spec.Service,
-		ProfileName:    &d.Spec.Profile,
-		Notify:         &d.Spec.Notify,
+		Name: &name,
 	}
 	if d.Status.EdgeId != "" {
 		md.Id = &d.Status.EdgeId
 	}
+	// Notify never comes back from EdgeX, so it is only sent if the user sets it
+	if d.Spec.Notify {
+		md.Notify = &d.Spec.Notify
+	}
+	if isFieldUpdated(fields, "description") {
+		md.Description = &d.Spec.Description
+	}
+	if isFieldUpdated(fields, "adminState") {
+		adminState := string(toEdgeXAdminState(d.Spec.AdminState))
+		md.AdminState = &adminState
+	}
+	if isFieldUpdated(fields, "operatingState") {
+		operatingState := string(toEdgeXOperatingState(d.Spec.OperatingState))
+		md.OperatingState = &operatingState
+	}
+	if isFieldUpdated(fields, "protocols") {
+		md.Protocols = toEdgeXProtocols(d.Spec.Protocols)
+	}
+	if isFieldUpdated(fields, "labels") {
+		// an empty but non-nil list is required to remove all the labels on EdgeX
+		md.Labels = append([]string{}, d.Spec.Labels...)
+	}
+	if isFieldUpdated(fields, "location") {
+		md.Location = toEdgeXLocation(d, d.Spec.Location)
+		if md.Location == nil {
+			// an empty string is required to remove the location on EdgeX
+			md.Location = ""
+		}
+	}
+	if isFieldUpdated(fields, "serviceName") {
+		md.ServiceName = &d.Spec.Service
+	}
+	if isFieldUpdated(fields, "profileName") {
+		md.ProfileName = &d.Spec.Profile
+	}
+	if isFieldUpdated(fields, "autoEvents") {
+		md.AutoEvents = toEdgeXAutoEvents(d.Spec.AutoEvents)
+		if md.AutoEvents == nil {
+			md.AutoEvents = []dtos.AutoEvent{}
+		}
+	}
 	return md
 }
 
+// isFieldUpdated checks whether the field should be carried by the update request
+func isFieldUpdated(fields []string, field string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 func toEdgeXProtocols(
 	pps map[string]devicev1alpha1.ProtocolProperties) map[string]dtos.ProtocolProperties {
 	ret := map[string]dtos.ProtocolProperties{}
@@ -183,6 +302,23 @@ func toEdgeXProtocols(
 	return ret
 }
 
+// toEdgeXAutoEvents converts the Kubernetes AutoEvents to the EdgeX AutoEvents,
+// a nil slice is kept as nil so that EdgeX leaves the autoEvents of the device unchanged on update
+func toEdgeXAutoEvents(aes []devicev1alpha1.AutoEvent) []dtos.AutoEvent {
+	if aes == nil {
+		return nil
+	}
+	ret := make([]dtos.AutoEvent, 0, len(aes))
+	for _, ae := range aes {
+		ret = append(ret, dtos.AutoEvent{
+			Interval:   ae.Interval,
+			OnChange:   ae.OnChange,
+			SourceName: ae.SourceName,
+		})
+	}
+	return ret
+}
+
 func toEdgeXAdminState(as devicev1alpha1.AdminState) models.AdminState {
 	if as == devicev1alpha1.Locked {
 		return models.Locked
@@ -200,15 +336,12 @@ func toEdgeXOperatingState(os devicev1alpha1.OperatingState) models.OperatingSta
 }
 
 // toKubeDevice serialize the EdgeX Device to the corresponding Kubernetes Device
-func toKubeDevice(ed dtos.Device) devicev1alpha1.Device {
-	var loc string
-	if ed.Location != nil {
-		loc = ed.Location.(string)
-	}
-	return devicev1alpha1.Device{
+func toKubeDevice(ed dtos.Device, namespace string) devicev1alpha1.Device {
+	loc, isJSON := toKubeLocation(ed.Location)
+	kd := devicev1alpha1.Device{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      toKubeName(ed.Name),
-			Namespace: "default",
+			Namespace: namespace,
 			Labels: map[string]string{
 				EdgeXObjectName: ed.Name,
 			},
@@ -222,7 +355,8 @@ func toKubeDevice(ed dtos.Device) devicev1alpha1.Device {
 			Location:       loc,
 			Service:        ed.ServiceName,
 			Profile:        ed.ProfileName,
-			// TODO: Notify
+			AutoEvents:     toKubeAutoEvents(ed.AutoEvents),
+			// Notify is not returned by EdgeX, so it can't be synchronized from EdgeX and is left unset
 		},
 		Status: devicev1alpha1.DeviceStatus{
 			LastConnected:  ed.LastConnected,
@@ -233,6 +367,11 @@ func toKubeDevice(ed dtos.Device) devicev1alpha1.Device {
 			OperatingState: devicev1alpha1.OperatingState(ed.OperatingState),
 		},
 	}
+	if isJSON {
+		setEdgeXAnnotation(&kd, devicev1alpha1.EdgeXLocationFormat, devicev1alpha1.EdgeXLocationFormatJSON)
+	}
+	setEdgeXTimestamps(&kd, ed.DBTimestamp)
+	return kd
 }
 
 // toKubeProtocols serialize the EdgeX ProtocolProperties to the corresponding
@@ -246,12 +385,29 @@ func toKubeProtocols(
 	return ret
 }
 
+// toKubeAutoEvents serialize the EdgeX AutoEvents to the corresponding Kubernetes AutoEvents
+func toKubeAutoEvents(eaes []dtos.AutoEvent) []devicev1alpha1.AutoEvent {
+	if eaes == nil {
+		return nil
+	}
+	ret := make([]devicev1alpha1.AutoEvent, 0, len(eaes))
+	for _, ae := range eaes {
+		ret = append(ret, devicev1alpha1.AutoEvent{
+			Interval:   ae.Interval,
+			OnChange:   ae.OnChange,
+			SourceName: ae.SourceName,
+		})
+	}
+	return ret
+}
+
 // toKubeDeviceProfile create DeviceProfile in cloud according to devicProfile in edge
-func toKubeDeviceProfile(dp *dtos.DeviceProfile) devicev1alpha1.DeviceProfile {
-	return devicev1alpha1.DeviceProfile{
+func toKubeDeviceProfile(dp *dtos.DeviceProfile, namespace string) devicev1alpha1.DeviceProfile {
+	deviceResources, typedAttrs := toKubeDeviceResources(dp.DeviceResources)
+	kdp := devicev1alpha1.DeviceProfile{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      toKubeName(dp.Name),
-			Namespace: "default",
+			Namespace: namespace,
 			Labels: map[string]string{
 				EdgeXObjectName: dp.Name,
 			},
@@ -261,7 +417,7 @@ func toKubeDeviceProfile(dp *dtos.DeviceProfile) devicev1alpha1.DeviceProfile {
 			Manufacturer:    dp.Manufacturer,
 			Model:           dp.Model,
 			Labels:          dp.Labels,
-			DeviceResources: toKubeDeviceResources(dp.DeviceResources),
+			DeviceResources: deviceResources,
 			DeviceCommands:  toKubeDeviceCommand(dp.DeviceCommands),
 		},
 		Status: devicev1alpha1.DeviceProfileStatus{
@@ -269,10 +425,16 @@ func toKubeDeviceProfile(dp *dtos.DeviceProfile) devicev1alpha1.DeviceProfile {
 			Synced: true,
 		},
 	}
+	setTypedAttributes(&kdp, typedAttrs)
+	setEdgeXTimestamps(&kdp, dp.DBTimestamp)
+	return kdp
 }
 
 func toKubeDeviceCommand(dcs []dtos.DeviceCommand) []devicev1alpha1.DeviceCommand {
-	var ret []devicev1alpha1.DeviceCommand
+	if dcs == nil {
+		return nil
+	}
+	ret := make([]devicev1alpha1.DeviceCommand, 0, len(dcs))
 	for _, dc := range dcs {
 		ret = append(ret, devicev1alpha1.DeviceCommand{
 			Name:               dc.Name,
@@ -285,7 +447,10 @@ func toKubeDeviceCommand(dcs []dtos.DeviceCommand) []devicev1alpha1.DeviceComman
 }
 
 func toEdgeXDeviceCommand(dcs []devicev1alpha1.DeviceCommand) []dtos.DeviceCommand {
-	var ret []dtos.DeviceCommand
+	if dcs == nil {
+		return nil
+	}
+	ret := make([]dtos.DeviceCommand, 0, len(dcs))
 	for _, dc := range dcs {
 		ret = append(ret, dtos.DeviceCommand{
 			Name:               dc.Name,
@@ -298,7 +463,10 @@ func toEdgeXDeviceCommand(dcs []devicev1alpha1.DeviceCommand) []dtos.DeviceComma
 }
 
 func toKubeResourceOperations(ros []dtos.ResourceOperation) []devicev1alpha1.ResourceOperation {
-	var ret []devicev1alpha1.ResourceOperation
+	if ros == nil {
+		return nil
+	}
+	ret := make([]devicev1alpha1.ResourceOperation, 0, len(ros))
 	for _, ro := range ros {
 		ret = append(ret, devicev1alpha1.ResourceOperation{
 			DeviceResource: ro.DeviceResource,
@@ -310,7 +478,10 @@ func toKubeResourceOperations(ros []dtos.ResourceOperation) []devicev1alpha1.Res
 }
 
 func toEdgeXResourceOperations(ros []devicev1alpha1.ResourceOperation) []dtos.ResourceOperation {
-	var ret []dtos.ResourceOperation
+	if ros == nil {
+		return nil
+	}
+	ret := make([]dtos.ResourceOperation, 0, len(ros))
 	for _, ro := range ros {
 		ret = append(ret, dtos.ResourceOperation{
 			DeviceResource: ro.DeviceResource,
@@ -321,41 +492,36 @@ func toEdgeXResourceOperations(ros []devicev1alpha1.ResourceOperation) []dtos.Re
 	return ret
 }
 
-func toKubeDeviceResources(drs []dtos.DeviceResource) []devicev1alpha1.DeviceResource {
-	var ret []devicev1alpha1.DeviceResource
-	for _, dr := range drs {
-		ret = append(ret, toKubeDeviceResource(dr))
+// toKubeDeviceResources converts the EdgeX deviceResources, the attributes of each deviceResource
+// whose values are not strings are returned in typed
+func toKubeDeviceResources(drs []dtos.DeviceResource) (ret []devicev1alpha1.DeviceResource, typed map[string][]string) {
+	if drs == nil {
+		return nil, nil
 	}
-	return ret
-}
-
-func toKubeDeviceResource(dr dtos.DeviceResource) devicev1alpha1.DeviceResource {
-	concreteAttrs := make(map[string]string)
-	for k, v := range dr.Attributes {
-		switch asserted := v.(type) {
-		case string:
-			concreteAttrs[k] = asserted
-			continue
-		case int:
-			concreteAttrs[k] = fmt.Sprintf("%d", asserted)
-			continue
-		case float64:
-			concreteAttrs[k] = fmt.Sprintf("%f", asserted)
-			continue
-		case fmt.Stringer:
-			concreteAttrs[k] = asserted.String()
-			continue
+	ret = make([]devicev1alpha1.DeviceResource, 0, len(drs))
+	for _, dr := range drs {
+		kdr, typedAttrs := toKubeDeviceResource(dr)
+		ret = append(ret, kdr)
+		if len(typedAttrs) != 0 {
+			if typed == nil {
+				typed = map[string][]string{}
+			}
+			typed[dr.Name] = typedAttrs
 		}
 	}
+	return ret, typed
+}
 
+func toKubeDeviceResource(dr dtos.DeviceResource) (devicev1alpha1.DeviceResource, []string) {
+	attrs, typedAttrs := toKubeAttributes(dr.Attributes)
 	return devicev1alpha1.DeviceResource{
 		Description: dr.Description,
 		Name:        dr.Name,
 		Tag:         dr.Tag,
 		IsHidden:    dr.IsHidden,
 		Properties:  toKubeProfileProperty(dr.Properties),
-		Attributes:  concreteAttrs,
-	}
+		Attributes:  attrs,
+	}, typedAttrs
 }
 
 func toKubeProfileProperty(rp dtos.ResourceProperties) devicev1alpha1.ResourceProperties {
@@ -379,12 +545,14 @@ func toKubeProfileProperty(rp dtos.ResourceProperties) devicev1alpha1.ResourcePr
 // toEdgeXDeviceProfile create DeviceProfile in edge according to devicProfile in cloud
 func toEdgeXDeviceProfile(dp *devicev1alpha1.DeviceProfile) dtos.DeviceProfile {
 	return dtos.DeviceProfile{
+		DBTimestamp:     getEdgeXTimestamps(dp),
+		Id:              dp.Status.EdgeId,
 		Description:     dp.Spec.Description,
 		Name:            getEdgeXName(dp),
 		Manufacturer:    dp.Spec.Manufacturer,
 		Model:           dp.Spec.Model,
 		Labels:          dp.Spec.Labels,
-		DeviceResources: toEdgeXDeviceResourceSlice(dp.Spec.DeviceResources),
+		DeviceResources: toEdgeXDeviceResourceSlice(dp.Spec.DeviceResources, getTypedAttributes(dp)),
 		DeviceCommands:  toEdgeXDeviceCommand(dp.Spec.DeviceCommands),
 	}
 }
@@ -404,7 +572,7 @@ func makeEdgeXDeviceProfilesRequest(dps []*devicev1alpha1.DeviceProfile) []*requ
 	return req
 }
 
-func makeEdgeXDeviceUpdateRequest(devs []*devicev1alpha1.Device) []*requests.UpdateDeviceRequest {
+func makeEdgeXDeviceUpdateRequest(devs []*devicev1alpha1.Device, fields []string) []*requests.UpdateDeviceRequest {
 	var req []*requests.UpdateDeviceRequest
 	for _, dev := range devs {
 		req = append(req, &requests.UpdateDeviceRequest{
@@ -413,7 +581,7 @@ func makeEdgeXDeviceUpdateRequest(devs []*devicev1alpha1.Device) []*requests.Upd
 					ApiVersion: APIVersionV2,
 				},
 			},
-			Device: toEdgeXUpdateDevice(dev),
+			Device: toEdgeXUpdateDevice(dev, fields),
 		})
 	}
 	return req
@@ -449,6 +617,21 @@ func makeEdgeXDeviceService(dss []*devicev1alpha1.DeviceService) []*requests.Add
 	return req
 }
 
+func makeEdgeXDeviceServiceUpdateRequest(dss []*devicev1alpha1.DeviceService, fields []string) []*requests.UpdateDeviceServiceRequest {
+	var req []*requests.UpdateDeviceServiceRequest
+	for _, ds := range dss {
+		req = append(req, &requests.UpdateDeviceServiceRequest{
+			BaseRequest: common.BaseRequest{
+				Versionable: common.Versionable{
+					ApiVersion: APIVersionV2,
+				},
+			},
+			Service: toEdgeXUpdateDeviceService(ds, fields),
+		})
+	}
+	return req
+}
+
 func toKubeName(edgexName string) string {
 	return strings.ReplaceAll(strings.ToLower(edgexName), "_", "-")
 }
@@ -0,0 +1,814 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-resty/resty/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// The EdgeX v1 API is served by EdgeX 1.x, e.g. Hanoi. Like the v3 API, the v1 objects are converted to and from
+// the v2 ones, so that the v2 conversions of the package are shared. The v1 API differs from the v2 API in:
+//   - the objects are added and updated one by one, and the id of the added object is returned as plain text
+//   - the objects are listed all at once, there are no pages
+//   - the device references the full service and profile objects, and its operating state is ENABLED or DISABLED
+//   - the deviceService is reached through an addressable instead of a base address
+//   - the deviceProfile lists the get and set operations of its deviceCommands, and the coreCommands
+//     which are served by core-command
+//   - core-command returns the full URL of each command, e.g. http://edgex-core-command:48082/api/v1/device/{id}/command/{id}
+
+const (
+	// APIVersionV1 is the API version of EdgeX 1.x
+	APIVersionV1 = "v1"
+	// V1VersionPath is the endpoint which reports the version of an EdgeX 1.x service
+	V1VersionPath = "/api/version"
+	// V1AddressablePath is the endpoint of the addressables which the v1 deviceServices are reached through
+	V1AddressablePath = "/api/v1/addressable"
+	// V1CallbackPath is the path of the callback endpoint of the v1 deviceServices
+	V1CallbackPath = "/api/v1/callback"
+
+	v1OperatingStateEnabled  = "ENABLED"
+	v1OperatingStateDisabled = "DISABLED"
+)
+
+// v1Fields holds the fields of the EdgeX v1 object which have no counterpart in the v2 object
+type v1Fields struct {
+	// OperatingState is the operating state of the deviceService
+	OperatingState string         `json:"operatingState,omitempty"`
+	Addressable    *v1Addressable `json:"addressable,omitempty"`
+	// DeviceResources holds the properties of the deviceResources of the deviceProfile by name
+	DeviceResources map[string]v1ResourceFields `json:"deviceResources,omitempty"`
+	CoreCommands    []v1Command                 `json:"coreCommands,omitempty"`
+}
+
+// v1ResourceFields holds the properties of the v1 deviceResource which have no counterpart in the v2 deviceResource
+type v1ResourceFields struct {
+	Size           string `json:"size,omitempty"`
+	Precision      string `json:"precision,omitempty"`
+	FloatEncoding  string `json:"floatEncoding,omitempty"`
+	UnitsType      string `json:"unitsType,omitempty"`
+	UnitsReadWrite string `json:"unitsReadWrite,omitempty"`
+}
+
+func (f *v1Fields) isEmpty() bool {
+	return f.OperatingState == "" && f.Addressable == nil && len(f.DeviceResources) == 0 && len(f.CoreCommands) == 0
+}
+
+// setV1Fields records the fields of the EdgeX v1 object in the annotations of the object
+func setV1Fields(obj metav1.Object, f v1Fields) {
+	if f.isEmpty() {
+		return
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to encode the EdgeX v1 fields", "name", obj.GetName())
+		return
+	}
+	setEdgeXAnnotation(obj, devicev1alpha1.EdgeXV1Fields, string(b))
+}
+
+// getV1Fields returns the fields of the EdgeX v1 object recorded in the annotations of the object
+func getV1Fields(obj metav1.Object) v1Fields {
+	var f v1Fields
+	v, ok := obj.GetAnnotations()[devicev1alpha1.EdgeXV1Fields]
+	if !ok {
+		return f
+	}
+	if err := json.Unmarshal([]byte(v), &f); err != nil {
+		klog.V(4).ErrorS(err, "fail to decode the EdgeX v1 fields", "name", obj.GetName())
+	}
+	return f
+}
+
+type v1Timestamps struct {
+	Created  int64 `json:"created,omitempty"`
+	Modified int64 `json:"modified,omitempty"`
+	Origin   int64 `json:"origin,omitempty"`
+}
+
+// v1Reference is the service or profile referenced by a v1 device, EdgeX returns the full objects
+// but only their names are needed
+type v1Reference struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// v1Device is both added and updated, the slices, maps and location are not omitted when they are empty,
+// since EdgeX leaves them unchanged on update only if they are null
+type v1Device struct {
+	v1Timestamps
+	Description    string                       `json:"description,omitempty"`
+	Id             string                       `json:"id,omitempty"`
+	Name           string                       `json:"name"`
+	AdminState     string                       `json:"adminState,omitempty"`
+	OperatingState string                       `json:"operatingState,omitempty"`
+	Protocols      map[string]map[string]string `json:"protocols"`
+	LastConnected  int64                        `json:"lastConnected,omitempty"`
+	LastReported   int64                        `json:"lastReported,omitempty"`
+	Labels         []string                     `json:"labels"`
+	Location       interface{}                  `json:"location"`
+	Service        *v1Reference                 `json:"service,omitempty"`
+	Profile        *v1Reference                 `json:"profile,omitempty"`
+	AutoEvents     []v1AutoEvent                `json:"autoEvents"`
+}
+
+type v1AutoEvent struct {
+	Frequency string `json:"frequency"`
+	OnChange  bool   `json:"onChange"`
+	Resource  string `json:"resource"`
+}
+
+type v1DeviceService struct {
+	v1Timestamps
+	Description    string         `json:"description,omitempty"`
+	Id             string         `json:"id,omitempty"`
+	Name           string         `json:"name"`
+	LastConnected  int64          `json:"lastConnected,omitempty"`
+	LastReported   int64          `json:"lastReported,omitempty"`
+	OperatingState string         `json:"operatingState,omitempty"`
+	Labels         []string       `json:"labels"`
+	Addressable    *v1Addressable `json:"addressable,omitempty"`
+	AdminState     string         `json:"adminState,omitempty"`
+}
+
+// v1Addressable leaves out the password of the addressable, so that it is not kept in the annotations of
+// the deviceService, EdgeX leaves the password unchanged when the addressable is updated without it
+type v1Addressable struct {
+	v1Timestamps
+	Id         string `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Protocol   string `json:"protocol,omitempty"`
+	HTTPMethod string `json:"method,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Port       int    `json:"port,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Publisher  string `json:"publisher,omitempty"`
+	User       string `json:"user,omitempty"`
+	Topic      string `json:"topic,omitempty"`
+}
+
+type v1DeviceProfile struct {
+	v1Timestamps
+	Description     string              `json:"description,omitempty"`
+	Id              string              `json:"id,omitempty"`
+	Name            string              `json:"name"`
+	Manufacturer    string              `json:"manufacturer,omitempty"`
+	Model           string              `json:"model,omitempty"`
+	Labels          []string            `json:"labels"`
+	DeviceResources []v1DeviceResource  `json:"deviceResources"`
+	DeviceCommands  []v1ProfileResource `json:"deviceCommands"`
+	CoreCommands    []v1Command         `json:"coreCommands"`
+}
+
+type v1DeviceResource struct {
+	Description string            `json:"description"`
+	Name        string            `json:"name"`
+	Tag         string            `json:"tag,omitempty"`
+	Properties  v1ProfileProperty `json:"properties"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+type v1ProfileProperty struct {
+	Value v1PropertyValue `json:"value"`
+	Units v1Units         `json:"units"`
+}
+
+type v1PropertyValue struct {
+	Type          string `json:"type,omitempty"`
+	ReadWrite     string `json:"readWrite,omitempty"`
+	Minimum       string `json:"minimum,omitempty"`
+	Maximum       string `json:"maximum,omitempty"`
+	DefaultValue  string `json:"defaultValue,omitempty"`
+	Size          string `json:"size,omitempty"`
+	Mask          string `json:"mask,omitempty"`
+	Shift         string `json:"shift,omitempty"`
+	Scale         string `json:"scale,omitempty"`
+	Offset        string `json:"offset,omitempty"`
+	Base          string `json:"base,omitempty"`
+	Assertion     string `json:"assertion,omitempty"`
+	Precision     string `json:"precision,omitempty"`
+	FloatEncoding string `json:"floatEncoding,omitempty"`
+	MediaType     string `json:"mediaType,omitempty"`
+}
+
+type v1Units struct {
+	Type         string `json:"type,omitempty"`
+	ReadWrite    string `json:"readWrite,omitempty"`
+	DefaultValue string `json:"defaultValue,omitempty"`
+}
+
+// v1ProfileResource is a deviceCommand of the v1 deviceProfile
+type v1ProfileResource struct {
+	Name string                `json:"name"`
+	Get  []v1ResourceOperation `json:"get,omitempty"`
+	Set  []v1ResourceOperation `json:"set,omitempty"`
+}
+
+type v1ResourceOperation struct {
+	Index          string            `json:"index,omitempty"`
+	Operation      string            `json:"operation,omitempty"`
+	DeviceResource string            `json:"deviceResource,omitempty"`
+	Parameter      string            `json:"parameter,omitempty"`
+	Mappings       map[string]string `json:"mappings,omitempty"`
+}
+
+// v1Command is a coreCommand of the v1 deviceProfile, which is served by core-command along with its URL
+type v1Command struct {
+	Id   string   `json:"id,omitempty"`
+	Name string   `json:"name"`
+	Get  v1Action `json:"get"`
+	Put  v1Put    `json:"put"`
+}
+
+type v1Action struct {
+	Path      string       `json:"path,omitempty"`
+	Responses []v1Response `json:"responses,omitempty"`
+	URL       string       `json:"url,omitempty"`
+}
+
+type v1Put struct {
+	v1Action
+	ParameterNames []string `json:"parameterNames,omitempty"`
+}
+
+type v1Response struct {
+	Code           string   `json:"code,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	ExpectedValues []string `json:"expectedValues,omitempty"`
+}
+
+// v1CommandResponse is the device returned by core-command along with its commands
+type v1CommandResponse struct {
+	Id       string      `json:"id"`
+	Name     string      `json:"name"`
+	Commands []v1Command `json:"commands"`
+}
+
+type v1Event struct {
+	Id       string      `json:"id,omitempty"`
+	Device   string      `json:"device"`
+	Origin   int64       `json:"origin"`
+	Readings []v1Reading `json:"readings"`
+}
+
+type v1Reading struct {
+	Id          string `json:"id,omitempty"`
+	Origin      int64  `json:"origin"`
+	Device      string `json:"device"`
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	ValueType   string `json:"valueType,omitempty"`
+	BinaryValue []byte `json:"binaryValue,omitempty"`
+	MediaType   string `json:"mediaType,omitempty"`
+}
+
+func (ts v1Timestamps) toDBTimestamp() dtos.DBTimestamp {
+	return dtos.DBTimestamp{Created: ts.Created, Modified: ts.Modified}
+}
+
+func fromDBTimestamp(ts dtos.DBTimestamp) v1Timestamps {
+	return v1Timestamps{Created: ts.Created, Modified: ts.Modified}
+}
+
+// fromV1OperatingState converts the v1 operating state, ENABLED or DISABLED, to UP or DOWN
+func fromV1OperatingState(os string) string {
+	switch os {
+	case v1OperatingStateEnabled:
+		return string(devicev1alpha1.Up)
+	case v1OperatingStateDisabled:
+		return string(devicev1alpha1.Down)
+	}
+	return string(devicev1alpha1.Unknown)
+}
+
+// toV1OperatingState converts the operating state to the v1 one, the v1 states are kept as they are.
+// EdgeX 1.x has no unknown state so that it is regarded as enabled
+func toV1OperatingState(os devicev1alpha1.OperatingState) string {
+	if os == devicev1alpha1.Down || os == v1OperatingStateDisabled {
+		return v1OperatingStateDisabled
+	}
+	return v1OperatingStateEnabled
+}
+
+func fromV1Device(d v1Device) dtos.Device {
+	ret := dtos.Device{
+		DBTimestamp:    d.toDBTimestamp(),
+		Id:             d.Id,
+		Name:           d.Name,
+		Description:    d.Description,
+		AdminState:     d.AdminState,
+		OperatingState: fromV1OperatingState(d.OperatingState),
+		LastConnected:  d.LastConnected,
+		LastReported:   d.LastReported,
+		Labels:         d.Labels,
+		Location:       d.Location,
+	}
+	if d.Service != nil {
+		ret.ServiceName = d.Service.Name
+	}
+	if d.Profile != nil {
+		ret.ProfileName = d.Profile.Name
+	}
+	if d.Protocols != nil {
+		ret.Protocols = make(map[string]dtos.ProtocolProperties, len(d.Protocols))
+		for name, pp := range d.Protocols {
+			ret.Protocols[name] = pp
+		}
+	}
+	for _, ae := range d.AutoEvents {
+		ret.AutoEvents = append(ret.AutoEvents, dtos.AutoEvent{Interval: ae.Frequency, OnChange: ae.OnChange, SourceName: ae.Resource})
+	}
+	return ret
+}
+
+func toV1Device(d *devicev1alpha1.Device) v1Device {
+	ed := toEdgeXDevice(d)
+	return v1Device{
+		v1Timestamps:   fromDBTimestamp(ed.DBTimestamp),
+		Id:             ed.Id,
+		Name:           ed.Name,
+		Description:    ed.Description,
+		AdminState:     ed.AdminState,
+		OperatingState: toV1OperatingState(d.Spec.OperatingState),
+		Protocols:      toV1Protocols(ed.Protocols),
+		LastConnected:  ed.LastConnected,
+		LastReported:   ed.LastReported,
+		Labels:         ed.Labels,
+		Location:       ed.Location,
+		Service:        &v1Reference{Name: ed.ServiceName},
+		Profile:        &v1Reference{Name: ed.ProfileName},
+		AutoEvents:     toV1AutoEvents(ed.AutoEvents),
+	}
+}
+
+// toV1UpdateDevice converts the device to the v1 device which only carries the given fields like toEdgeXUpdateDevice,
+// the strings left empty and the fields left null remain unchanged on EdgeX
+func toV1UpdateDevice(d *devicev1alpha1.Device, fields []string) v1Device {
+	ud := toEdgeXUpdateDevice(d, fields)
+	ret := v1Device{
+		Name:       *ud.Name,
+		Labels:     ud.Labels,
+		Location:   ud.Location,
+		AutoEvents: toV1AutoEvents(ud.AutoEvents),
+	}
+	if ud.Id != nil {
+		ret.Id = *ud.Id
+	}
+	if ud.Description != nil {
+		ret.Description = *ud.Description
+	}
+	if ud.AdminState != nil {
+		ret.AdminState = *ud.AdminState
+	}
+	if ud.OperatingState != nil {
+		ret.OperatingState = toV1OperatingState(d.Spec.OperatingState)
+	}
+	if ud.Protocols != nil {
+		ret.Protocols = toV1Protocols(ud.Protocols)
+	}
+	if ud.ServiceName != nil {
+		ret.Service = &v1Reference{Name: *ud.ServiceName}
+	}
+	if ud.ProfileName != nil {
+		ret.Profile = &v1Reference{Name: *ud.ProfileName}
+	}
+	return ret
+}
+
+func toV1Protocols(pps map[string]dtos.ProtocolProperties) map[string]map[string]string {
+	ret := make(map[string]map[string]string, len(pps))
+	for name, pp := range pps {
+		ret[name] = pp
+	}
+	return ret
+}
+
+func toV1AutoEvents(aes []dtos.AutoEvent) []v1AutoEvent {
+	if aes == nil {
+		return nil
+	}
+	ret := make([]v1AutoEvent, 0, len(aes))
+	for _, ae := range aes {
+		ret = append(ret, v1AutoEvent{Frequency: ae.Interval, OnChange: ae.OnChange, Resource: ae.SourceName})
+	}
+	return ret
+}
+
+func fromV1DeviceService(ds v1DeviceService) (dtos.DeviceService, v1Fields) {
+	ret := dtos.DeviceService{
+		DBTimestamp:   ds.toDBTimestamp(),
+		Id:            ds.Id,
+		Name:          ds.Name,
+		Description:   ds.Description,
+		LastConnected: ds.LastConnected,
+		LastReported:  ds.LastReported,
+		Labels:        ds.Labels,
+		AdminState:    ds.AdminState,
+	}
+	f := v1Fields{OperatingState: ds.OperatingState, Addressable: ds.Addressable}
+	if a := ds.Addressable; a != nil && a.Address != "" {
+		protocol := strings.ToLower(a.Protocol)
+		if protocol == "" {
+			protocol = "http"
+		}
+		ret.BaseAddress = fmt.Sprintf("%s://%s:%d", protocol, a.Address, a.Port)
+	}
+	return ret, f
+}
+
+func toV1DeviceService(ds *devicev1alpha1.DeviceService) v1DeviceService {
+	eds := toEdgexDeviceService(ds)
+	f := getV1Fields(ds)
+	operatingState := f.OperatingState
+	if operatingState == "" {
+		operatingState = v1OperatingStateEnabled
+	}
+	addressable := toV1Addressable(eds.Name, eds.BaseAddress, f.Addressable)
+	return v1DeviceService{
+		v1Timestamps:   fromDBTimestamp(eds.DBTimestamp),
+		Id:             eds.Id,
+		Name:           eds.Name,
+		Description:    eds.Description,
+		LastConnected:  eds.LastConnected,
+		LastReported:   eds.LastReported,
+		OperatingState: operatingState,
+		Labels:         eds.Labels,
+		Addressable:    &addressable,
+		AdminState:     eds.AdminState,
+	}
+}
+
+// toV1UpdateDeviceService converts the deviceService to the v1 deviceService which only carries the given fields,
+// the addressable is only referenced by its name since EdgeX doesn't update it along with the deviceService
+func toV1UpdateDeviceService(ds *devicev1alpha1.DeviceService, fields []string) v1DeviceService {
+	uds := toEdgeXUpdateDeviceService(ds, fields)
+	ret := v1DeviceService{
+		Name:        *uds.Name,
+		Labels:      uds.Labels,
+		Addressable: &v1Addressable{Name: toV1DeviceService(ds).Addressable.Name},
+	}
+	if uds.Id != nil {
+		ret.Id = *uds.Id
+	}
+	if uds.Description != nil {
+		ret.Description = *uds.Description
+	}
+	if uds.AdminState != nil {
+		ret.AdminState = *uds.AdminState
+	}
+	return ret
+}
+
+// toV1Addressable returns the addressable of the deviceService which is reached at the base address,
+// the other fields of the addressable are kept from the one the deviceService was read with
+func toV1Addressable(name, baseAddress string, old *v1Addressable) v1Addressable {
+	a := v1Addressable{Name: name, HTTPMethod: http.MethodPost, Path: V1CallbackPath}
+	if old != nil {
+		a = *old
+	}
+	if baseAddress == "" {
+		return a
+	}
+	u, err := url.Parse(baseAddress)
+	if err != nil || u.Hostname() == "" {
+		klog.V(4).ErrorS(err, "fail to parse the base address of deviceService", "DeviceServiceName", name, "baseAddress", baseAddress)
+		return a
+	}
+	a.Protocol = strings.ToUpper(u.Scheme)
+	a.Address = u.Hostname()
+	a.Port = 80
+	if u.Scheme == "https" {
+		a.Port = 443
+	}
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		a.Port = port
+	}
+	return a
+}
+
+func fromV1DeviceProfile(dp v1DeviceProfile) (dtos.DeviceProfile, v1Fields) {
+	ret := dtos.DeviceProfile{
+		DBTimestamp:  dp.toDBTimestamp(),
+		Id:           dp.Id,
+		Name:         dp.Name,
+		Manufacturer: dp.Manufacturer,
+		Description:  dp.Description,
+		Model:        dp.Model,
+		Labels:       dp.Labels,
+	}
+	f := v1Fields{CoreCommands: dp.CoreCommands}
+	for _, dr := range dp.DeviceResources {
+		value, units := dr.Properties.Value, dr.Properties.Units
+		edr := dtos.DeviceResource{
+			Description: dr.Description,
+			Name:        dr.Name,
+			Tag:         dr.Tag,
+			Properties: dtos.ResourceProperties{
+				ValueType:    value.Type,
+				ReadWrite:    value.ReadWrite,
+				Units:        units.DefaultValue,
+				Minimum:      value.Minimum,
+				Maximum:      value.Maximum,
+				DefaultValue: value.DefaultValue,
+				Mask:         value.Mask,
+				Shift:        value.Shift,
+				Scale:        value.Scale,
+				Offset:       value.Offset,
+				Base:         value.Base,
+				Assertion:    value.Assertion,
+				MediaType:    value.MediaType,
+			},
+		}
+		if dr.Attributes != nil {
+			edr.Attributes = make(map[string]interface{}, len(dr.Attributes))
+			for k, v := range dr.Attributes {
+				edr.Attributes[k] = v
+			}
+		}
+		ret.DeviceResources = append(ret.DeviceResources, edr)
+		rf := v1ResourceFields{
+			Size:           value.Size,
+			Precision:      value.Precision,
+			FloatEncoding:  value.FloatEncoding,
+			UnitsType:      units.Type,
+			UnitsReadWrite: units.ReadWrite,
+		}
+		if rf != (v1ResourceFields{}) {
+			if f.DeviceResources == nil {
+				f.DeviceResources = map[string]v1ResourceFields{}
+			}
+			f.DeviceResources[dr.Name] = rf
+		}
+	}
+	for _, pr := range dp.DeviceCommands {
+		ret.DeviceCommands = append(ret.DeviceCommands, fromV1ProfileResource(pr))
+	}
+	return ret, f
+}
+
+// fromV1ProfileResource converts the v1 deviceCommand, the command reads the resources of its get operations
+// and sets the resources of its set operations, whose parameters are the default values of the resources
+func fromV1ProfileResource(pr v1ProfileResource) dtos.DeviceCommand {
+	readWrite := ""
+	if len(pr.Get) != 0 {
+		readWrite += "R"
+	}
+	if len(pr.Set) != 0 {
+		readWrite += "W"
+	}
+	ops := pr.Get
+	if len(ops) == 0 {
+		ops = pr.Set
+	}
+	dc := dtos.DeviceCommand{Name: pr.Name, ReadWrite: readWrite}
+	for _, op := range ops {
+		ro := dtos.ResourceOperation{DeviceResource: op.DeviceResource, Mappings: op.Mappings}
+		for _, set := range pr.Set {
+			if set.DeviceResource == op.DeviceResource {
+				ro.DefaultValue = set.Parameter
+				break
+			}
+		}
+		dc.ResourceOperations = append(dc.ResourceOperations, ro)
+	}
+	return dc
+}
+
+func toV1DeviceProfile(dp *devicev1alpha1.DeviceProfile) v1DeviceProfile {
+	edp := toEdgeXDeviceProfile(dp)
+	f := getV1Fields(dp)
+	ret := v1DeviceProfile{
+		v1Timestamps:    fromDBTimestamp(edp.DBTimestamp),
+		Id:              edp.Id,
+		Name:            edp.Name,
+		Manufacturer:    edp.Manufacturer,
+		Description:     edp.Description,
+		Model:           edp.Model,
+		Labels:          edp.Labels,
+		DeviceResources: []v1DeviceResource{},
+		DeviceCommands:  []v1ProfileResource{},
+		CoreCommands:    f.CoreCommands,
+	}
+	for _, dr := range edp.DeviceResources {
+		props, rf := dr.Properties, f.DeviceResources[dr.Name]
+		attrs, _ := toKubeAttributes(dr.Attributes)
+		ret.DeviceResources = append(ret.DeviceResources, v1DeviceResource{
+			Description: dr.Description,
+			Name:        dr.Name,
+			Tag:         dr.Tag,
+			Properties: v1ProfileProperty{
+				Value: v1PropertyValue{
+					Type:          props.ValueType,
+					ReadWrite:     props.ReadWrite,
+					Minimum:       props.Minimum,
+					Maximum:       props.Maximum,
+					DefaultValue:  props.DefaultValue,
+					Size:          rf.Size,
+					Mask:          props.Mask,
+					Shift:         props.Shift,
+					Scale:         props.Scale,
+					Offset:        props.Offset,
+					Base:          props.Base,
+					Assertion:     props.Assertion,
+					Precision:     rf.Precision,
+					FloatEncoding: rf.FloatEncoding,
+					MediaType:     props.MediaType,
+				},
+				Units: v1Units{Type: rf.UnitsType, ReadWrite: rf.UnitsReadWrite, DefaultValue: props.Units},
+			},
+			Attributes: attrs,
+		})
+	}
+	for _, dc := range edp.DeviceCommands {
+		ret.DeviceCommands = append(ret.DeviceCommands, toV1ProfileResource(dc))
+	}
+	if ret.CoreCommands == nil {
+		ret.CoreCommands = makeV1Commands(edp)
+	}
+	return ret
+}
+
+func toV1ProfileResource(dc dtos.DeviceCommand) v1ProfileResource {
+	pr := v1ProfileResource{Name: dc.Name}
+	for i, ro := range dc.ResourceOperations {
+		index := strconv.Itoa(i + 1)
+		if strings.Contains(dc.ReadWrite, "R") {
+			pr.Get = append(pr.Get, v1ResourceOperation{
+				Index: index, Operation: "get", DeviceResource: ro.DeviceResource, Mappings: ro.Mappings,
+			})
+		}
+		if strings.Contains(dc.ReadWrite, "W") {
+			pr.Set = append(pr.Set, v1ResourceOperation{
+				Index: index, Operation: "set", DeviceResource: ro.DeviceResource, Parameter: ro.DefaultValue, Mappings: ro.Mappings,
+			})
+		}
+	}
+	return pr
+}
+
+// makeV1Commands makes the coreCommands of the deviceProfile which was not read from EdgeX 1.x. The v2 API serves
+// both the deviceCommands and the visible deviceResources as commands, so that the coreCommands are made for them
+func makeV1Commands(dp dtos.DeviceProfile) []v1Command {
+	ret := []v1Command{}
+	named := map[string]bool{}
+	for _, dc := range dp.DeviceCommands {
+		resources := make([]string, 0, len(dc.ResourceOperations))
+		for _, ro := range dc.ResourceOperations {
+			resources = append(resources, ro.DeviceResource)
+		}
+		ret = append(ret, newV1Command(dc.Name, dc.ReadWrite, resources))
+		named[dc.Name] = true
+	}
+	for _, dr := range dp.DeviceResources {
+		if dr.IsHidden || named[dr.Name] {
+			continue
+		}
+		ret = append(ret, newV1Command(dr.Name, dr.Properties.ReadWrite, []string{dr.Name}))
+	}
+	return ret
+}
+
+func newV1Command(name, readWrite string, resources []string) v1Command {
+	path := fmt.Sprintf("%s/{deviceId}/%s", apiPath(APIVersionV1, DevicePath), name)
+	unavailable := v1Response{Code: strconv.Itoa(http.StatusServiceUnavailable), Description: "service unavailable"}
+	c := v1Command{Name: name}
+	if strings.Contains(readWrite, "R") {
+		c.Get = v1Action{Path: path, Responses: []v1Response{
+			{Code: strconv.Itoa(http.StatusOK), ExpectedValues: resources}, unavailable,
+		}}
+	}
+	if strings.Contains(readWrite, "W") {
+		c.Put = v1Put{
+			v1Action:       v1Action{Path: path, Responses: []v1Response{{Code: strconv.Itoa(http.StatusOK)}, unavailable}},
+			ParameterNames: resources,
+		}
+	}
+	return c
+}
+
+// toCoreCommand converts the command served by v1 core-command, the full URL of the command is the same for
+// reading and setting it. The parameters of the command are the resources it sets, or the ones it reads
+// if it can't be set, so that getReadingName tells the reading of the command like it does for the v2 API
+func (c v1Command) toCoreCommand() dtos.CoreCommand {
+	cc := dtos.CoreCommand{Name: c.Name, Get: c.Get.Path != "", Set: c.Put.Path != ""}
+	resources := c.Put.ParameterNames
+	if cc.Get {
+		cc.Url = c.Get.URL
+	} else {
+		cc.Url = c.Put.URL
+	}
+	if !cc.Set {
+		for _, r := range c.Get.Responses {
+			if r.Code == strconv.Itoa(http.StatusOK) {
+				resources = r.ExpectedValues
+				break
+			}
+		}
+	}
+	for _, r := range resources {
+		cc.Parameters = append(cc.Parameters, dtos.CoreCommandParameter{ResourceName: r})
+	}
+	return cc
+}
+
+func (r v1Reading) toBaseReading() dtos.BaseReading {
+	return dtos.BaseReading{
+		Id:            r.Id,
+		Origin:        r.Origin,
+		DeviceName:    r.Device,
+		ResourceName:  r.Name,
+		ValueType:     r.ValueType,
+		BinaryReading: dtos.BinaryReading{BinaryValue: r.BinaryValue, MediaType: r.MediaType},
+		SimpleReading: dtos.SimpleReading{Value: r.Value},
+	}
+}
+
+// decodeV1Event decodes the v1 event returned by a read command to the v2 one,
+// the events carrying binary readings are encoded in CBOR
+func decodeV1Event(resp *resty.Response) (dtos.Event, error) {
+	var e v1Event
+	var err error
+	if strings.HasPrefix(resp.Header().Get(common.ContentType), common.ContentTypeCBOR) {
+		err = cbor.Unmarshal(resp.Body(), &e)
+	} else {
+		err = json.Unmarshal(resp.Body(), &e)
+	}
+	event := dtos.Event{Id: e.Id, DeviceName: e.Device, Origin: e.Origin}
+	for _, r := range e.Readings {
+		event.Readings = append(event.Readings, r.toBaseReading())
+	}
+	return event, err
+}
+
+// decodeV1CoreCommands decodes the commands of the device returned by v1 core-command
+func decodeV1CoreCommands(body []byte) ([]dtos.CoreCommand, error) {
+	var cr v1CommandResponse
+	if err := json.Unmarshal(body, &cr); err != nil {
+		return nil, err
+	}
+	ret := make([]dtos.CoreCommand, 0, len(cr.Commands))
+	for _, c := range cr.Commands {
+		ret = append(ret, c.toCoreCommand())
+	}
+	return ret, nil
+}
+
+// v1PageBounds returns the bounds of the page of the n objects selected by the options, the v1 API has no pages
+// so that all the objects are listed and paged on the client side
+func v1PageBounds(n int, opts clients.ListOptions) (int, int) {
+	offset, limit := opts.Offset, opts.Limit
+	if offset > n {
+		offset = n
+	}
+	if limit <= 0 || offset+limit > n {
+		return offset, n
+	}
+	return offset, offset + limit
+}
+
+// v1Pages calls fn with the bounds of each page of the n objects listed from EdgeX 1.x,
+// the pages start from the offset of the options
+func v1Pages(n int, opts clients.ListOptions, fn func(start, end int) error) error {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = clients.DefaultPageSize
+	}
+	for start := opts.Offset; start < n; start += limit {
+		end := start + limit
+		if end > n {
+			end = n
+		}
+		if err := fn(start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"k8s.io/klog/v2"
+)
+
+// EdgexV1DeviceClient manages the devices on EdgeX 1.x through the v1 API. The properties of the devices
+// are read and set by the embedded device client, which speaks the v1 core-command API
+type EdgexV1DeviceClient struct {
+	*EdgexDeviceClient
+}
+
+func NewEdgexV1DeviceClient(coreMetaAddr, coreCommandAddr string) *EdgexV1DeviceClient {
+	efc := NewEdgexDeviceClient(coreMetaAddr, coreCommandAddr)
+	efc.APIVersion = APIVersionV1
+	return &EdgexV1DeviceClient{EdgexDeviceClient: efc}
+}
+
+// Create function sends a POST request to EdgeX to add a new device
+func (efc *EdgexV1DeviceClient) Create(ctx context.Context, device *devicev1alpha1.Device, options clients.CreateOptions) (*devicev1alpha1.Device, error) {
+	id, err := efc.create(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	createdDevice := device.DeepCopy()
+	createdDevice.Status.EdgeId = id
+	createdDevice.Status.Synced = true
+	return createdDevice, nil
+}
+
+// CreateBatch adds the devices one by one, since the v1 API can't add them in a single request
+func (efc *EdgexV1DeviceClient) CreateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options clients.CreateOptions) ([]clients.BatchResult, error) {
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	results := make([]clients.BatchResult, len(devices))
+	for i, d := range devices {
+		results[i].Name = getEdgeXName(d)
+		results[i].EdgeId, results[i].Err = efc.create(ctx, d)
+	}
+	return results, nil
+}
+
+// create adds the device and returns its id on EdgeX
+func (efc *EdgexV1DeviceClient) create(ctx context.Context, device *devicev1alpha1.Device) (string, error) {
+	name := getEdgeXName(device)
+	klog.V(5).Infof("will add the Device: %s", name)
+	reqBody, err := json.Marshal(toV1Device(device))
+	if err != nil {
+		return "", err
+	}
+	postURL := fmt.Sprintf("%s%s", baseURL(efc.CoreMetaAddr), apiPath(APIVersionV1, DevicePath))
+	resp, err := efc.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(reqBody).Post(postURL)
+	if err != nil {
+		return "", newRequestError(err, "failed to create device %s on edgex foundry", name)
+	} else if resp.StatusCode() != http.StatusOK {
+		return "", toCreateError(newResponseError(resp, "failed to create device %s on edgex foundry", name))
+	}
+	return strings.TrimSpace(string(resp.Body())), nil
+}
+
+// Update is used to update the fields of the device by unique name of the device,
+// only the fields listed in options.UpdateFields are updated if it is not empty
+func (efc *EdgexV1DeviceClient) Update(ctx context.Context, device *devicev1alpha1.Device, options clients.UpdateOptions) (*devicev1alpha1.Device, error) {
+	if device == nil {
+		return nil, nil
+	}
+	if err := efc.update(ctx, device, options.UpdateFields); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// UpdateBatch updates the devices one by one, since the v1 API can't update them in a single request
+func (efc *EdgexV1DeviceClient) UpdateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options clients.UpdateOptions) ([]clients.BatchResult, error) {
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	results := make([]clients.BatchResult, len(devices))
+	for i, d := range devices {
+		results[i].Name = getEdgeXName(d)
+		results[i].Err = efc.update(ctx, d, options.UpdateFields)
+	}
+	return results, nil
+}
+
+func (efc *EdgexV1DeviceClient) update(ctx context.Context, device *devicev1alpha1.Device, fields []string) error {
+	name := getEdgeXName(device)
+	reqBody, err := json.Marshal(toV1UpdateDevice(device, fields))
+	if err != nil {
+		return err
+	}
+	putURL := fmt.Sprintf("%s%s", baseURL(efc.CoreMetaAddr), apiPath(APIVersionV1, DevicePath))
+	resp, err := efc.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(reqBody).
+		Put(putURL)
+	if err != nil {
+		return newRequestError(err, "failed to update device %s", name)
+	} else if resp.StatusCode() != http.StatusOK {
+		return newResponseError(resp, "failed to update device %s", name)
+	}
+	efc.commands.invalidateDevice(name)
+	return nil
+}
+
+// Get is used to query the device information corresponding to the device name
+func (efc *EdgexV1DeviceClient) Get(ctx context.Context, deviceName string, options clients.GetOptions) (*devicev1alpha1.Device, error) {
+	klog.V(5).Infof("will get Devices: %s", deviceName)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(efc.CoreMetaAddr), apiPath(APIVersionV1, DevicePath), url.PathEscape(deviceName))
+	resp, err := efc.R().SetContext(ctx).Get(getURL)
+	if err != nil {
+		return nil, newRequestError(err, "failed to get device %s", deviceName)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get device %s", deviceName)
+	}
+	var ed v1Device
+	if err := json.Unmarshal(resp.Body(), &ed); err != nil {
+		return nil, err
+	}
+	device := toKubeDevice(fromV1Device(ed), efc.Namespace)
+	return &device, nil
+}
+
+// List is used to get the device objects on edge platform which match the selectors of options
+func (efc *EdgexV1DeviceClient) List(ctx context.Context, options clients.ListOptions) ([]devicev1alpha1.Device, error) {
+	devices, err := efc.list(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	start, end := v1PageBounds(len(devices), options)
+	return devices[start:end], nil
+}
+
+// ListPages lists all the devices at once and hands them over page by page, since the v1 API has no pages
+func (efc *EdgexV1DeviceClient) ListPages(ctx context.Context, options clients.ListOptions, fn func(devices []devicev1alpha1.Device) error) error {
+	devices, err := efc.list(ctx, options)
+	if err != nil {
+		return err
+	}
+	return v1Pages(len(devices), options, func(start, end int) error {
+		return fn(devices[start:end])
+	})
+}
+
+// list returns all the device objects which match the selectors of options, EdgeX 1.x can filter devices
+// either by a label, by service or by profile
+func (efc *EdgexV1DeviceClient) list(ctx context.Context, options clients.ListOptions) ([]devicev1alpha1.Device, error) {
+	if err := validateFieldSelector("device", options.FieldSelector, deviceSelectableFields); err != nil {
+		return nil, err
+	}
+	lp := fmt.Sprintf("%s%s", baseURL(efc.CoreMetaAddr), apiPath(APIVersionV1, DevicePath))
+	if service, ok := options.FieldSelector[FieldServiceName]; ok {
+		lp = fmt.Sprintf("%s/servicename/%s", lp, url.PathEscape(service))
+	} else if profile, ok := options.FieldSelector[FieldProfileName]; ok {
+		lp = fmt.Sprintf("%s/profilename/%s", lp, url.PathEscape(profile))
+	} else if labels := clients.SelectorLabels(options.LabelSelector); len(labels) != 0 {
+		lp = fmt.Sprintf("%s/label/%s", lp, url.PathEscape(labels[0]))
+	}
+	resp, err := efc.R().SetContext(ctx).EnableTrace().Get(lp)
+	if err != nil {
+		return nil, newRequestError(err, "failed to list devices")
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to list devices")
+	}
+	var edgeDevices []v1Device
+	if err := json.Unmarshal(resp.Body(), &edgeDevices); err != nil {
+		return nil, err
+	}
+	var res []devicev1alpha1.Device
+	for _, v1d := range edgeDevices {
+		ed := fromV1Device(v1d)
+		if !clients.LabelsMatch(ed.Labels, options.LabelSelector) || !clients.FieldsMatch(deviceFields(ed), options.FieldSelector) {
+			continue
+		}
+		res = append(res, toKubeDevice(ed, efc.Namespace))
+	}
+	return res, nil
+}
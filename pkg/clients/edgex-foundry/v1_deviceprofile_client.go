@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	devcli "github.com/openyurtio/device-controller/pkg/clients"
+
+	"k8s.io/klog/v2"
+)
+
+// EdgexV1DeviceProfile manages the deviceProfiles on EdgeX 1.x through the v1 API,
+// the deviceProfiles are deleted by the embedded client since the v1 API keeps the path of the v2 API
+type EdgexV1DeviceProfile struct {
+	*EdgexDeviceProfile
+}
+
+func NewEdgexV1DeviceProfile(coreMetaAddr string) *EdgexV1DeviceProfile {
+	cdc := NewEdgexDeviceProfile(coreMetaAddr)
+	cdc.APIVersion = APIVersionV1
+	return &EdgexV1DeviceProfile{EdgexDeviceProfile: cdc}
+}
+
+func (cdc *EdgexV1DeviceProfile) List(ctx context.Context, opts devcli.ListOptions) ([]v1alpha1.DeviceProfile, error) {
+	deviceProfiles, err := cdc.list(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	start, end := v1PageBounds(len(deviceProfiles), opts)
+	return deviceProfiles[start:end], nil
+}
+
+// ListPages lists all the deviceProfiles at once and hands them over page by page, since the v1 API has no pages
+func (cdc *EdgexV1DeviceProfile) ListPages(ctx context.Context, opts devcli.ListOptions, fn func(deviceProfiles []v1alpha1.DeviceProfile) error) error {
+	deviceProfiles, err := cdc.list(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return v1Pages(len(deviceProfiles), opts, func(start, end int) error {
+		return fn(deviceProfiles[start:end])
+	})
+}
+
+// list returns all the deviceProfile objects which match the selectors of options,
+// EdgeX 1.x can filter deviceProfiles either by a label or by manufacturer and model
+func (cdc *EdgexV1DeviceProfile) list(ctx context.Context, opts devcli.ListOptions) ([]v1alpha1.DeviceProfile, error) {
+	klog.V(5).Info("will list DeviceProfiles")
+	if err := validateFieldSelector("deviceProfile", opts.FieldSelector, deviceProfileSelectableFields); err != nil {
+		return nil, err
+	}
+	lp := fmt.Sprintf("%s%s", baseURL(cdc.CoreMetaAddr), apiPath(APIVersionV1, DeviceProfilePath))
+	manufacturer, byManufacturer := opts.FieldSelector[FieldManufacturer]
+	model, byModel := opts.FieldSelector[FieldModel]
+	switch {
+	case byManufacturer && byModel:
+		lp = fmt.Sprintf("%s/manufacturer/%s/model/%s", lp, url.PathEscape(manufacturer), url.PathEscape(model))
+	case byManufacturer:
+		lp = fmt.Sprintf("%s/manufacturer/%s", lp, url.PathEscape(manufacturer))
+	case byModel:
+		lp = fmt.Sprintf("%s/model/%s", lp, url.PathEscape(model))
+	default:
+		if labels := devcli.SelectorLabels(opts.LabelSelector); len(labels) != 0 {
+			lp = fmt.Sprintf("%s/label/%s", lp, url.PathEscape(labels[0]))
+		}
+	}
+	resp, err := cdc.R().SetContext(ctx).EnableTrace().Get(lp)
+	if err != nil {
+		return nil, newRequestError(err, "failed to list deviceProfiles")
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to list deviceProfiles")
+	}
+	var edgeProfiles []v1DeviceProfile
+	if err := json.Unmarshal(resp.Body(), &edgeProfiles); err != nil {
+		return nil, err
+	}
+	var deviceProfiles []v1alpha1.DeviceProfile
+	for _, v1dp := range edgeProfiles {
+		dp, fields := fromV1DeviceProfile(v1dp)
+		cdc.commands.observeProfile(dp.Name, dp.Modified)
+		if !devcli.LabelsMatch(dp.Labels, opts.LabelSelector) || !devcli.FieldsMatch(deviceProfileFields(dp), opts.FieldSelector) {
+			continue
+		}
+		kdp := toKubeDeviceProfile(&dp, cdc.Namespace)
+		setV1Fields(&kdp, fields)
+		deviceProfiles = append(deviceProfiles, kdp)
+	}
+	return deviceProfiles, nil
+}
+
+func (cdc *EdgexV1DeviceProfile) Get(ctx context.Context, name string, opts devcli.GetOptions) (*v1alpha1.DeviceProfile, error) {
+	klog.V(5).Infof("will get DeviceProfiles: %s", name)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(cdc.CoreMetaAddr), apiPath(APIVersionV1, DeviceProfilePath), url.PathEscape(name))
+	resp, err := cdc.R().SetContext(ctx).Get(getURL)
+	if err != nil {
+		return nil, newRequestError(err, "failed to get DeviceProfile %s", name)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get DeviceProfile %s", name)
+	}
+	var v1dp v1DeviceProfile
+	if err := json.Unmarshal(resp.Body(), &v1dp); err != nil {
+		return nil, err
+	}
+	edp, fields := fromV1DeviceProfile(v1dp)
+	cdc.commands.observeProfile(edp.Name, edp.Modified)
+	kubedp := toKubeDeviceProfile(&edp, cdc.Namespace)
+	setV1Fields(&kubedp, fields)
+	return &kubedp, nil
+}
+
+func (cdc *EdgexV1DeviceProfile) Create(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile, opts devcli.CreateOptions) (*v1alpha1.DeviceProfile, error) {
+	id, err := cdc.create(ctx, deviceProfile)
+	if err != nil {
+		return nil, err
+	}
+	createdDeviceProfile := deviceProfile.DeepCopy()
+	createdDeviceProfile.Status.EdgeId = id
+	createdDeviceProfile.Status.Synced = true
+	return createdDeviceProfile, nil
+}
+
+// CreateBatch adds the deviceProfiles one by one, since the v1 API can't add them in a single request
+func (cdc *EdgexV1DeviceProfile) CreateBatch(ctx context.Context, deviceProfiles []*v1alpha1.DeviceProfile, opts devcli.CreateOptions) ([]devcli.BatchResult, error) {
+	if len(deviceProfiles) == 0 {
+		return nil, nil
+	}
+	results := make([]devcli.BatchResult, len(deviceProfiles))
+	for i, dp := range deviceProfiles {
+		results[i].Name = getEdgeXName(dp)
+		results[i].EdgeId, results[i].Err = cdc.create(ctx, dp)
+	}
+	return results, nil
+}
+
+// create adds the deviceProfile and returns its id on EdgeX
+func (cdc *EdgexV1DeviceProfile) create(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile) (string, error) {
+	name := getEdgeXName(deviceProfile)
+	klog.V(5).Infof("will add the DeviceProfile: %s", name)
+	reqBody, err := json.Marshal(toV1DeviceProfile(deviceProfile))
+	if err != nil {
+		return "", err
+	}
+	postURL := fmt.Sprintf("%s%s", baseURL(cdc.CoreMetaAddr), apiPath(APIVersionV1, DeviceProfilePath))
+	resp, err := cdc.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(reqBody).Post(postURL)
+	if err != nil {
+		return "", newRequestError(err, "failed to create edgex deviceProfile %s", name)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return "", toCreateError(newResponseError(resp, "failed to create edgex deviceProfile %s", name))
+	}
+	return strings.TrimSpace(string(resp.Body())), nil
+}
+
+// Update is used to replace the deviceProfile on edge platform with the given deviceProfile,
+// the deviceProfile on edge platform is located by its name
+func (cdc *EdgexV1DeviceProfile) Update(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile, opts devcli.UpdateOptions) (*v1alpha1.DeviceProfile, error) {
+	if deviceProfile == nil {
+		return nil, nil
+	}
+	if err := cdc.update(ctx, deviceProfile); err != nil {
+		return nil, err
+	}
+	return deviceProfile.DeepCopy(), nil
+}
+
+// UpdateBatch replaces the deviceProfiles one by one, since the v1 API can't replace them in a single request
+func (cdc *EdgexV1DeviceProfile) UpdateBatch(ctx context.Context, deviceProfiles []*v1alpha1.DeviceProfile, opts devcli.UpdateOptions) ([]devcli.BatchResult, error) {
+	if len(deviceProfiles) == 0 {
+		return nil, nil
+	}
+	results := make([]devcli.BatchResult, len(deviceProfiles))
+	for i, dp := range deviceProfiles {
+		results[i].Name = getEdgeXName(dp)
+		results[i].Err = cdc.update(ctx, dp)
+	}
+	return results, nil
+}
+
+func (cdc *EdgexV1DeviceProfile) update(ctx context.Context, deviceProfile *v1alpha1.DeviceProfile) error {
+	name := getEdgeXName(deviceProfile)
+	klog.V(5).Infof("will update the DeviceProfile: %s", name)
+	reqBody, err := json.Marshal(toV1DeviceProfile(deviceProfile))
+	if err != nil {
+		return err
+	}
+	putURL := fmt.Sprintf("%s%s", baseURL(cdc.CoreMetaAddr), apiPath(APIVersionV1, DeviceProfilePath))
+	resp, err := cdc.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(reqBody).Put(putURL)
+	if err != nil {
+		return newRequestError(err, "failed to update edgex deviceProfile %s", name)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return newResponseError(resp, "failed to update edgex deviceProfile %s", name)
+	}
+	cdc.commands.invalidateProfile(name)
+	return nil
+}
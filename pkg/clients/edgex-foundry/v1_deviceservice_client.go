@@ -0,0 +1,260 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	edgeCli "github.com/openyurtio/device-controller/pkg/clients"
+
+	"k8s.io/klog/v2"
+)
+
+// EdgexV1DeviceServiceClient manages the deviceServices on EdgeX 1.x through the v1 API,
+// the deviceServices are deleted by the embedded client since the v1 API keeps the path of the v2 API
+type EdgexV1DeviceServiceClient struct {
+	*EdgexDeviceServiceClient
+}
+
+func NewEdgexV1DeviceServiceClient(coreMetaAddr string) *EdgexV1DeviceServiceClient {
+	eds := NewEdgexDeviceServiceClient(coreMetaAddr)
+	eds.APIVersion = APIVersionV1
+	return &EdgexV1DeviceServiceClient{EdgexDeviceServiceClient: eds}
+}
+
+// Create function sends a POST request to EdgeX to add a new deviceService
+func (eds *EdgexV1DeviceServiceClient) Create(ctx context.Context, deviceService *v1alpha1.DeviceService, options edgeCli.CreateOptions) (*v1alpha1.DeviceService, error) {
+	id, err := eds.create(ctx, deviceService)
+	if err != nil {
+		return nil, err
+	}
+	createdDeviceService := deviceService.DeepCopy()
+	createdDeviceService.Status.EdgeId = id
+	createdDeviceService.Status.Synced = true
+	return createdDeviceService, nil
+}
+
+// CreateBatch adds the deviceServices one by one, since the v1 API can't add them in a single request
+func (eds *EdgexV1DeviceServiceClient) CreateBatch(ctx context.Context, deviceServices []*v1alpha1.DeviceService, options edgeCli.CreateOptions) ([]edgeCli.BatchResult, error) {
+	if len(deviceServices) == 0 {
+		return nil, nil
+	}
+	results := make([]edgeCli.BatchResult, len(deviceServices))
+	for i, ds := range deviceServices {
+		results[i].Name = getEdgeXName(ds)
+		results[i].EdgeId, results[i].Err = eds.create(ctx, ds)
+	}
+	return results, nil
+}
+
+// create adds the addressable of the deviceService if it doesn't exist, then the deviceService,
+// and returns the id of the deviceService on EdgeX
+func (eds *EdgexV1DeviceServiceClient) create(ctx context.Context, deviceService *v1alpha1.DeviceService) (string, error) {
+	name := getEdgeXName(deviceService)
+	v1ds := toV1DeviceService(deviceService)
+	if err := eds.addAddressable(ctx, v1ds.Addressable); err != nil {
+		return "", err
+	}
+	klog.V(5).InfoS("will add the DeviceService", "DeviceService", name)
+	jsonBody, err := json.Marshal(v1ds)
+	if err != nil {
+		return "", err
+	}
+	postURL := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), apiPath(APIVersionV1, DeviceServicePath))
+	resp, err := eds.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(jsonBody).Post(postURL)
+	if err != nil {
+		return "", newRequestError(err, "failed to create DeviceService %s on edgex foundry", name)
+	} else if resp.StatusCode() != http.StatusOK {
+		return "", toCreateError(newResponseError(resp, "failed to create DeviceService %s on edgex foundry", name))
+	}
+	return strings.TrimSpace(string(resp.Body())), nil
+}
+
+// addAddressable adds the addressable, the addressable which already exists is left unchanged
+func (eds *EdgexV1DeviceServiceClient) addAddressable(ctx context.Context, a *v1Addressable) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	postURL := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), V1AddressablePath)
+	resp, err := eds.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).Post(postURL)
+	if err != nil {
+		return newRequestError(err, "failed to create addressable %s on edgex foundry", a.Name)
+	} else if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusConflict {
+		return newResponseError(resp, "failed to create addressable %s on edgex foundry", a.Name)
+	}
+	return nil
+}
+
+// updateAddressable updates the addressable, which is located by its name
+func (eds *EdgexV1DeviceServiceClient) updateAddressable(ctx context.Context, a *v1Addressable) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	putURL := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), V1AddressablePath)
+	resp, err := eds.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).Put(putURL)
+	if err != nil {
+		return newRequestError(err, "failed to update addressable %s", a.Name)
+	} else if resp.StatusCode() != http.StatusOK {
+		return newResponseError(resp, "failed to update addressable %s", a.Name)
+	}
+	return nil
+}
+
+// Update is used to update the fields of the deviceService by unique name of the deviceService,
+// only the fields listed in options.UpdateFields are updated if it is not empty
+func (eds *EdgexV1DeviceServiceClient) Update(ctx context.Context, ds *v1alpha1.DeviceService, options edgeCli.UpdateOptions) (*v1alpha1.DeviceService, error) {
+	if ds == nil {
+		return nil, nil
+	}
+	if err := eds.update(ctx, ds, options.UpdateFields); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// UpdateBatch updates the deviceServices one by one, since the v1 API can't update them in a single request
+func (eds *EdgexV1DeviceServiceClient) UpdateBatch(ctx context.Context, deviceServices []*v1alpha1.DeviceService, options edgeCli.UpdateOptions) ([]edgeCli.BatchResult, error) {
+	if len(deviceServices) == 0 {
+		return nil, nil
+	}
+	results := make([]edgeCli.BatchResult, len(deviceServices))
+	for i, ds := range deviceServices {
+		results[i].Name = getEdgeXName(ds)
+		results[i].Err = eds.update(ctx, ds, options.UpdateFields)
+	}
+	return results, nil
+}
+
+// update updates the deviceService, the base address is updated through the addressable of the deviceService
+func (eds *EdgexV1DeviceServiceClient) update(ctx context.Context, ds *v1alpha1.DeviceService, fields []string) error {
+	name := getEdgeXName(ds)
+	if isFieldUpdated(fields, "baseAddress") {
+		if err := eds.updateAddressable(ctx, toV1DeviceService(ds).Addressable); err != nil {
+			return err
+		}
+	}
+	klog.V(5).InfoS("will update the DeviceService", "DeviceService", name, "fields", fields)
+	reqBody, err := json.Marshal(toV1UpdateDeviceService(ds, fields))
+	if err != nil {
+		return err
+	}
+	putURL := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), apiPath(APIVersionV1, DeviceServicePath))
+	resp, err := eds.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(reqBody).
+		Put(putURL)
+	if err != nil {
+		return newRequestError(err, "failed to update deviceservice %s", name)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return newResponseError(resp, "failed to update deviceservice %s", name)
+	}
+	return nil
+}
+
+// Get is used to query the deviceService information corresponding to the deviceService name
+func (eds *EdgexV1DeviceServiceClient) Get(ctx context.Context, name string, options edgeCli.GetOptions) (*v1alpha1.DeviceService, error) {
+	klog.V(5).InfoS("will get DeviceServices", "DeviceService", name)
+	getURL := fmt.Sprintf("%s%s/name/%s", baseURL(eds.CoreMetaAddr), apiPath(APIVersionV1, DeviceServicePath), url.PathEscape(name))
+	resp, err := eds.R().SetContext(ctx).Get(getURL)
+	if err != nil {
+		return nil, newRequestError(err, "failed to get deviceservice %s", name)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to get deviceservice %s", name)
+	}
+	var edgeService v1DeviceService
+	if err := json.Unmarshal(resp.Body(), &edgeService); err != nil {
+		return nil, err
+	}
+	service, fields := fromV1DeviceService(edgeService)
+	ds := toKubeDeviceService(service, eds.Namespace)
+	setV1Fields(&ds, fields)
+	return &ds, nil
+}
+
+// List is used to get the deviceService objects on edge platform which match the selectors of options
+func (eds *EdgexV1DeviceServiceClient) List(ctx context.Context, options edgeCli.ListOptions) ([]v1alpha1.DeviceService, error) {
+	deviceServices, err := eds.list(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	start, end := v1PageBounds(len(deviceServices), options)
+	return deviceServices[start:end], nil
+}
+
+// ListPages lists all the deviceServices at once and hands them over page by page, since the v1 API has no pages
+func (eds *EdgexV1DeviceServiceClient) ListPages(ctx context.Context, options edgeCli.ListOptions, fn func(deviceServices []v1alpha1.DeviceService) error) error {
+	deviceServices, err := eds.list(ctx, options)
+	if err != nil {
+		return err
+	}
+	return v1Pages(len(deviceServices), options, func(start, end int) error {
+		return fn(deviceServices[start:end])
+	})
+}
+
+// list returns all the deviceService objects which match the selectors of options,
+// EdgeX 1.x can filter deviceServices by a label
+func (eds *EdgexV1DeviceServiceClient) list(ctx context.Context, options edgeCli.ListOptions) ([]v1alpha1.DeviceService, error) {
+	klog.V(5).Info("will list DeviceServices")
+	if err := validateFieldSelector("deviceService", options.FieldSelector, deviceServiceSelectableFields); err != nil {
+		return nil, err
+	}
+	lp := fmt.Sprintf("%s%s", baseURL(eds.CoreMetaAddr), apiPath(APIVersionV1, DeviceServicePath))
+	if labels := edgeCli.SelectorLabels(options.LabelSelector); len(labels) != 0 {
+		lp = fmt.Sprintf("%s/label/%s", lp, url.PathEscape(labels[0]))
+	}
+	resp, err := eds.R().SetContext(ctx).
+		EnableTrace().
+		Get(lp)
+	if err != nil {
+		return nil, newRequestError(err, "failed to list deviceservices")
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newResponseError(resp, "failed to list deviceservices")
+	}
+	var edgeServices []v1DeviceService
+	if err := json.Unmarshal(resp.Body(), &edgeServices); err != nil {
+		return nil, err
+	}
+	var res []v1alpha1.DeviceService
+	for _, v1ds := range edgeServices {
+		ds, fields := fromV1DeviceService(v1ds)
+		if !edgeCli.LabelsMatch(ds.Labels, options.LabelSelector) || !edgeCli.FieldsMatch(deviceServiceFields(ds), options.FieldSelector) {
+			continue
+		}
+		kds := toKubeDeviceService(ds, eds.Namespace)
+		setV1Fields(&kds, fields)
+		res = append(res, kds)
+	}
+	return res, nil
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	V1DeviceMetadata   = `{"created":1623035541185,"modified":1623035541185,"origin":1623035541175,"description":"Example of Device Virtual","id":"0e6ff6b0-5fbe-4fd7-8cba-6fb5b6e5d7a4","name":"Random-Boolean-Device","adminState":"UNLOCKED","operatingState":"ENABLED","protocols":{"other":{"Address":"device-virtual-bool-01","Port":"300"}},"labels":["device-virtual-example"],"location":null,"service":{"id":"7cbc5e4b-2e8b-4a7c-9c6b-84e5fd4a5e42","name":"edgex-device-virtual","operatingState":"ENABLED","adminState":"UNLOCKED","addressable":{"name":"edgex-device-virtual","protocol":"HTTP","method":"POST","address":"edgex-device-virtual","port":49990,"path":"/api/v1/callback"}},"profile":{"id":"8f5d3a13-9a78-4e59-b7b6-2c0a0cf0f5c2","name":"Random-Boolean-Device"},"autoEvents":[{"frequency":"10s","resource":"Bool"}]}`
+	V1DeviceList       = `[` + V1DeviceMetadata + `,{"id":"9a5b6f40-6d5b-4cd0-9b25-2d7f1b0e0b17","name":"Random-Integer-Device","adminState":"LOCKED","operatingState":"DISABLED","protocols":{"other":{"Address":"device-virtual-int-01","Port":"300"}},"labels":["device-virtual-example"],"service":{"name":"edgex-device-virtual"},"profile":{"name":"Random-Integer-Device"}}]`
+	V1DeviceCommands   = `{"id":"0e6ff6b0-5fbe-4fd7-8cba-6fb5b6e5d7a4","name":"Random-Boolean-Device","adminState":"UNLOCKED","operatingState":"ENABLED","commands":[{"id":"c2b6bbde-8c4e-4b6e-a1a5-63bc5a9a2d0e","name":"Bool","get":{"path":"/api/v1/device/{deviceId}/Bool","responses":[{"code":"200","expectedValues":["Bool"]},{"code":"503","description":"service unavailable"}],"url":"http://edgex-core-command:48082/api/v1/device/0e6ff6b0-5fbe-4fd7-8cba-6fb5b6e5d7a4/command/c2b6bbde-8c4e-4b6e-a1a5-63bc5a9a2d0e"},"put":{"path":"/api/v1/device/{deviceId}/Bool","responses":[{"code":"200"},{"code":"503","description":"service unavailable"}],"url":"http://edgex-core-command:48082/api/v1/device/0e6ff6b0-5fbe-4fd7-8cba-6fb5b6e5d7a4/command/c2b6bbde-8c4e-4b6e-a1a5-63bc5a9a2d0e","parameterNames":["Bool"]}}]}`
+	V1DeviceEvent      = `{"device":"Random-Boolean-Device","origin":1623035641185203000,"readings":[{"origin":1623035641185203000,"device":"Random-Boolean-Device","name":"Bool","value":"true","valueType":"Bool"}]}`
+	V1ServiceMetadata  = `{"created":1623035540973,"modified":1623035540973,"origin":1623035540970,"description":"","id":"7cbc5e4b-2e8b-4a7c-9c6b-84e5fd4a5e42","name":"edgex-device-virtual","operatingState":"ENABLED","labels":[],"addressable":{"created":1623035540965,"modified":1623035540965,"origin":1623035540963,"id":"b0a2fbc4-6e5c-4d88-9a1b-0c5f7a3c7e11","name":"edgex-device-virtual","protocol":"HTTP","method":"POST","address":"edgex-device-virtual","port":49990,"path":"/api/v1/callback"},"adminState":"UNLOCKED"}`
+	V1ProfileMetadata  = `{"created":1623035541010,"modified":1623035541010,"description":"Example of Device-Virtual","id":"8f5d3a13-9a78-4e59-b7b6-2c0a0cf0f5c2","name":"Random-Boolean-Device","manufacturer":"IOTech","model":"Device-Virtual-01","labels":["device-virtual-example"],"deviceResources":[{"description":"Generate random boolean value","name":"Bool","properties":{"value":{"type":"Bool","readWrite":"RW","defaultValue":"true"},"units":{"type":"String","readWrite":"R","defaultValue":"Random"}}},{"description":"used to decide whether to re-generate a random value","name":"EnableRandomization_Bool","properties":{"value":{"type":"Bool","readWrite":"W","defaultValue":"true"},"units":{"type":"String","readWrite":"R","defaultValue":"Random"}}}],"deviceCommands":[{"name":"WriteBoolValue","get":[{"index":"1","operation":"get","deviceResource":"Bool"}],"set":[{"index":"1","operation":"set","deviceResource":"Bool","parameter":"false"},{"index":"2","operation":"set","deviceResource":"EnableRandomization_Bool","parameter":"false"}]}],"coreCommands":[{"name":"WriteBoolValue","get":{"path":"/api/v1/device/{deviceId}/WriteBoolValue","responses":[{"code":"200","expectedValues":["Bool"]}]},"put":{"path":"/api/v1/device/{deviceId}/WriteBoolValue","responses":[{"code":"200"}],"parameterNames":["Bool","EnableRandomization_Bool"]}}]}`
+	V1Version          = `{"version":"1.3.1"}`
+	V1CreatedId        = "2fff4f1a-7110-442f-b347-9f896338ba57"
+	V1DeviceNotFound   = "Item not found"
+	V1CoreMetadataAddr = "edgex-core-metadata:48081"
+)
+
+func Test_DetectV1APIVersion(t *testing.T) {
+	client := NewEdgexDeviceServiceClient(V1CoreMetadataAddr)
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	// core-metadata of EdgeX 1.x serves neither the v3 nor the v2 endpoint
+	httpmock.RegisterNoResponder(httpmock.NewStringResponder(404, "404 page not found"))
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/version",
+		httpmock.NewStringResponder(200, V1Version))
+	version, err := DetectAPIVersion(context.TODO(), client.Client, V1CoreMetadataAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, APIVersionV1, version)
+}
+
+func Test_V1Device(t *testing.T) {
+	client := NewEdgexV1DeviceClient(V1CoreMetadataAddr, "edgex-core-command:48082")
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/device/name/Random-Boolean-Device",
+		httpmock.NewStringResponder(200, V1DeviceMetadata))
+	device, err := client.Get(context.TODO(), "Random-Boolean-Device", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "edgex-device-virtual", device.Spec.Service)
+	assert.Equal(t, "Random-Boolean-Device", device.Spec.Profile)
+	assert.Equal(t, devicev1alpha1.Up, device.Spec.OperatingState)
+	assert.Equal(t, []devicev1alpha1.AutoEvent{{Interval: "10s", SourceName: "Bool"}}, device.Spec.AutoEvents)
+	assert.Equal(t, "0e6ff6b0-5fbe-4fd7-8cba-6fb5b6e5d7a4", device.Status.EdgeId)
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/device/name/Random-Float-Device",
+		httpmock.NewStringResponder(404, V1DeviceNotFound))
+	_, err = client.Get(context.TODO(), "Random-Float-Device", clients.GetOptions{})
+	assert.True(t, clients.IsNotFoundErr(err))
+
+	// the name is escaped in the path
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/device/name/Floor%201%2FSensor",
+		httpmock.NewStringResponder(200, V1DeviceMetadata))
+	_, err = client.Get(context.TODO(), "Floor 1/Sensor", clients.GetOptions{})
+	assert.Nil(t, err)
+
+	// the device is added alone and its id is returned as plain text
+	var sent map[string]interface{}
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:48081/api/v1/device",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := json.Unmarshal(body, &sent); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(200, V1CreatedId), nil
+		})
+	created, err := client.Create(context.TODO(), device, clients.CreateOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, V1CreatedId, created.Status.EdgeId)
+	assert.Equal(t, "ENABLED", sent["operatingState"])
+	assert.Equal(t, map[string]interface{}{"name": "edgex-device-virtual"}, sent["service"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"frequency": "10s", "onChange": false, "resource": "Bool"}}, sent["autoEvents"])
+
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:48081/api/v1/device",
+		httpmock.NewStringResponder(409, "Duplicate name for Device"))
+	results, err := client.CreateBatch(context.TODO(), []*devicev1alpha1.Device{device}, clients.CreateOptions{})
+	assert.Nil(t, err)
+	assert.True(t, clients.IsAlreadyExistsErr(results[0].Err))
+
+	// only the updated fields are carried, the others are left null or empty
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:48081/api/v1/device",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			sent = nil
+			if err := json.Unmarshal(body, &sent); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(200, "true"), nil
+		})
+	device.Spec.OperatingState = devicev1alpha1.Down
+	_, err = client.Update(context.TODO(), device, clients.UpdateOptions{UpdateFields: []string{"operatingState"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "DISABLED", sent["operatingState"])
+	assert.Nil(t, sent["labels"])
+	assert.NotContains(t, sent, "adminState")
+}
+
+func Test_V1ListDevices(t *testing.T) {
+	client := NewEdgexV1DeviceClient(V1CoreMetadataAddr, "edgex-core-command:48082")
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/device/servicename/edgex-device-virtual",
+		httpmock.NewStringResponder(200, V1DeviceList))
+	devices, err := client.List(context.TODO(), clients.ListOptions{
+		FieldSelector: map[string]string{FieldServiceName: "edgex-device-virtual", FieldOperatingState: "DOWN"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "random-integer-device", devices[0].Name)
+
+	// the devices are listed at once and paged on the client side
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/device/label/device-virtual-example",
+		httpmock.NewStringResponder(200, V1DeviceList))
+	var pages [][]string
+	err = client.ListPages(context.TODO(), clients.ListOptions{
+		LabelSelector: map[string]string{"device-virtual-example": ""},
+		Limit:         1,
+	}, func(devices []devicev1alpha1.Device) error {
+		var names []string
+		for _, d := range devices {
+			names = append(names, d.Name)
+		}
+		pages = append(pages, names)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"random-boolean-device"}, {"random-integer-device"}}, pages)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET http://edgex-core-metadata:48081/api/v1/device/label/device-virtual-example"])
+}
+
+func Test_V1DeviceProperties(t *testing.T) {
+	client := NewEdgexV1DeviceClient(V1CoreMetadataAddr, "edgex-core-command:48082")
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	commandURL := "http://edgex-core-command:48082/api/v1/device/0e6ff6b0-5fbe-4fd7-8cba-6fb5b6e5d7a4/command/c2b6bbde-8c4e-4b6e-a1a5-63bc5a9a2d0e"
+	httpmock.RegisterResponder("GET", "http://edgex-core-command:48082/api/v1/device/name/Random-Boolean-Device",
+		httpmock.NewStringResponder(200, V1DeviceCommands))
+	httpmock.RegisterResponder("GET", commandURL, httpmock.NewStringResponder(200, V1DeviceEvent))
+	device := &devicev1alpha1.Device{Spec: devicev1alpha1.DeviceSpec{Profile: "Random-Boolean-Device"}}
+	device.Name = "Random-Boolean-Device"
+
+	aps, err := client.GetPropertyState(context.TODO(), "Bool", device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, commandURL, aps.GetURL)
+	assert.Equal(t, "true", aps.ActualValue)
+	assert.Equal(t, "Bool", aps.ValueType)
+
+	_, apsm, err := client.ListPropertiesState(context.TODO(), device, clients.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "true", apsm["Bool"].ActualValue)
+
+	// the value is set through the full URL of the command
+	httpmock.RegisterResponder("PUT", commandURL,
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			assert.JSONEq(t, `{"Bool":"false"}`, string(body))
+			return httpmock.NewStringResponse(200, ""), nil
+		})
+	device.Spec.DeviceProperties = map[string]devicev1alpha1.DesiredPropertyState{
+		"Bool": {Name: "Bool", DesiredValue: "false"},
+	}
+	assert.Nil(t, client.UpdatePropertyState(context.TODO(), "Bool", device, clients.UpdateOptions{}))
+
+	// the latest reading is read from core-data which takes the limit in the path
+	client.PropertySource = clients.PropertySourceReadings
+	client.CoreDataAddr = "edgex-core-data:48080"
+	httpmock.RegisterResponder("GET", "http://edgex-core-data:48080/api/v1/reading/name/Bool/device/Random-Boolean-Device/1",
+		httpmock.NewStringResponder(200, `[{"origin":1623035641185203000,"device":"Random-Boolean-Device","name":"Bool","value":"false","valueType":"Bool"}]`))
+	aps, err = client.GetPropertyState(context.TODO(), "Bool", device, clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "false", aps.ActualValue)
+}
+
+func Test_V1DeviceService(t *testing.T) {
+	client := NewEdgexV1DeviceServiceClient(V1CoreMetadataAddr)
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/deviceservice/name/edgex-device-virtual",
+		httpmock.NewStringResponder(200, V1ServiceMetadata))
+	ds, err := client.Get(context.TODO(), "edgex-device-virtual", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://edgex-device-virtual:49990", ds.Spec.BaseAddress)
+
+	// the addressable is added before the deviceService, and it may exist already
+	var addressable, service map[string]interface{}
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:48081/api/v1/addressable",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := json.Unmarshal(body, &addressable); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(409, "Duplicate name for addressable"), nil
+		})
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:48081/api/v1/deviceservice",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := json.Unmarshal(body, &service); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(200, V1CreatedId), nil
+		})
+	ds.Spec.BaseAddress = "http://edgex-device-virtual-2:49991"
+	created, err := client.Create(context.TODO(), ds, clients.CreateOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, V1CreatedId, created.Status.EdgeId)
+	assert.Equal(t, "edgex-device-virtual-2", addressable["address"])
+	assert.Equal(t, float64(49991), addressable["port"])
+	assert.Equal(t, "/api/v1/callback", addressable["path"])
+	assert.Equal(t, addressable, service["addressable"])
+	assert.Equal(t, "ENABLED", service["operatingState"])
+
+	// the base address is updated through the addressable
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:48081/api/v1/addressable",
+		httpmock.NewStringResponder(200, "true"))
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:48081/api/v1/deviceservice",
+		httpmock.NewStringResponder(200, "true"))
+	_, err = client.Update(context.TODO(), ds, clients.UpdateOptions{UpdateFields: []string{"baseAddress"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["PUT http://edgex-core-metadata:48081/api/v1/addressable"])
+	_, err = client.Update(context.TODO(), ds, clients.UpdateOptions{UpdateFields: []string{"labels"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["PUT http://edgex-core-metadata:48081/api/v1/addressable"])
+}
+
+func Test_V1DeviceProfile(t *testing.T) {
+	client := NewEdgexV1DeviceProfile(V1CoreMetadataAddr)
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:48081/api/v1/deviceprofile/name/Random-Boolean-Device",
+		httpmock.NewStringResponder(200, V1ProfileMetadata))
+	dp, err := client.Get(context.TODO(), "Random-Boolean-Device", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Random", dp.Spec.DeviceResources[0].Properties.Units)
+	assert.Equal(t, []devicev1alpha1.DeviceCommand{{
+		Name:      "WriteBoolValue",
+		ReadWrite: "RW",
+		ResourceOperations: []devicev1alpha1.ResourceOperation{
+			{DeviceResource: "Bool", DefaultValue: "false"},
+		},
+	}}, dp.Spec.DeviceCommands)
+
+	// the deviceProfile is sent back to EdgeX with its coreCommands and the properties the v1alpha1 API can't represent
+	var sent map[string]interface{}
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:48081/api/v1/deviceprofile",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := json.Unmarshal(body, &sent); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(200, "true"), nil
+		})
+	_, err = client.Update(context.TODO(), dp, clients.UpdateOptions{})
+	assert.Nil(t, err)
+	var expected map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(V1ProfileMetadata), &expected))
+	assert.Equal(t, expected["deviceResources"], sent["deviceResources"])
+	assert.Equal(t, expected["coreCommands"], sent["coreCommands"])
+
+	// the coreCommands of the deviceProfile created on OpenYurt are made for its commands and visible resources
+	delete(dp.Annotations, devicev1alpha1.EdgeXV1Fields)
+	dp.Spec.DeviceResources[1].IsHidden = true
+	commands := toV1DeviceProfile(dp).CoreCommands
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, []string{"Bool"}, commands[0].Put.ParameterNames)
+	assert.Equal(t, "/api/v1/device/{deviceId}/Bool", commands[1].Get.Path)
+}
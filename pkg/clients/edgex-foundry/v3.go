@@ -0,0 +1,603 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"encoding/json"
+	"strconv"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// The EdgeX v3 API keeps the layout of the v2 API, the objects of the v3 API are converted to and from the v2 ones,
+// so that the v2 conversions of the package are shared by both versions. The v3 objects differ in:
+//   - the protocol properties of the devices are typed
+//   - the devices, deviceServices, deviceResources and deviceCommands carry tags or properties
+//   - the numeric properties of the deviceResources are numbers instead of strings
+//   - the devices and deviceServices no longer report lastConnected and lastReported
+
+// v3Fields holds the fields of the EdgeX v3 object which have no counterpart in the v2 object
+type v3Fields struct {
+	Tags       map[string]interface{} `json:"tags,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// TypedProtocols lists the protocol properties of the device whose values are not strings, keyed by the protocol
+	TypedProtocols map[string][]string `json:"typedProtocols,omitempty"`
+	// Optional is the optional properties of the deviceResource
+	Optional map[string]interface{} `json:"optional,omitempty"`
+	// DeviceResources and DeviceCommands hold the fields of the deviceResources and deviceCommands of the deviceProfile by name
+	DeviceResources map[string]v3Fields `json:"deviceResources,omitempty"`
+	DeviceCommands  map[string]v3Fields `json:"deviceCommands,omitempty"`
+}
+
+func (f *v3Fields) isEmpty() bool {
+	return len(f.Tags) == 0 && len(f.Properties) == 0 && len(f.TypedProtocols) == 0 && len(f.Optional) == 0 &&
+		len(f.DeviceResources) == 0 && len(f.DeviceCommands) == 0
+}
+
+// setV3Fields records the fields of the EdgeX v3 object in the annotations of the object
+func setV3Fields(obj metav1.Object, f v3Fields) {
+	if f.isEmpty() {
+		return
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to encode the EdgeX v3 fields", "name", obj.GetName())
+		return
+	}
+	setEdgeXAnnotation(obj, devicev1alpha1.EdgeXV3Fields, string(b))
+}
+
+// getV3Fields returns the fields of the EdgeX v3 object recorded in the annotations of the object
+func getV3Fields(obj metav1.Object) v3Fields {
+	var f v3Fields
+	v, ok := obj.GetAnnotations()[devicev1alpha1.EdgeXV3Fields]
+	if !ok {
+		return f
+	}
+	if err := json.Unmarshal([]byte(v), &f); err != nil {
+		klog.V(4).ErrorS(err, "fail to decode the EdgeX v3 fields", "name", obj.GetName())
+	}
+	return f
+}
+
+type v3Device struct {
+	dtos.DBTimestamp `json:",inline"`
+	Id               string                            `json:"id,omitempty"`
+	Name             string                            `json:"name"`
+	Description      string                            `json:"description,omitempty"`
+	AdminState       string                            `json:"adminState"`
+	OperatingState   string                            `json:"operatingState"`
+	Labels           []string                          `json:"labels,omitempty"`
+	Location         interface{}                       `json:"location,omitempty"`
+	ServiceName      string                            `json:"serviceName"`
+	ProfileName      string                            `json:"profileName"`
+	AutoEvents       []dtos.AutoEvent                  `json:"autoEvents,omitempty"`
+	Protocols        map[string]map[string]interface{} `json:"protocols"`
+	Tags             map[string]interface{}            `json:"tags,omitempty"`
+	Properties       map[string]interface{}            `json:"properties,omitempty"`
+}
+
+// v3UpdateDevice leaves out the tags and properties, so that they remain unchanged on EdgeX
+type v3UpdateDevice struct {
+	Id             *string                           `json:"id"`
+	Name           *string                           `json:"name"`
+	Description    *string                           `json:"description"`
+	AdminState     *string                           `json:"adminState"`
+	OperatingState *string                           `json:"operatingState"`
+	ServiceName    *string                           `json:"serviceName"`
+	ProfileName    *string                           `json:"profileName"`
+	Labels         []string                          `json:"labels"`
+	Location       interface{}                       `json:"location"`
+	AutoEvents     []dtos.AutoEvent                  `json:"autoEvents"`
+	Protocols      map[string]map[string]interface{} `json:"protocols"`
+}
+
+type v3DeviceService struct {
+	dtos.DBTimestamp `json:",inline"`
+	Id               string                 `json:"id,omitempty"`
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description,omitempty"`
+	Labels           []string               `json:"labels,omitempty"`
+	BaseAddress      string                 `json:"baseAddress"`
+	AdminState       string                 `json:"adminState"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type v3UpdateDeviceService struct {
+	Id          *string  `json:"id"`
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	BaseAddress *string  `json:"baseAddress"`
+	Labels      []string `json:"labels"`
+	AdminState  *string  `json:"adminState"`
+}
+
+type v3DeviceProfile struct {
+	dtos.DBTimestamp `json:",inline"`
+	Id               string             `json:"id"`
+	Name             string             `json:"name"`
+	Manufacturer     string             `json:"manufacturer"`
+	Description      string             `json:"description"`
+	Model            string             `json:"model"`
+	Labels           []string           `json:"labels"`
+	DeviceResources  []v3DeviceResource `json:"deviceResources"`
+	DeviceCommands   []v3DeviceCommand  `json:"deviceCommands"`
+}
+
+type v3DeviceResource struct {
+	Description string                 `json:"description"`
+	Name        string                 `json:"name"`
+	IsHidden    bool                   `json:"isHidden"`
+	Properties  v3ResourceProperties   `json:"properties"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	Tags        map[string]interface{} `json:"tags,omitempty"`
+}
+
+type v3ResourceProperties struct {
+	ValueType    string                 `json:"valueType"`
+	ReadWrite    string                 `json:"readWrite"`
+	Units        string                 `json:"units"`
+	Minimum      *float64               `json:"minimum,omitempty"`
+	Maximum      *float64               `json:"maximum,omitempty"`
+	DefaultValue string                 `json:"defaultValue"`
+	Mask         *uint64                `json:"mask,omitempty"`
+	Shift        *int64                 `json:"shift,omitempty"`
+	Scale        *float64               `json:"scale,omitempty"`
+	Offset       *float64               `json:"offset,omitempty"`
+	Base         *float64               `json:"base,omitempty"`
+	Assertion    string                 `json:"assertion"`
+	MediaType    string                 `json:"mediaType"`
+	Optional     map[string]interface{} `json:"optional,omitempty"`
+}
+
+type v3DeviceCommand struct {
+	Name               string                   `json:"name"`
+	IsHidden           bool                     `json:"isHidden"`
+	ReadWrite          string                   `json:"readWrite"`
+	ResourceOperations []dtos.ResourceOperation `json:"resourceOperations"`
+	Tags               map[string]interface{}   `json:"tags,omitempty"`
+}
+
+type v3DeviceRequest struct {
+	common.BaseRequest `json:",inline"`
+	Device             v3Device `json:"device"`
+}
+
+type v3UpdateDeviceRequest struct {
+	common.BaseRequest `json:",inline"`
+	Device             v3UpdateDevice `json:"device"`
+}
+
+type v3DeviceServiceRequest struct {
+	common.BaseRequest `json:",inline"`
+	Service            v3DeviceService `json:"service"`
+}
+
+type v3UpdateDeviceServiceRequest struct {
+	common.BaseRequest `json:",inline"`
+	Service            v3UpdateDeviceService `json:"service"`
+}
+
+type v3DeviceProfileRequest struct {
+	common.BaseRequest `json:",inline"`
+	Profile            v3DeviceProfile `json:"profile"`
+}
+
+type v3DeviceResponse struct {
+	common.BaseResponse `json:",inline"`
+	Device              v3Device `json:"device"`
+}
+
+type v3MultiDevicesResponse struct {
+	common.BaseWithTotalCountResponse `json:",inline"`
+	Devices                           []v3Device `json:"devices"`
+}
+
+type v3DeviceServiceResponse struct {
+	common.BaseResponse `json:",inline"`
+	Service             v3DeviceService `json:"service"`
+}
+
+type v3MultiDeviceServicesResponse struct {
+	common.BaseWithTotalCountResponse `json:",inline"`
+	Services                          []v3DeviceService `json:"services"`
+}
+
+type v3DeviceProfileResponse struct {
+	common.BaseResponse `json:",inline"`
+	Profile             v3DeviceProfile `json:"profile"`
+}
+
+type v3MultiDeviceProfilesResponse struct {
+	common.BaseWithTotalCountResponse `json:",inline"`
+	Profiles                          []v3DeviceProfile `json:"profiles"`
+}
+
+func newV3BaseRequest() common.BaseRequest {
+	return common.BaseRequest{Versionable: common.Versionable{ApiVersion: APIVersionV3}}
+}
+
+// fromV3Device converts the EdgeX v3 device to the v2 one, the protocol properties which are not strings are encoded as JSON
+func fromV3Device(d v3Device) (dtos.Device, v3Fields) {
+	f := v3Fields{Tags: d.Tags, Properties: d.Properties}
+	protocols, typed := fromV3Protocols(d.Protocols)
+	f.TypedProtocols = typed
+	return dtos.Device{
+		DBTimestamp:    d.DBTimestamp,
+		Id:             d.Id,
+		Name:           d.Name,
+		Description:    d.Description,
+		AdminState:     d.AdminState,
+		OperatingState: d.OperatingState,
+		Labels:         d.Labels,
+		Location:       d.Location,
+		ServiceName:    d.ServiceName,
+		ProfileName:    d.ProfileName,
+		AutoEvents:     d.AutoEvents,
+		Protocols:      protocols,
+	}, f
+}
+
+func toV3Device(d dtos.Device, f v3Fields) v3Device {
+	return v3Device{
+		DBTimestamp:    d.DBTimestamp,
+		Id:             d.Id,
+		Name:           d.Name,
+		Description:    d.Description,
+		AdminState:     d.AdminState,
+		OperatingState: d.OperatingState,
+		Labels:         d.Labels,
+		Location:       d.Location,
+		ServiceName:    d.ServiceName,
+		ProfileName:    d.ProfileName,
+		AutoEvents:     d.AutoEvents,
+		Protocols:      toV3Protocols(d.Protocols, f.TypedProtocols),
+		Tags:           f.Tags,
+		Properties:     f.Properties,
+	}
+}
+
+// toV3UpdateDevice converts the v2 UpdateDevice, the v3 API notifies the device service on its own, so notify is dropped
+func toV3UpdateDevice(d dtos.UpdateDevice, f v3Fields) v3UpdateDevice {
+	return v3UpdateDevice{
+		Id:             d.Id,
+		Name:           d.Name,
+		Description:    d.Description,
+		AdminState:     d.AdminState,
+		OperatingState: d.OperatingState,
+		ServiceName:    d.ServiceName,
+		ProfileName:    d.ProfileName,
+		Labels:         d.Labels,
+		Location:       d.Location,
+		AutoEvents:     d.AutoEvents,
+		Protocols:      toV3Protocols(d.Protocols, f.TypedProtocols),
+	}
+}
+
+func fromV3Protocols(pps map[string]map[string]interface{}) (map[string]dtos.ProtocolProperties, map[string][]string) {
+	if pps == nil {
+		return nil, nil
+	}
+	ret := make(map[string]dtos.ProtocolProperties, len(pps))
+	var typed map[string][]string
+	for name, pp := range pps {
+		props, typedProps := toKubeAttributes(pp)
+		ret[name] = props
+		if len(typedProps) != 0 {
+			if typed == nil {
+				typed = map[string][]string{}
+			}
+			typed[name] = typedProps
+		}
+	}
+	return ret, typed
+}
+
+func toV3Protocols(pps map[string]dtos.ProtocolProperties, typed map[string][]string) map[string]map[string]interface{} {
+	if pps == nil {
+		return nil
+	}
+	ret := make(map[string]map[string]interface{}, len(pps))
+	for name, pp := range pps {
+		ret[name] = toEdgeXAttributes(pp, typed[name])
+	}
+	return ret
+}
+
+func fromV3DeviceService(ds v3DeviceService) (dtos.DeviceService, v3Fields) {
+	return dtos.DeviceService{
+		DBTimestamp: ds.DBTimestamp,
+		Id:          ds.Id,
+		Name:        ds.Name,
+		Description: ds.Description,
+		Labels:      ds.Labels,
+		BaseAddress: ds.BaseAddress,
+		AdminState:  ds.AdminState,
+	}, v3Fields{Properties: ds.Properties}
+}
+
+func toV3DeviceService(ds dtos.DeviceService, f v3Fields) v3DeviceService {
+	return v3DeviceService{
+		DBTimestamp: ds.DBTimestamp,
+		Id:          ds.Id,
+		Name:        ds.Name,
+		Description: ds.Description,
+		Labels:      ds.Labels,
+		BaseAddress: ds.BaseAddress,
+		AdminState:  ds.AdminState,
+		Properties:  f.Properties,
+	}
+}
+
+func toV3UpdateDeviceService(ds dtos.UpdateDeviceService) v3UpdateDeviceService {
+	return v3UpdateDeviceService{
+		Id:          ds.Id,
+		Name:        ds.Name,
+		Description: ds.Description,
+		BaseAddress: ds.BaseAddress,
+		Labels:      ds.Labels,
+		AdminState:  ds.AdminState,
+	}
+}
+
+// fromV3DeviceProfile converts the EdgeX v3 deviceProfile to the v2 one, the numeric properties of the deviceResources
+// are formatted as strings
+func fromV3DeviceProfile(dp v3DeviceProfile) (dtos.DeviceProfile, v3Fields) {
+	var f v3Fields
+	edp := dtos.DeviceProfile{
+		DBTimestamp:  dp.DBTimestamp,
+		Id:           dp.Id,
+		Name:         dp.Name,
+		Manufacturer: dp.Manufacturer,
+		Description:  dp.Description,
+		Model:        dp.Model,
+		Labels:       dp.Labels,
+	}
+	if dp.DeviceResources != nil {
+		edp.DeviceResources = make([]dtos.DeviceResource, 0, len(dp.DeviceResources))
+	}
+	for _, dr := range dp.DeviceResources {
+		edp.DeviceResources = append(edp.DeviceResources, dtos.DeviceResource{
+			Description: dr.Description,
+			Name:        dr.Name,
+			IsHidden:    dr.IsHidden,
+			Properties:  fromV3ResourceProperties(dr.Properties),
+			Attributes:  dr.Attributes,
+		})
+		if rf := (v3Fields{Tags: dr.Tags, Optional: dr.Properties.Optional}); !rf.isEmpty() {
+			if f.DeviceResources == nil {
+				f.DeviceResources = map[string]v3Fields{}
+			}
+			f.DeviceResources[dr.Name] = rf
+		}
+	}
+	if dp.DeviceCommands != nil {
+		edp.DeviceCommands = make([]dtos.DeviceCommand, 0, len(dp.DeviceCommands))
+	}
+	for _, dc := range dp.DeviceCommands {
+		edp.DeviceCommands = append(edp.DeviceCommands, dtos.DeviceCommand{
+			Name:               dc.Name,
+			IsHidden:           dc.IsHidden,
+			ReadWrite:          dc.ReadWrite,
+			ResourceOperations: dc.ResourceOperations,
+		})
+		if len(dc.Tags) != 0 {
+			if f.DeviceCommands == nil {
+				f.DeviceCommands = map[string]v3Fields{}
+			}
+			f.DeviceCommands[dc.Name] = v3Fields{Tags: dc.Tags}
+		}
+	}
+	return edp, f
+}
+
+// toV3DeviceProfile converts the v2 deviceProfile to the EdgeX v3 one, the tag of the deviceResources is dropped
+// since the v3 API replaces it with the tags
+func toV3DeviceProfile(dp dtos.DeviceProfile, f v3Fields) v3DeviceProfile {
+	ret := v3DeviceProfile{
+		DBTimestamp:  dp.DBTimestamp,
+		Id:           dp.Id,
+		Name:         dp.Name,
+		Manufacturer: dp.Manufacturer,
+		Description:  dp.Description,
+		Model:        dp.Model,
+		Labels:       dp.Labels,
+	}
+	if dp.DeviceResources != nil {
+		ret.DeviceResources = make([]v3DeviceResource, 0, len(dp.DeviceResources))
+	}
+	for _, dr := range dp.DeviceResources {
+		rf := f.DeviceResources[dr.Name]
+		properties := toV3ResourceProperties(dr.Properties)
+		properties.Optional = rf.Optional
+		ret.DeviceResources = append(ret.DeviceResources, v3DeviceResource{
+			Description: dr.Description,
+			Name:        dr.Name,
+			IsHidden:    dr.IsHidden,
+			Properties:  properties,
+			Attributes:  dr.Attributes,
+			Tags:        rf.Tags,
+		})
+	}
+	if dp.DeviceCommands != nil {
+		ret.DeviceCommands = make([]v3DeviceCommand, 0, len(dp.DeviceCommands))
+	}
+	for _, dc := range dp.DeviceCommands {
+		ret.DeviceCommands = append(ret.DeviceCommands, v3DeviceCommand{
+			Name:               dc.Name,
+			IsHidden:           dc.IsHidden,
+			ReadWrite:          dc.ReadWrite,
+			ResourceOperations: dc.ResourceOperations,
+			Tags:               f.DeviceCommands[dc.Name].Tags,
+		})
+	}
+	return ret
+}
+
+func fromV3ResourceProperties(rp v3ResourceProperties) dtos.ResourceProperties {
+	return dtos.ResourceProperties{
+		ValueType:    rp.ValueType,
+		ReadWrite:    rp.ReadWrite,
+		Units:        rp.Units,
+		Minimum:      formatFloat(rp.Minimum),
+		Maximum:      formatFloat(rp.Maximum),
+		DefaultValue: rp.DefaultValue,
+		Mask:         formatUint(rp.Mask),
+		Shift:        formatInt(rp.Shift),
+		Scale:        formatFloat(rp.Scale),
+		Offset:       formatFloat(rp.Offset),
+		Base:         formatFloat(rp.Base),
+		Assertion:    rp.Assertion,
+		MediaType:    rp.MediaType,
+	}
+}
+
+func toV3ResourceProperties(rp dtos.ResourceProperties) v3ResourceProperties {
+	return v3ResourceProperties{
+		ValueType:    rp.ValueType,
+		ReadWrite:    rp.ReadWrite,
+		Units:        rp.Units,
+		Minimum:      parseFloat("minimum", rp.Minimum),
+		Maximum:      parseFloat("maximum", rp.Maximum),
+		DefaultValue: rp.DefaultValue,
+		Mask:         parseUint("mask", rp.Mask),
+		Shift:        parseInt("shift", rp.Shift),
+		Scale:        parseFloat("scale", rp.Scale),
+		Offset:       parseFloat("offset", rp.Offset),
+		Base:         parseFloat("base", rp.Base),
+		Assertion:    rp.Assertion,
+		MediaType:    rp.MediaType,
+	}
+}
+
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+func formatUint(v *uint64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatUint(*v, 10)
+}
+
+func formatInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+// parseFloat parses the numeric property of the deviceResource, the property which is empty or not a number is left unset
+func parseFloat(name, s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		klog.V(4).ErrorS(err, "the property of deviceResource is not a number, it is not sent to EdgeX", "property", name)
+		return nil
+	}
+	return &v
+}
+
+func parseUint(name, s string) *uint64 {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		klog.V(4).ErrorS(err, "the property of deviceResource is not a number, it is not sent to EdgeX", "property", name)
+		return nil
+	}
+	return &v
+}
+
+func parseInt(name, s string) *int64 {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		klog.V(4).ErrorS(err, "the property of deviceResource is not a number, it is not sent to EdgeX", "property", name)
+		return nil
+	}
+	return &v
+}
+
+func makeV3DeviceRequest(devs []*devicev1alpha1.Device) []*v3DeviceRequest {
+	var req []*v3DeviceRequest
+	for _, dev := range devs {
+		req = append(req, &v3DeviceRequest{
+			BaseRequest: newV3BaseRequest(),
+			Device:      toV3Device(toEdgeXDevice(dev), getV3Fields(dev)),
+		})
+	}
+	return req
+}
+
+func makeV3DeviceUpdateRequest(devs []*devicev1alpha1.Device, fields []string) []*v3UpdateDeviceRequest {
+	var req []*v3UpdateDeviceRequest
+	for _, dev := range devs {
+		req = append(req, &v3UpdateDeviceRequest{
+			BaseRequest: newV3BaseRequest(),
+			Device:      toV3UpdateDevice(toEdgeXUpdateDevice(dev, fields), getV3Fields(dev)),
+		})
+	}
+	return req
+}
+
+func makeV3DeviceServiceRequest(dss []*devicev1alpha1.DeviceService) []*v3DeviceServiceRequest {
+	var req []*v3DeviceServiceRequest
+	for _, ds := range dss {
+		req = append(req, &v3DeviceServiceRequest{
+			BaseRequest: newV3BaseRequest(),
+			Service:     toV3DeviceService(toEdgexDeviceService(ds), getV3Fields(ds)),
+		})
+	}
+	return req
+}
+
+func makeV3DeviceServiceUpdateRequest(dss []*devicev1alpha1.DeviceService, fields []string) []*v3UpdateDeviceServiceRequest {
+	var req []*v3UpdateDeviceServiceRequest
+	for _, ds := range dss {
+		req = append(req, &v3UpdateDeviceServiceRequest{
+			BaseRequest: newV3BaseRequest(),
+			Service:     toV3UpdateDeviceService(toEdgeXUpdateDeviceService(ds, fields)),
+		})
+	}
+	return req
+}
+
+func makeV3DeviceProfilesRequest(dps []*devicev1alpha1.DeviceProfile) []*v3DeviceProfileRequest {
+	var req []*v3DeviceProfileRequest
+	for _, dp := range dps {
+		req = append(req, &v3DeviceProfileRequest{
+			BaseRequest: newV3BaseRequest(),
+			Profile:     toV3DeviceProfile(toEdgeXDeviceProfile(dp), getV3Fields(dp)),
+		})
+	}
+	return req
+}
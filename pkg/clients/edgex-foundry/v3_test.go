@@ -0,0 +1,183 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	V3DeviceMetadata  = `{"apiVersion":"v3","statusCode":200,"device":{"created":1661829206505,"modified":1661829206505,"id":"f6255845-f4b2-4182-bd3c-abc9eac4a649","name":"Modbus-TCP-Device","adminState":"UNLOCKED","operatingState":"UP","labels":["modbus"],"serviceName":"device-modbus","profileName":"Modbus-Device","protocols":{"modbus-tcp":{"Address":"10.0.0.8","Port":502,"UnitID":1}},"tags":{"floor":3},"properties":{"owner":"plant-a"}}}`
+	V3ProfileMetadata = `{"apiVersion":"v3","statusCode":200,"profile":{"created":1661829206490,"modified":1661829206490,"id":"9fb79e54-d6e2-4d1f-9a55-1ec0a7ffc0c6","name":"Modbus-Device","manufacturer":"OpenYurt","description":"","model":"MB-1","labels":["modbus"],"deviceResources":[{"description":"","name":"Temperature","isHidden":false,"properties":{"valueType":"Float32","readWrite":"R","units":"C","minimum":-40,"maximum":125.5,"defaultValue":"","mask":255,"shift":-8,"scale":0.1,"assertion":"","mediaType":"","optional":{"precision":2}},"attributes":{"primaryTable":"HOLDING_REGISTERS","startingAddress":1},"tags":{"unit":"celsius"}},{"description":"","name":"Switch","isHidden":false,"properties":{"valueType":"Bool","readWrite":"RW","units":"","defaultValue":"false","assertion":"","mediaType":""},"attributes":{"primaryTable":"COILS","startingAddress":2}}],"deviceCommands":[{"name":"Status","isHidden":false,"readWrite":"R","resourceOperations":[{"deviceResource":"Temperature","defaultValue":"","mappings":null},{"deviceResource":"Switch","defaultValue":"","mappings":null}],"tags":{"group":"status"}}]}}`
+	V3Version         = `{"apiVersion":"v3","version":"3.1.0","serviceName":"core-metadata"}`
+	V2Version         = `{"apiVersion":"v2","version":"2.1.0"}`
+)
+
+func Test_DetectAPIVersion(t *testing.T) {
+	client := NewEdgexDeviceServiceClient("edgex-core-metadata:59881")
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v3/version",
+		httpmock.NewStringResponder(200, V3Version))
+	version, err := DetectAPIVersion(context.TODO(), client.Client, "edgex-core-metadata:59881")
+	assert.Nil(t, err)
+	assert.Equal(t, APIVersionV3, version)
+
+	// core-metadata of EdgeX 2.x doesn't serve the v3 endpoint
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v3/version",
+		httpmock.NewStringResponder(404, "404 page not found"))
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/version",
+		httpmock.NewStringResponder(200, V2Version))
+	version, err = DetectAPIVersion(context.TODO(), client.Client, "edgex-core-metadata:59881")
+	assert.Nil(t, err)
+	assert.Equal(t, APIVersionV2, version)
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v2/version",
+		httpmock.NewStringResponder(404, "404 page not found"))
+	_, err = DetectAPIVersion(context.TODO(), client.Client, "edgex-core-metadata:59881")
+	assert.NotNil(t, err)
+}
+
+func Test_DetectAPIVersionWithRetry(t *testing.T) {
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+	defer func(backoff wait.Backoff, timeout time.Duration) {
+		detectAPIVersionBackoff, detectAPIVersionTimeout = backoff, timeout
+	}(detectAPIVersionBackoff, detectAPIVersionTimeout)
+	detectAPIVersionBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 5}
+
+	// core-metadata is not reachable until the third attempt
+	attempts := 0
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v3/version",
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts < 3 {
+				return nil, errors.New("connection refused")
+			}
+			return httpmock.NewStringResponse(200, V3Version), nil
+		})
+	version, err := detectAPIVersionWithRetry(client, "edgex-core-metadata:59881")
+	assert.Nil(t, err)
+	assert.Equal(t, APIVersionV3, version)
+	assert.Equal(t, 3, attempts)
+
+	// the last error is returned once the retries are exhausted
+	attempts = 0
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v3/version",
+		httpmock.NewErrorResponder(errors.New("connection refused")))
+	_, err = detectAPIVersionWithRetry(client, "edgex-core-metadata:59881")
+	assert.True(t, clients.IsUnavailableErr(err))
+
+	// and so is it once the timeout elapses
+	detectAPIVersionBackoff, detectAPIVersionTimeout = wait.Backoff{Duration: time.Hour, Steps: 5}, 10*time.Millisecond
+	_, err = detectAPIVersionWithRetry(client, "edgex-core-metadata:59881")
+	assert.True(t, clients.IsUnavailableErr(err))
+}
+
+func Test_V3Device(t *testing.T) {
+	client := NewEdgexDeviceClient("edgex-core-metadata:59881", "edgex-core-command:59882")
+	client.APIVersion = APIVersionV3
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v3/device/name/Modbus-TCP-Device",
+		httpmock.NewStringResponder(200, V3DeviceMetadata))
+	device, err := client.Get(context.TODO(), "Modbus-TCP-Device", clients.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, devicev1alpha1.ProtocolProperties{"Address": "10.0.0.8", "Port": "502", "UnitID": "1"}, device.Spec.Protocols["modbus-tcp"])
+	assert.JSONEq(t, `{"tags":{"floor":3},"properties":{"owner":"plant-a"},"typedProtocols":{"modbus-tcp":["Port","UnitID"]}}`,
+		device.Annotations[devicev1alpha1.EdgeXV3Fields])
+
+	// the device is added with the typed protocol properties, tags and properties it is read with
+	var sent []map[string]interface{}
+	httpmock.RegisterResponder("POST", "http://edgex-core-metadata:59881/api/v3/device",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := json.Unmarshal(body, &sent); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(207, DeviceCreateSuccess), nil
+		})
+	_, err = client.Create(context.TODO(), device, clients.CreateOptions{})
+	assert.Nil(t, err)
+	var expected map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(V3DeviceMetadata), &expected))
+	assert.Equal(t, "v3", sent[0]["apiVersion"])
+	assert.Equal(t, expected["device"], sent[0]["device"])
+
+	// the v3 API takes true or false as the read options
+	getURL := "http://edgex-core-command:59882/api/v3/device/name/Modbus-TCP-Device/Temperature"
+	httpmock.RegisterResponderWithQuery("GET", getURL, "ds-pushevent=true&ds-returnevent=false",
+		httpmock.NewStringResponder(200, `{"apiVersion":"v3","statusCode":200}`))
+	device.Status.DeviceProperties = map[string]devicev1alpha1.ActualPropertyState{
+		"Temperature": {Name: "Temperature", GetURL: getURL, ActualValue: "21.5"},
+	}
+	yes, no := true, false
+	aps, err := client.GetPropertyState(context.TODO(), "Temperature", device, clients.GetOptions{
+		ReadOptions: clients.PropertyReadOptions{PushEvent: &yes, ReturnEvent: &no},
+	})
+	assert.Nil(t, err)
+	assert.True(t, aps.Unknown)
+	assert.Equal(t, "", aps.ActualValue)
+}
+
+func Test_V3DeviceProfile(t *testing.T) {
+	client := NewEdgexDeviceProfile("edgex-core-metadata:59881")
+	client.APIVersion = APIVersionV3
+	httpmock.ActivateNonDefault(client.Client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://edgex-core-metadata:59881/api/v3/deviceprofile/name/Modbus-Device",
+		httpmock.NewStringResponder(200, V3ProfileMetadata))
+	dp, err := client.Get(context.TODO(), "Modbus-Device", clients.GetOptions{})
+	assert.Nil(t, err)
+	props := dp.Spec.DeviceResources[0].Properties
+	assert.Equal(t, []string{"-40", "125.5", "255", "-8", "0.1", ""}, []string{props.Minimum, props.Maximum, props.Mask, props.Shift, props.Scale, props.Offset})
+
+	// the deviceProfile is sent back to EdgeX without loss
+	var sent []map[string]interface{}
+	httpmock.RegisterResponder("PUT", "http://edgex-core-metadata:59881/api/v3/deviceprofile",
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := json.Unmarshal(body, &sent); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(207, `[{"apiVersion":"v3","statusCode":200}]`), nil
+		})
+	_, err = client.Update(context.TODO(), dp, clients.UpdateOptions{})
+	assert.Nil(t, err)
+	var expected map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(V3ProfileMetadata), &expected))
+	assert.Equal(t, "v3", sent[0]["apiVersion"])
+	assert.Equal(t, expected["profile"], sent[0]["profile"])
+}
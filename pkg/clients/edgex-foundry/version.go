@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edgex_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
+	edgex_resp "github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/responses"
+	"github.com/go-resty/resty/v2"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// APIVersionV3 is the API version of EdgeX 3.x
+	APIVersionV3 = "v3"
+	// VersionPath is the endpoint which reports the version of an EdgeX service
+	VersionPath = "/api/v2/version"
+)
+
+// supportedAPIVersions are the EdgeX API versions the clients speak, the newest first
+var supportedAPIVersions = []string{APIVersionV3, APIVersionV2, APIVersionV1}
+
+var (
+	// the API version is detected again with backoff while core-metadata can't be reached, e.g. EdgeX starts
+	// along with the controller, and the detection gives up once detectAPIVersionTimeout elapses
+	detectAPIVersionTimeout = 2 * time.Minute
+	detectAPIVersionBackoff = wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.1, Steps: 10, Cap: 30 * time.Second}
+)
+
+// apiPath returns the path in the API version, the paths of the package are defined in the v2 API,
+// the v3 API keeps their layout and the v1 API serves the objects by name under the same paths
+func apiPath(version, path string) string {
+	if version == "" || version == APIVersionV2 {
+		return path
+	}
+	return strings.Replace(path, "/api/"+APIVersionV2, "/api/"+version, 1)
+}
+
+// versionPath returns the version endpoint of the API version, EdgeX 1.x serves it out of the versioned API
+func versionPath(version string) string {
+	if version == APIVersionV1 {
+		return V1VersionPath
+	}
+	return apiPath(version, VersionPath)
+}
+
+// ValidateAPIVersion checks whether the clients speak the EdgeX API version
+func ValidateAPIVersion(version string) error {
+	for _, v := range supportedAPIVersions {
+		if version == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported EdgeX API version %q, supported versions: %v", version, supportedAPIVersions)
+}
+
+// DetectAPIVersion asks core-metadata for the API version it serves through the version endpoint,
+// the newest version is asked first since the older core-metadata doesn't serve its endpoint
+func DetectAPIVersion(ctx context.Context, client *resty.Client, coreMetaAddr string) (string, error) {
+	for _, version := range supportedAPIVersions {
+		getURL := fmt.Sprintf("%s%s", baseURL(coreMetaAddr), versionPath(version))
+		resp, err := client.R().SetContext(ctx).Get(getURL)
+		if err != nil {
+			return "", newRequestError(err, "failed to get the version of core-metadata")
+		}
+		if resp.StatusCode() == http.StatusNotFound {
+			continue
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return "", newResponseError(resp, "failed to get the version of core-metadata")
+		}
+		var vResp common.VersionResponse
+		if err := json.Unmarshal(resp.Body(), &vResp); err != nil {
+			return "", err
+		}
+		klog.V(2).InfoS("detected the EdgeX API version", "apiVersion", version, "version", vResp.Version)
+		return version, nil
+	}
+	return "", fmt.Errorf("core-metadata %s serves none of the EdgeX API versions %v", coreMetaAddr, supportedAPIVersions)
+}
+
+// detectAPIVersionWithRetry detects the API version with backoff until it succeeds or detectAPIVersionTimeout elapses,
+// the last error of the detection is returned if it doesn't succeed
+func detectAPIVersionWithRetry(client *resty.Client, coreMetaAddr string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), detectAPIVersionTimeout)
+	defer cancel()
+	var version string
+	var detectErr error
+	err := wait.ExponentialBackoffWithContext(ctx, detectAPIVersionBackoff, func() (bool, error) {
+		version, detectErr = DetectAPIVersion(ctx, client, coreMetaAddr)
+		if detectErr != nil {
+			klog.V(2).InfoS("failed to detect the EdgeX API version, retrying", "coreMetadata", coreMetaAddr, "err", detectErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && detectErr != nil {
+		return "", detectErr
+	}
+	return version, err
+}
+
+func makeDeviceRequest(version string, devs []*devicev1alpha1.Device) interface{} {
+	if version == APIVersionV3 {
+		return makeV3DeviceRequest(devs)
+	}
+	return makeEdgeXDeviceRequest(devs)
+}
+
+func makeDeviceUpdateRequest(version string, devs []*devicev1alpha1.Device, fields []string) interface{} {
+	if version == APIVersionV3 {
+		return makeV3DeviceUpdateRequest(devs, fields)
+	}
+	return makeEdgeXDeviceUpdateRequest(devs, fields)
+}
+
+func makeDeviceServiceRequest(version string, dss []*devicev1alpha1.DeviceService) interface{} {
+	if version == APIVersionV3 {
+		return makeV3DeviceServiceRequest(dss)
+	}
+	return makeEdgeXDeviceService(dss)
+}
+
+func makeDeviceServiceUpdateRequest(version string, dss []*devicev1alpha1.DeviceService, fields []string) interface{} {
+	if version == APIVersionV3 {
+		return makeV3DeviceServiceUpdateRequest(dss, fields)
+	}
+	return makeEdgeXDeviceServiceUpdateRequest(dss, fields)
+}
+
+func makeDeviceProfilesRequest(version string, dps []*devicev1alpha1.DeviceProfile) interface{} {
+	if version == APIVersionV3 {
+		return makeV3DeviceProfilesRequest(dps)
+	}
+	return makeEdgeXDeviceProfilesRequest(dps)
+}
+
+// decodeDevice decodes the device in the response of the API version, the v3 device is converted to the v2 one
+// and its fields which have no counterpart in v2 are returned along with it
+func decodeDevice(version string, body []byte) (dtos.Device, v3Fields, error) {
+	if version != APIVersionV3 {
+		var resp edgex_resp.DeviceResponse
+		err := json.Unmarshal(body, &resp)
+		return resp.Device, v3Fields{}, err
+	}
+	var resp v3DeviceResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return dtos.Device{}, v3Fields{}, err
+	}
+	d, f := fromV3Device(resp.Device)
+	return d, f, nil
+}
+
+// decodeMultiDevices decodes the devices in the response of the API version like decodeDevice, and the total count
+func decodeMultiDevices(version string, body []byte) ([]dtos.Device, []v3Fields, uint32, error) {
+	if version != APIVersionV3 {
+		var resp edgex_resp.MultiDevicesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, nil, 0, err
+		}
+		return resp.Devices, make([]v3Fields, len(resp.Devices)), resp.TotalCount, nil
+	}
+	var resp v3MultiDevicesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, nil, 0, err
+	}
+	devices := make([]dtos.Device, len(resp.Devices))
+	fields := make([]v3Fields, len(resp.Devices))
+	for i := range resp.Devices {
+		devices[i], fields[i] = fromV3Device(resp.Devices[i])
+	}
+	return devices, fields, resp.TotalCount, nil
+}
+
+// decodeDeviceService decodes the deviceService in the response of the API version like decodeDevice
+func decodeDeviceService(version string, body []byte) (dtos.DeviceService, v3Fields, error) {
+	if version != APIVersionV3 {
+		var resp edgex_resp.DeviceServiceResponse
+		err := json.Unmarshal(body, &resp)
+		return resp.Service, v3Fields{}, err
+	}
+	var resp v3DeviceServiceResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return dtos.DeviceService{}, v3Fields{}, err
+	}
+	ds, f := fromV3DeviceService(resp.Service)
+	return ds, f, nil
+}
+
+// decodeMultiDeviceServices decodes the deviceServices in the response of the API version like decodeMultiDevices
+func decodeMultiDeviceServices(version string, body []byte) ([]dtos.DeviceService, []v3Fields, uint32, error) {
+	if version != APIVersionV3 {
+		var resp edgex_resp.MultiDeviceServicesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, nil, 0, err
+		}
+		return resp.Services, make([]v3Fields, len(resp.Services)), resp.TotalCount, nil
+	}
+	var resp v3MultiDeviceServicesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, nil, 0, err
+	}
+	services := make([]dtos.DeviceService, len(resp.Services))
+	fields := make([]v3Fields, len(resp.Services))
+	for i := range resp.Services {
+		services[i], fields[i] = fromV3DeviceService(resp.Services[i])
+	}
+	return services, fields, resp.TotalCount, nil
+}
+
+// decodeDeviceProfile decodes the deviceProfile in the response of the API version like decodeDevice
+func decodeDeviceProfile(version string, body []byte) (dtos.DeviceProfile, v3Fields, error) {
+	if version != APIVersionV3 {
+		var resp edgex_resp.DeviceProfileResponse
+		err := json.Unmarshal(body, &resp)
+		return resp.Profile, v3Fields{}, err
+	}
+	var resp v3DeviceProfileResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return dtos.DeviceProfile{}, v3Fields{}, err
+	}
+	dp, f := fromV3DeviceProfile(resp.Profile)
+	return dp, f, nil
+}
+
+// decodeMultiDeviceProfiles decodes the deviceProfiles in the response of the API version like decodeMultiDevices
+func decodeMultiDeviceProfiles(version string, body []byte) ([]dtos.DeviceProfile, []v3Fields, uint32, error) {
+	if version != APIVersionV3 {
+		var resp edgex_resp.MultiDeviceProfilesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, nil, 0, err
+		}
+		return resp.Profiles, make([]v3Fields, len(resp.Profiles)), resp.TotalCount, nil
+	}
+	var resp v3MultiDeviceProfilesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, nil, 0, err
+	}
+	profiles := make([]dtos.DeviceProfile, len(resp.Profiles))
+	fields := make([]v3Fields, len(resp.Profiles))
+	for i := range resp.Profiles {
+		profiles[i], fields[i] = fromV3DeviceProfile(resp.Profiles[i])
+	}
+	return profiles, fields, resp.TotalCount, nil
+}
+
+// readOptionValue returns the value of the ds-pushevent and ds-returnevent query parameters,
+// the v2 API takes yes or no and the v3 API takes true or false
+func readOptionValue(version string, b bool) string {
+	if version != APIVersionV3 {
+		return yesOrNo(b)
+	}
+	return strconv.FormatBool(b)
+}
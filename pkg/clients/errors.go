@@ -16,15 +16,160 @@ limitations under the License.
 
 package clients
 
-import "strings"
+import (
+	"errors"
+	"net/http"
+	"time"
+)
 
-type NotFoundError struct{}
+// StatusReason is the category of the error returned by the edge platform
+type StatusReason string
 
-func (e *NotFoundError) Error() string { return "Item not found" }
+const (
+	// StatusReasonNotFound means the requested object does not exist on the edge platform
+	StatusReasonNotFound StatusReason = "NotFound"
+	// StatusReasonAlreadyExists means the object to be created already exists on the edge platform
+	StatusReasonAlreadyExists StatusReason = "AlreadyExists"
+	// StatusReasonConflict means the request conflicts with the current state of the object on the edge platform
+	StatusReasonConflict StatusReason = "Conflict"
+	// StatusReasonLocked means the device is locked (AdminState) or down (OperatingState)
+	StatusReasonLocked StatusReason = "Locked"
+	// StatusReasonInvalidRequest means the request is rejected by the edge platform because it is invalid
+	StatusReasonInvalidRequest StatusReason = "InvalidRequest"
+	// StatusReasonUnavailable means the edge platform can not be reached or is not able to serve the request
+	StatusReasonUnavailable StatusReason = "Unavailable"
+	// StatusReasonUnauthorized means the request is not authorized by the edge platform
+	StatusReasonUnauthorized StatusReason = "Unauthorized"
+	// StatusReasonForbidden means the credentials are accepted but the request is not permitted by the edge platform
+	StatusReasonForbidden StatusReason = "Forbidden"
+	// StatusReasonUnknown means the error can not be classified
+	StatusReasonUnknown StatusReason = "Unknown"
+)
 
-func IsNotFoundErr(err error) bool {
-	if err == nil {
-		return false
+// ErrIncompleteList is returned by ListPages if the objects on the edge platform changed while they were listed,
+// so that some of them may be missing from the pages
+var ErrIncompleteList = errors.New("the objects on the edge platform changed while they were listed page by page")
+
+// StatusError is the error returned by the edge platform clients,
+// it can be retrieved by errors.As and carries the response of the edge platform
+type StatusError struct {
+	Reason StatusReason
+	// StatusCode is the HTTP status code returned by the edge platform, 0 means the edge platform is not reached
+	StatusCode int
+	Message    string
+	// Response is the raw response body returned by the edge platform
+	Response []byte
+	// Err is the underlying error which causes the failure, if any
+	Err error
+	// RetryAfter is the suggested delay before retrying, 0 means no suggestion
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string { return e.Message }
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// NewStatusError returns a StatusError with the given reason
+func NewStatusError(reason StatusReason, statusCode int, message string, response []byte) *StatusError {
+	return &StatusError{
+		Reason:     reason,
+		StatusCode: statusCode,
+		Message:    message,
+		Response:   response,
 	}
-	return strings.Contains(err.Error(), "not found") || strings.HasPrefix(err.Error(), "no item found")
+}
+
+// NewNotFoundError returns a StatusError indicating that the object does not exist on the edge platform
+func NewNotFoundError(message string) *StatusError {
+	return NewStatusError(StatusReasonNotFound, http.StatusNotFound, message, nil)
+}
+
+// NewUnavailableError returns a StatusError indicating that the edge platform can not be reached
+func NewUnavailableError(message string, err error) *StatusError {
+	return &StatusError{
+		Reason:  StatusReasonUnavailable,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// ReasonForStatusCode returns the reason corresponding to the HTTP status code returned by the edge platform
+func ReasonForStatusCode(statusCode int) StatusReason {
+	switch statusCode {
+	case http.StatusNotFound:
+		return StatusReasonNotFound
+	case http.StatusConflict:
+		return StatusReasonConflict
+	case http.StatusLocked:
+		return StatusReasonLocked
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return StatusReasonInvalidRequest
+	case http.StatusUnauthorized:
+		return StatusReasonUnauthorized
+	case http.StatusForbidden:
+		return StatusReasonForbidden
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return StatusReasonUnavailable
+	}
+	return StatusReasonUnknown
+}
+
+// ReasonForError returns the reason of the StatusError in the chain of err,
+// StatusReasonUnknown is returned if there is no StatusError in it
+func ReasonForError(err error) StatusReason {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Reason
+	}
+	return StatusReasonUnknown
+}
+
+// SuggestsRetryAfter returns the delay suggested by the StatusError in the chain of err before retrying,
+// and whether there is a suggestion
+func SuggestsRetryAfter(err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// IsNotFoundErr returns true if the error indicates that the object does not exist on the edge platform
+func IsNotFoundErr(err error) bool {
+	return ReasonForError(err) == StatusReasonNotFound
+}
+
+// IsAlreadyExistsErr returns true if the error indicates that the object already exists on the edge platform
+func IsAlreadyExistsErr(err error) bool {
+	return ReasonForError(err) == StatusReasonAlreadyExists
+}
+
+// IsConflictErr returns true if the error indicates that the request conflicts with the object on the edge platform
+func IsConflictErr(err error) bool {
+	return ReasonForError(err) == StatusReasonConflict
+}
+
+// IsLockedErr returns true if the error indicates that the device is locked or down
+func IsLockedErr(err error) bool {
+	return ReasonForError(err) == StatusReasonLocked
+}
+
+// IsInvalidRequestErr returns true if the error indicates that the request is rejected as invalid
+func IsInvalidRequestErr(err error) bool {
+	return ReasonForError(err) == StatusReasonInvalidRequest
+}
+
+// IsUnavailableErr returns true if the error indicates that the edge platform can not serve the request
+func IsUnavailableErr(err error) bool {
+	return ReasonForError(err) == StatusReasonUnavailable
+}
+
+// IsUnauthorizedErr returns true if the error indicates that the request is not authorized by the edge platform
+func IsUnauthorizedErr(err error) bool {
+	return ReasonForError(err) == StatusReasonUnauthorized
+}
+
+// IsForbiddenErr returns true if the error indicates that the request is not permitted by the edge platform
+func IsForbiddenErr(err error) bool {
+	return ReasonForError(err) == StatusReasonForbidden
 }
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReasonForStatusCode(t *testing.T) {
+	cases := map[int]StatusReason{
+		http.StatusNotFound:            StatusReasonNotFound,
+		http.StatusConflict:            StatusReasonConflict,
+		http.StatusLocked:              StatusReasonLocked,
+		http.StatusBadRequest:          StatusReasonInvalidRequest,
+		http.StatusUnauthorized:        StatusReasonUnauthorized,
+		http.StatusForbidden:           StatusReasonForbidden,
+		http.StatusServiceUnavailable:  StatusReasonUnavailable,
+		http.StatusInternalServerError: StatusReasonUnknown,
+	}
+	for code, reason := range cases {
+		assert.Equal(t, reason, ReasonForStatusCode(code), "status code %d", code)
+	}
+}
+
+func Test_ReasonForError(t *testing.T) {
+	err := NewStatusError(StatusReasonLocked, http.StatusLocked, "device is locked", []byte(`{"statusCode":423}`))
+	wrapped := fmt.Errorf("failed to get property: %w", err)
+	assert.True(t, IsLockedErr(wrapped))
+	assert.False(t, IsNotFoundErr(wrapped))
+
+	var statusErr *StatusError
+	assert.True(t, errors.As(wrapped, &statusErr))
+	assert.Equal(t, http.StatusLocked, statusErr.StatusCode)
+
+	// the text of an untyped error is never inspected
+	assert.False(t, IsNotFoundErr(errors.New("device not found")))
+	assert.False(t, IsNotFoundErr(nil))
+
+	cause := errors.New("connection refused")
+	unavailable := NewUnavailableError("failed to get device: connection refused", cause)
+	assert.True(t, IsUnavailableErr(unavailable))
+	assert.True(t, errors.Is(unavailable, cause))
+}
@@ -32,11 +32,36 @@ type DeleteOptions struct{}
 
 // UpdateOptions defines additional options when updating an object
 // Additional general field definitions can be added
-type UpdateOptions struct{}
+type UpdateOptions struct {
+	// UpdateFields restricts the update to the listed spec fields of the object,
+	// the fields are identified by their json names, such as "description" and "labels".
+	// Defaults to all the fields.
+	// +optional
+	UpdateFields []string
+}
 
 // GetOptions defines additional options when getting an object
 // Additional general field definitions can be added
-type GetOptions struct{}
+type GetOptions struct {
+	// ReadOptions controls how the actual state of a property is read from the device,
+	// it is only used by GetPropertyState.
+	// +optional
+	ReadOptions PropertyReadOptions
+}
+
+// PropertyReadOptions defines how the actual state of the device properties is read from the devices,
+// it is ignored if the actual states are not read from the devices
+type PropertyReadOptions struct {
+	// PushEvent makes the edge platform persist the reading taken by the read request.
+	// Defaults to the behavior of the edge platform.
+	// +optional
+	PushEvent *bool
+	// ReturnEvent makes the edge platform return the reading taken by the read request, the actual state
+	// in the device status is kept if it is false because the reading is not known.
+	// Defaults to the behavior of the edge platform.
+	// +optional
+	ReturnEvent *bool
+}
 
 // ListOptions defines additional options when listing an object
 type ListOptions struct {
@@ -48,16 +73,79 @@ type ListOptions struct {
 	// Defaults to everything.
 	// +optional
 	FieldSelector map[string]string
+	// The maximum number of objects returned by List, or the number of objects in each page of ListPages.
+	// Defaults to all the objects for List, and DefaultPageSize for ListPages.
+	// +optional
+	Limit int
+	// The number of objects skipped before the first returned one.
+	// +optional
+	Offset int
+	// ReadOptions controls how the actual states of the properties are read from the device by ListPropertiesState,
+	// it is overridden by PropertyReadOptions for the properties in it.
+	// +optional
+	ReadOptions PropertyReadOptions
+	// PropertyReadOptions are the read options of the properties keyed by the property names.
+	// +optional
+	PropertyReadOptions map[string]PropertyReadOptions
 }
 
+// ReadOptionsOf returns the read options of the property
+func (o ListOptions) ReadOptionsOf(propertyName string) PropertyReadOptions {
+	if ro, exists := o.PropertyReadOptions[propertyName]; exists {
+		return ro
+	}
+	return o.ReadOptions
+}
+
+// BatchResult is the result of an object in a batch request,
+// the results are in the same order as the objects in the request
+type BatchResult struct {
+	// Name is the name of the object on the edge platform
+	Name string
+	// EdgeId is the id of the object on the edge platform, it is only set by the batch creation
+	EdgeId string
+	// Err is the error returned by the edge platform for the object, nil means the object is handled successfully
+	Err error
+}
+
+// RequestLimiter budgets the requests sent to the edge platform, such as *rate.Limiter
+type RequestLimiter interface {
+	// Wait blocks until a request is allowed to be sent, or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// requestLimiterKey is the key of the RequestLimiter in the context
+type requestLimiterKey struct{}
+
+// WithRequestLimiter returns the context whose requests to the edge platform take their budget from the limiter,
+// every request sent takes a token, including each retry of it
+func WithRequestLimiter(ctx context.Context, limiter RequestLimiter) context.Context {
+	return context.WithValue(ctx, requestLimiterKey{}, limiter)
+}
+
+// RequestLimiterFrom returns the RequestLimiter of the context, or nil if the requests are not budgeted
+func RequestLimiterFrom(ctx context.Context) RequestLimiter {
+	limiter, _ := ctx.Value(requestLimiterKey{}).(RequestLimiter)
+	return limiter
+}
+
+// DefaultPageSize is the number of objects in each page of ListPages if the limit is not specified
+const DefaultPageSize = 100
+
 // DeviceInterface defines the interfaces which used to create, delete, update, get and list Device objects on edge-side platform
 type DeviceInterface interface {
 	DevicePropertyInterface
 	Create(ctx context.Context, device *devicev1alpha1.Device, options CreateOptions) (*devicev1alpha1.Device, error)
 	Delete(ctx context.Context, name string, options DeleteOptions) error
 	Update(ctx context.Context, device *devicev1alpha1.Device, options UpdateOptions) (*devicev1alpha1.Device, error)
+	// CreateBatch creates the devices with a single request and returns the result of each of them
+	CreateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options CreateOptions) ([]BatchResult, error)
+	// UpdateBatch updates the devices with a single request and returns the result of each of them
+	UpdateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options UpdateOptions) ([]BatchResult, error)
 	Get(ctx context.Context, name string, options GetOptions) (*devicev1alpha1.Device, error)
 	List(ctx context.Context, options ListOptions) ([]devicev1alpha1.Device, error)
+	// ListPages calls fn with each page of the devices, until all the pages are consumed or fn returns an error
+	ListPages(ctx context.Context, options ListOptions, fn func(devices []devicev1alpha1.Device) error) error
 }
 
 // DevicePropertyInterface defines the interfaces which used to get, list and set the actual status value of the device properties
@@ -72,8 +160,14 @@ type DeviceServiceInterface interface {
 	Create(ctx context.Context, deviceService *devicev1alpha1.DeviceService, options CreateOptions) (*devicev1alpha1.DeviceService, error)
 	Delete(ctx context.Context, name string, options DeleteOptions) error
 	Update(ctx context.Context, deviceService *devicev1alpha1.DeviceService, options UpdateOptions) (*devicev1alpha1.DeviceService, error)
+	// CreateBatch creates the deviceServices with a single request and returns the result of each of them
+	CreateBatch(ctx context.Context, deviceServices []*devicev1alpha1.DeviceService, options CreateOptions) ([]BatchResult, error)
+	// UpdateBatch updates the deviceServices with a single request and returns the result of each of them
+	UpdateBatch(ctx context.Context, deviceServices []*devicev1alpha1.DeviceService, options UpdateOptions) ([]BatchResult, error)
 	Get(ctx context.Context, name string, options GetOptions) (*devicev1alpha1.DeviceService, error)
 	List(ctx context.Context, options ListOptions) ([]devicev1alpha1.DeviceService, error)
+	// ListPages calls fn with each page of the deviceServices, until all the pages are consumed or fn returns an error
+	ListPages(ctx context.Context, options ListOptions, fn func(deviceServices []devicev1alpha1.DeviceService) error) error
 }
 
 // DeviceProfileInterface defines the interfaces which used to create, delete, update, get and list DeviceProfile objects on edge-side platform
@@ -81,6 +175,12 @@ type DeviceProfileInterface interface {
 	Create(ctx context.Context, deviceProfile *devicev1alpha1.DeviceProfile, options CreateOptions) (*devicev1alpha1.DeviceProfile, error)
 	Delete(ctx context.Context, name string, options DeleteOptions) error
 	Update(ctx context.Context, deviceProfile *devicev1alpha1.DeviceProfile, options UpdateOptions) (*devicev1alpha1.DeviceProfile, error)
+	// CreateBatch creates the deviceProfiles with a single request and returns the result of each of them
+	CreateBatch(ctx context.Context, deviceProfiles []*devicev1alpha1.DeviceProfile, options CreateOptions) ([]BatchResult, error)
+	// UpdateBatch updates the deviceProfiles with a single request and returns the result of each of them
+	UpdateBatch(ctx context.Context, deviceProfiles []*devicev1alpha1.DeviceProfile, options UpdateOptions) ([]BatchResult, error)
 	Get(ctx context.Context, name string, options GetOptions) (*devicev1alpha1.DeviceProfile, error)
 	List(ctx context.Context, options ListOptions) ([]devicev1alpha1.DeviceProfile, error)
+	// ListPages calls fn with each page of the deviceProfiles, until all the pages are consumed or fn returns an error
+	ListPages(ctx context.Context, options ListOptions, fn func(deviceProfiles []devicev1alpha1.DeviceProfile) error) error
 }
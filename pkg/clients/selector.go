@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The labels of the objects on the edge platform are plain strings, a LabelSelector entry "key": "value"
+// is satisfied by the label "key=value", and an entry with an empty value is satisfied by the label "key".
+
+// SelectorLabels returns the labels that are required by the label selector, sorted
+func SelectorLabels(selector map[string]string) []string {
+	labels := make([]string, 0, len(selector))
+	for k, v := range selector {
+		if v == "" {
+			labels = append(labels, k)
+		} else {
+			labels = append(labels, k+"="+v)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// LabelsMatch returns true if the labels of an object contain all the labels required by the label selector
+func LabelsMatch(labels []string, selector map[string]string) bool {
+	if len(selector) == 0 {
+		return true
+	}
+	owned := make(map[string]struct{}, len(labels))
+	for _, l := range labels {
+		owned[l] = struct{}{}
+	}
+	for _, l := range SelectorLabels(selector) {
+		if _, ok := owned[l]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldsMatch returns true if every field of the field selector has the same value in fields
+func FieldsMatch(fields map[string]string, selector map[string]string) bool {
+	for k, v := range selector {
+		if fields[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSelector parses a selector in the form of "key1=value1,key2", an entry without "=" has an empty value
+func ParseSelector(s string) (map[string]string, error) {
+	selector := map[string]string{}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		kv := strings.SplitN(term, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, fmt.Errorf("invalid selector term %q: the key is empty", term)
+		}
+		if len(kv) == 2 {
+			selector[key] = strings.TrimSpace(kv[1])
+		} else {
+			selector[key] = ""
+		}
+	}
+	return selector, nil
+}
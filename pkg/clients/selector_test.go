@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseSelector(t *testing.T) {
+	selector, err := ParseSelector(" floor=1, sensor ,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"floor": "1", "sensor": ""}, selector)
+	assert.Equal(t, []string{"floor=1", "sensor"}, SelectorLabels(selector))
+
+	selector, err = ParseSelector("")
+	assert.Nil(t, err)
+	assert.Empty(t, selector)
+
+	_, err = ParseSelector("=1")
+	assert.NotNil(t, err)
+}
+
+func Test_LabelsMatch(t *testing.T) {
+	labels := []string{"floor=1", "sensor", "temperature"}
+	assert.True(t, LabelsMatch(labels, nil))
+	assert.True(t, LabelsMatch(labels, map[string]string{"floor": "1", "sensor": ""}))
+	assert.False(t, LabelsMatch(labels, map[string]string{"floor": "2"}))
+	assert.False(t, LabelsMatch(labels, map[string]string{"floor": ""}))
+	assert.False(t, LabelsMatch(nil, map[string]string{"sensor": ""}))
+}
+
+func Test_FieldsMatch(t *testing.T) {
+	fields := map[string]string{"name": "Random-Float-Device", "serviceName": "device-virtual"}
+	assert.True(t, FieldsMatch(fields, nil))
+	assert.True(t, FieldsMatch(fields, map[string]string{"serviceName": "device-virtual"}))
+	assert.False(t, FieldsMatch(fields, map[string]string{"serviceName": "device-modbus"}))
+	assert.False(t, FieldsMatch(fields, map[string]string{"profileName": "Random-Float-Device"}))
+}
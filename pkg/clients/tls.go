@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSMaterial holds the PEM encoded CA bundle which verifies the edge platform,
+// and the client certificate and key which the controller presents to it, each of them is optional
+type TLSMaterial struct {
+	CA   []byte
+	Cert []byte
+	Key  []byte
+}
+
+// TLSFunc returns the current TLS material, it is called again periodically so that the rotated material is picked up
+type TLSFunc func(ctx context.Context) (*TLSMaterial, error)
+
+// FileTLS returns the TLS material read from the files, the files whose paths are empty are skipped
+func FileTLS(caFile, certFile, keyFile string) TLSFunc {
+	return func(ctx context.Context) (*TLSMaterial, error) {
+		var m TLSMaterial
+		for _, f := range []struct {
+			path string
+			data *[]byte
+		}{{caFile, &m.CA}, {certFile, &m.Cert}, {keyFile, &m.Key}} {
+			if f.path == "" {
+				continue
+			}
+			data, err := ioutil.ReadFile(f.path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", f.path, err)
+			}
+			*f.data = data
+		}
+		return &m, nil
+	}
+}
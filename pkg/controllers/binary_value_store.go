@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// binaryValueKey is the key of the binary value in the Secret or ConfigMap which stores it
+	binaryValueKey = "value"
+	// BinaryValueMediaTypeAnnotation records the media type of the binary value on the object which stores it
+	BinaryValueMediaTypeAnnotation = "device.openyurt.io/media-type"
+)
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=create;update;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=create;update;delete
+
+// binaryValueStore stores the binary values read from the devices in Secrets or ConfigMaps owned by the devices,
+// so that only their hashes and sizes are kept in the device status
+type binaryValueStore struct {
+	client.Client
+	// kind is either "Secret" or "ConfigMap", the values are not stored if it is empty
+	kind string
+	// the values larger than sizeLimit are not stored
+	sizeLimit int64
+}
+
+func newBinaryValueStore(c client.Client, opts *options.YurtDeviceControllerOptions) *binaryValueStore {
+	return &binaryValueStore{
+		Client:    c,
+		kind:      opts.BinaryValueStore,
+		sizeLimit: int64(opts.BinaryValueSizeLimit),
+	}
+}
+
+// storeValues stores the binary values of the properties which are just read from the device
+func (s *binaryValueStore) storeValues(ctx context.Context, d *devicev1alpha1.Device, properties map[string]devicev1alpha1.ActualPropertyState) {
+	for name, aps := range properties {
+		if aps.BinaryValue == nil || aps.BinaryValue.Data == nil {
+			continue
+		}
+		s.storeValue(ctx, d, &aps)
+		properties[name] = aps
+	}
+}
+
+// storeValue stores the binary value of the property if it is just read from the device and differs from the stored one,
+// the reference to the stored value is recorded in the actual state and the data is dropped
+func (s *binaryValueStore) storeValue(ctx context.Context, d *devicev1alpha1.Device, aps *devicev1alpha1.ActualPropertyState) {
+	if aps.BinaryValue == nil || aps.BinaryValue.Data == nil {
+		return
+	}
+	bv := aps.BinaryValue.DeepCopy()
+	data := bv.Data
+	bv.Data, bv.ValueFrom = nil, nil
+	aps.BinaryValue = bv
+	if s == nil || s.kind == "" {
+		return
+	}
+	// the value stored before is kept if it is unchanged, so that reading the same value doesn't rewrite it
+	if oldAps, exists := d.Status.DeviceProperties[aps.Name]; exists && oldAps.BinaryValue != nil && oldAps.BinaryValue.ValueFrom != nil &&
+		oldAps.BinaryValue.SHA256 == bv.SHA256 && oldAps.BinaryValue.ValueFrom.Kind == s.kind {
+		bv.ValueFrom = oldAps.BinaryValue.ValueFrom.DeepCopy()
+		return
+	}
+	if bv.Size > s.sizeLimit {
+		klog.V(4).Infof("DeviceName: %s, the binary value of property %s is not stored, its size %d exceeds the limit %d",
+			d.GetName(), aps.Name, bv.Size, s.sizeLimit)
+		return
+	}
+
+	meta := metav1.ObjectMeta{
+		Name:        binaryValueObjectName(d.GetName(), aps.Name),
+		Namespace:   d.GetNamespace(),
+		Annotations: map[string]string{BinaryValueMediaTypeAnnotation: aps.MediaType},
+		OwnerReferences: []metav1.OwnerReference{
+			*metav1.NewControllerRef(d, devicev1alpha1.GroupVersion.WithKind("Device")),
+		},
+	}
+	var obj client.Object
+	if s.kind == "Secret" {
+		obj = &corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{binaryValueKey: data}}
+	} else {
+		obj = &corev1.ConfigMap{ObjectMeta: meta, BinaryData: map[string][]byte{binaryValueKey: data}}
+	}
+	// the objects are written without being read, so that they are not cached by the controller
+	err := s.Create(ctx, obj)
+	if apierrors.IsAlreadyExists(err) {
+		err = s.Update(ctx, obj)
+	}
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to store the binary value of property", "DeviceName", d.GetName(), "propertyName", aps.Name)
+		return
+	}
+	bv.ValueFrom = &devicev1alpha1.BinaryValueSource{Kind: s.kind, Name: meta.Name, Key: binaryValueKey}
+}
+
+// deleteValues deletes the objects which store the binary values of the properties that go away,
+// i.e. the properties which no longer refer to the same object in the new actual states
+func (s *binaryValueStore) deleteValues(ctx context.Context, d *devicev1alpha1.Device, oldProperties, newProperties map[string]devicev1alpha1.ActualPropertyState) {
+	if s == nil {
+		return
+	}
+	for name, oldAps := range oldProperties {
+		if oldAps.BinaryValue == nil || oldAps.BinaryValue.ValueFrom == nil {
+			continue
+		}
+		oldSource := oldAps.BinaryValue.ValueFrom
+		if newAps, ok := newProperties[name]; ok && newAps.BinaryValue != nil && newAps.BinaryValue.ValueFrom != nil &&
+			*newAps.BinaryValue.ValueFrom == *oldSource {
+			continue
+		}
+		meta := metav1.ObjectMeta{Name: oldSource.Name, Namespace: d.GetNamespace()}
+		var obj client.Object
+		if oldSource.Kind == "Secret" {
+			obj = &corev1.Secret{ObjectMeta: meta}
+		} else {
+			obj = &corev1.ConfigMap{ObjectMeta: meta}
+		}
+		if err := s.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			klog.V(4).ErrorS(err, "fail to delete the stored binary value of property", "DeviceName", d.GetName(), "propertyName", name)
+		}
+	}
+}
+
+// binaryValueObjectName returns the name of the object which stores the binary value of the property,
+// the characters which are not allowed in the object names are replaced by "-", and a short hash of
+// the device and property names is appended so that the names which are mapped alike don't collide
+func binaryValueObjectName(deviceName, propertyName string) string {
+	sum := sha256.Sum256([]byte(deviceName + "/" + propertyName))
+	hash := hex.EncodeToString(sum[:4])
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(deviceName+"-"+propertyName))
+	// leave room for the hash and the "-" before it
+	if maxLen := 253 - len(hash) - 1; len(name) > maxLen {
+		name = name[:maxLen]
+	}
+	return strings.Trim(name, "-.") + "-" + hash
+}
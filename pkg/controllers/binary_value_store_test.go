@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBinaryValueObjectName(t *testing.T) {
+	tests := []struct {
+		deviceName, propertyName, want string
+	}{
+		{"hangzhou-camera-01", "Snapshot", "hangzhou-camera-01-snapshot-af313ad2"},
+		{"hangzhou-camera-01", "Front_Image", "hangzhou-camera-01-front-image-83442fac"},
+		{"hangzhou-camera-01", "Front-Image", "hangzhou-camera-01-front-image-b5007cd0"},
+		{"hangzhou-camera-01", "Image_", "hangzhou-camera-01-image-1217a47a"},
+	}
+	for _, tt := range tests {
+		if got := binaryValueObjectName(tt.deviceName, tt.propertyName); got != tt.want {
+			t.Errorf("binaryValueObjectName(%q, %q) = %q, want %q", tt.deviceName, tt.propertyName, got, tt.want)
+		}
+	}
+	// the long names are truncated before the hash is appended, so they are told apart by the hash
+	long := binaryValueObjectName(strings.Repeat("camera", 50), "Front")
+	if len(long) != 253 {
+		t.Errorf("expected the long name to be truncated to 253 characters, got %d", len(long))
+	}
+	if other := binaryValueObjectName(strings.Repeat("camera", 50), "Rear"); other == long {
+		t.Errorf("expected the long names of different properties to differ, both are %q", long)
+	}
+}
+
+func TestBinaryValueNotStored(t *testing.T) {
+	d := &devicev1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: "hangzhou-camera-01", Namespace: "default"}}
+	properties := map[string]devicev1alpha1.ActualPropertyState{
+		"Snapshot": {
+			Name:        "Snapshot",
+			BinaryValue: &devicev1alpha1.BinaryValue{SHA256: "abc", Size: 4, Data: []byte{1, 2, 3, 4}},
+		},
+	}
+	// the values are only described in the status if they are not stored, and the stores are never contacted
+	for _, s := range []*binaryValueStore{nil, {}, {kind: "ConfigMap", sizeLimit: 2}} {
+		s.storeValues(context.TODO(), d, properties)
+		bv := properties["Snapshot"].BinaryValue
+		if bv.Data != nil || bv.ValueFrom != nil || bv.SHA256 != "abc" || bv.Size != 4 {
+			t.Errorf("expected only the hash and size to be kept, got %+v", bv)
+		}
+		properties["Snapshot"].BinaryValue.Data = []byte{1, 2, 3, 4}
+	}
+}
+
+func TestDeleteBinaryValues(t *testing.T) {
+	d := &devicev1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: "hangzhou-camera-01", Namespace: "default"}}
+	stored := func(name string) devicev1alpha1.ActualPropertyState {
+		return devicev1alpha1.ActualPropertyState{Name: name, BinaryValue: &devicev1alpha1.BinaryValue{
+			SHA256:    "abc",
+			Size:      4,
+			ValueFrom: &devicev1alpha1.BinaryValueSource{Kind: "Secret", Name: binaryValueObjectName(d.Name, name), Key: binaryValueKey},
+		}}
+	}
+	var objs []client.Object
+	for _, name := range []string{"Snapshot", "Thumbnail", "Front"} {
+		objs = append(objs, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: binaryValueObjectName(d.Name, name), Namespace: d.Namespace}})
+	}
+	s := &binaryValueStore{Client: newFakeClient(t, objs...), kind: "Secret"}
+
+	oldProperties := map[string]devicev1alpha1.ActualPropertyState{
+		"Snapshot":  stored("Snapshot"),
+		"Thumbnail": stored("Thumbnail"),
+		"Front":     stored("Front"),
+	}
+	// Thumbnail goes away and the value of Front is no longer stored, e.g. it exceeds the size limit
+	newProperties := map[string]devicev1alpha1.ActualPropertyState{
+		"Snapshot": stored("Snapshot"),
+		"Front":    {Name: "Front", BinaryValue: &devicev1alpha1.BinaryValue{SHA256: "def", Size: 1 << 20}},
+	}
+	s.deleteValues(context.TODO(), d, oldProperties, newProperties)
+
+	for name, kept := range map[string]bool{"Snapshot": true, "Thumbnail": false, "Front": false} {
+		key := types.NamespacedName{Name: binaryValueObjectName(d.Name, name), Namespace: d.Namespace}
+		err := s.Get(context.TODO(), key, &corev1.Secret{})
+		if kept && err != nil {
+			t.Errorf("expected the value of %s to be kept, got %v", name, err)
+		}
+		if !kept && !apierrors.IsNotFound(err) {
+			t.Errorf("expected the value of %s to be deleted, got %v", name, err)
+		}
+	}
+}
+
+func TestStoreUnchangedBinaryValue(t *testing.T) {
+	d := &devicev1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: "hangzhou-camera-01", Namespace: "default"}}
+	source := &devicev1alpha1.BinaryValueSource{Kind: "Secret", Name: binaryValueObjectName(d.Name, "Snapshot"), Key: binaryValueKey}
+	d.Status.DeviceProperties = map[string]devicev1alpha1.ActualPropertyState{
+		"Snapshot": {Name: "Snapshot", BinaryValue: &devicev1alpha1.BinaryValue{SHA256: "abc", Size: 4, ValueFrom: source}},
+	}
+	s := &binaryValueStore{Client: newFakeClient(t), kind: "Secret", sizeLimit: 1024}
+
+	// the value with the same hash refers to the stored one without writing it again
+	aps := &devicev1alpha1.ActualPropertyState{Name: "Snapshot", BinaryValue: &devicev1alpha1.BinaryValue{SHA256: "abc", Size: 4, Data: []byte{1, 2, 3, 4}}}
+	s.storeValue(context.TODO(), d, aps)
+	if bv := aps.BinaryValue; bv.Data != nil || bv.ValueFrom == nil || *bv.ValueFrom != *source {
+		t.Errorf("expected the stored value to be referred to, got %+v", bv)
+	}
+	key := types.NamespacedName{Name: source.Name, Namespace: d.Namespace}
+	if err := s.Get(context.TODO(), key, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the unchanged value not to be written, got %v", err)
+	}
+
+	// the changed value is written
+	aps = &devicev1alpha1.ActualPropertyState{Name: "Snapshot", BinaryValue: &devicev1alpha1.BinaryValue{SHA256: "def", Size: 4, Data: []byte{5, 6, 7, 8}}}
+	s.storeValue(context.TODO(), d, aps)
+	if err := s.Get(context.TODO(), key, &corev1.Secret{}); err != nil {
+		t.Errorf("expected the changed value to be written, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultDeviceBatchWindow is how long a device creation waits for the others to join its batch
+	defaultDeviceBatchWindow = 100 * time.Millisecond
+	// defaultDeviceBatchSize is the maximum number of devices in a batch
+	defaultDeviceBatchSize = 100
+)
+
+// deviceCreateBatcher merges the device creations of concurrent reconciles into batch requests,
+// so that bringing up a large number of devices does not cost a round trip to the edge platform per device.
+// Each reconcile waits for its batch, so a batch holds at most as many devices as the reconciles running concurrently
+type deviceCreateBatcher struct {
+	deviceCli clients.DeviceInterface
+	window    time.Duration
+	maxSize   int
+
+	mu      sync.Mutex
+	pending []*deviceCreateRequest
+	timer   *time.Timer
+}
+
+type deviceCreateRequest struct {
+	// ctx is the context of the reconcile which waits for the creation
+	ctx    context.Context
+	device *devicev1alpha1.Device
+	done   chan clients.BatchResult
+}
+
+// newDeviceCreateBatcher returns the batcher fed by the number of concurrent reconciles, a batch is sent without
+// waiting for the window once all of them have joined it, since no other device can join it any more
+func newDeviceCreateBatcher(deviceCli clients.DeviceInterface, reconciles int) *deviceCreateBatcher {
+	maxSize := defaultDeviceBatchSize
+	if reconciles > 0 && reconciles < maxSize {
+		maxSize = reconciles
+	}
+	return &deviceCreateBatcher{
+		deviceCli: deviceCli,
+		window:    defaultDeviceBatchWindow,
+		maxSize:   maxSize,
+	}
+}
+
+// Create adds the device to the current batch and waits until the batch is sent to the edge platform,
+// the id of the created device on the edge platform is returned
+func (b *deviceCreateBatcher) Create(ctx context.Context, d *devicev1alpha1.Device) (string, error) {
+	req := &deviceCreateRequest{ctx: ctx, device: d, done: make(chan clients.BatchResult, 1)}
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	if len(b.pending) >= b.maxSize {
+		batch := b.takePending()
+		b.mu.Unlock()
+		go b.send(batch)
+	} else {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.window, b.flush)
+		}
+		b.mu.Unlock()
+	}
+
+	select {
+	case res := <-req.done:
+		return res.EdgeId, res.Err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// takePending must be called with the lock held
+func (b *deviceCreateBatcher) takePending() []*deviceCreateRequest {
+	batch := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
+
+func (b *deviceCreateBatcher) flush() {
+	b.mu.Lock()
+	batch := b.takePending()
+	b.mu.Unlock()
+	b.send(batch)
+}
+
+func (b *deviceCreateBatcher) send(batch []*deviceCreateRequest) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := batchContext(batch)
+	defer cancel()
+	devices := make([]*devicev1alpha1.Device, 0, len(batch))
+	for _, req := range batch {
+		devices = append(devices, req.device)
+	}
+	klog.V(4).Infof("Adding %d devices to the edge platform in a batch", len(devices))
+	results, err := b.deviceCli.CreateBatch(ctx, devices, clients.CreateOptions{})
+	if err != nil && len(batch) > 1 && clients.IsInvalidRequestErr(err) {
+		// the whole batch is rejected because of some invalid devices, so the devices are created one by one
+		// to keep the valid ones from failing together with them
+		klog.V(4).ErrorS(err, "the batch of devices is rejected by the edge platform, adding them one by one")
+		for _, req := range batch {
+			req.done <- b.createOne(ctx, req.device)
+		}
+		return
+	}
+	for i, req := range batch {
+		if err != nil {
+			req.done <- clients.BatchResult{Err: err}
+		} else {
+			req.done <- results[i]
+		}
+	}
+}
+
+func (b *deviceCreateBatcher) createOne(ctx context.Context, d *devicev1alpha1.Device) clients.BatchResult {
+	results, err := b.deviceCli.CreateBatch(ctx, []*devicev1alpha1.Device{d}, clients.CreateOptions{})
+	if err != nil {
+		return clients.BatchResult{Err: err}
+	}
+	return results[0]
+}
+
+// batchContext returns the context of the batch request, the batch is shared by several reconciles,
+// so the context is only done once the contexts of all of them are done, and its deadline is the latest one of them
+func batchContext(batch []*deviceCreateRequest) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var latest time.Time
+	for _, req := range batch {
+		deadline, ok := req.ctx.Deadline()
+		if !ok {
+			latest = time.Time{}
+			break
+		}
+		if deadline.After(latest) {
+			latest = deadline
+		}
+	}
+	if !latest.IsZero() {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, latest)
+		cancelAll := cancel
+		cancel = func() {
+			cancelDeadline()
+			cancelAll()
+		}
+	}
+	go func() {
+		for _, req := range batch {
+			select {
+			case <-req.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeDeviceClient is the device client of the tests, the methods which are not stubbed panic
+type fakeDeviceClient struct {
+	clients.DeviceInterface
+
+	mu          sync.Mutex
+	batches     [][]string
+	reads       int
+	updates     []string
+	pages       [][]devicev1alpha1.Device
+	createBatch func(devices []*devicev1alpha1.Device) ([]clients.BatchResult, error)
+	// getProperty returns the actual state of the property read from the device
+	getProperty func(name string) (*devicev1alpha1.ActualPropertyState, error)
+	// get returns the device on the edge platform
+	get func(name string) (*devicev1alpha1.Device, error)
+}
+
+func (f *fakeDeviceClient) Get(ctx context.Context, name string, options clients.GetOptions) (*devicev1alpha1.Device, error) {
+	return f.get(name)
+}
+
+func (f *fakeDeviceClient) GetPropertyState(ctx context.Context, propertyName string, device *devicev1alpha1.Device, options clients.GetOptions) (*devicev1alpha1.ActualPropertyState, error) {
+	// a read is a request sent to the edge platform, so it takes a token of the budget of the context
+	if limiter := clients.RequestLimiterFrom(ctx); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	f.mu.Lock()
+	f.reads++
+	f.mu.Unlock()
+	return f.getProperty(propertyName)
+}
+
+func (f *fakeDeviceClient) ListPropertiesState(ctx context.Context, device *devicev1alpha1.Device, options clients.ListOptions) (map[string]devicev1alpha1.DesiredPropertyState, map[string]devicev1alpha1.ActualPropertyState, error) {
+	actualProperties := map[string]devicev1alpha1.ActualPropertyState{}
+	for _, dp := range device.Spec.DeviceProperties {
+		aps, err := f.GetPropertyState(ctx, dp.Name, device, clients.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		actualProperties[dp.Name] = *aps
+	}
+	return nil, actualProperties, nil
+}
+
+func (f *fakeDeviceClient) UpdatePropertyState(ctx context.Context, propertyName string, device *devicev1alpha1.Device, options clients.UpdateOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, propertyName)
+	return nil
+}
+
+func (f *fakeDeviceClient) ListPages(ctx context.Context, options clients.ListOptions, fn func(devices []devicev1alpha1.Device) error) error {
+	for _, page := range f.pages {
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeDeviceClient) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+func (f *fakeDeviceClient) CreateBatch(ctx context.Context, devices []*devicev1alpha1.Device, options clients.CreateOptions) ([]clients.BatchResult, error) {
+	var names []string
+	for _, d := range devices {
+		names = append(names, d.Name)
+	}
+	f.mu.Lock()
+	f.batches = append(f.batches, names)
+	f.mu.Unlock()
+	return f.createBatch(devices)
+}
+
+func (f *fakeDeviceClient) sentBatches() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.batches...)
+}
+
+func newTestDevice(name string) *devicev1alpha1.Device {
+	return &devicev1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+// createAll creates the devices concurrently and returns the result of each of them in order
+func createAll(b *deviceCreateBatcher, names ...string) []clients.BatchResult {
+	results := make([]clients.BatchResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i].EdgeId, results[i].Err = b.Create(context.TODO(), newTestDevice(name))
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+func succeed(devices []*devicev1alpha1.Device) ([]clients.BatchResult, error) {
+	var results []clients.BatchResult
+	for _, d := range devices {
+		results = append(results, clients.BatchResult{Name: d.Name, EdgeId: "id-" + d.Name})
+	}
+	return results, nil
+}
+
+func TestDeviceCreateBatcherFlushBySize(t *testing.T) {
+	cli := &fakeDeviceClient{createBatch: succeed}
+	b := newDeviceCreateBatcher(cli, 5)
+	// the batch is never flushed by the window
+	b.window, b.maxSize = time.Hour, 2
+
+	results := createAll(b, "device-a", "device-b")
+	if batches := cli.sentBatches(); len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("expected a batch of 2 devices, got %v", batches)
+	}
+	for i, name := range []string{"device-a", "device-b"} {
+		if results[i].Err != nil || results[i].EdgeId != "id-"+name {
+			t.Errorf("expected %s to be created with id-%s, got %v", name, name, results[i])
+		}
+	}
+}
+
+func TestDeviceCreateBatcherFlushByTimeout(t *testing.T) {
+	cli := &fakeDeviceClient{createBatch: succeed}
+	b := newDeviceCreateBatcher(cli, 5)
+	b.window = 10 * time.Millisecond
+
+	results := createAll(b, "device-a")
+	if batches := cli.sentBatches(); len(batches) != 1 || len(batches[0]) != 1 {
+		t.Fatalf("expected a batch of 1 device once the window elapses, got %v", batches)
+	}
+	if results[0].Err != nil || results[0].EdgeId != "id-device-a" {
+		t.Errorf("expected device-a to be created, got %v", results[0])
+	}
+}
+
+func TestDeviceCreateBatcherSize(t *testing.T) {
+	// a batch holds at most as many devices as the reconciles which feed it
+	if b := newDeviceCreateBatcher(&fakeDeviceClient{}, 5); b.maxSize != 5 {
+		t.Errorf("expected batches of 5 devices with 5 reconciles, got %d", b.maxSize)
+	}
+	if b := newDeviceCreateBatcher(&fakeDeviceClient{}, 500); b.maxSize != defaultDeviceBatchSize {
+		t.Errorf("expected batches of %d devices with 500 reconciles, got %d", defaultDeviceBatchSize, b.maxSize)
+	}
+
+	// the batch is sent once all the reconciles have joined it, without waiting for the window
+	cli := &fakeDeviceClient{createBatch: succeed}
+	b := newDeviceCreateBatcher(cli, 3)
+	b.window = time.Hour
+	createAll(b, "device-a", "device-b", "device-c")
+	if batches := cli.sentBatches(); len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("expected a batch of 3 devices, got %v", batches)
+	}
+}
+
+func TestDeviceCreateBatcherItemResults(t *testing.T) {
+	exists := clients.NewStatusError(clients.StatusReasonAlreadyExists, http.StatusConflict, "device name device-b exists", nil)
+	cli := &fakeDeviceClient{createBatch: func(devices []*devicev1alpha1.Device) ([]clients.BatchResult, error) {
+		// the edge platform answers a multi-status response, each device gets its own result
+		var results []clients.BatchResult
+		for _, d := range devices {
+			if d.Name == "device-b" {
+				results = append(results, clients.BatchResult{Name: d.Name, Err: exists})
+			} else {
+				results = append(results, clients.BatchResult{Name: d.Name, EdgeId: "id-" + d.Name})
+			}
+		}
+		return results, nil
+	}}
+	b := newDeviceCreateBatcher(cli, 5)
+	b.window, b.maxSize = time.Hour, 3
+
+	results := createAll(b, "device-a", "device-b", "device-c")
+	if results[0].Err != nil || results[0].EdgeId != "id-device-a" {
+		t.Errorf("expected device-a to be created, got %v", results[0])
+	}
+	if !clients.IsAlreadyExistsErr(results[1].Err) {
+		t.Errorf("expected device-b to already exist, got %v", results[1])
+	}
+	if results[2].Err != nil || results[2].EdgeId != "id-device-c" {
+		t.Errorf("expected device-c to be created, got %v", results[2])
+	}
+}
+
+func TestDeviceCreateBatcherRequestFailure(t *testing.T) {
+	invalid := clients.NewStatusError(clients.StatusReasonInvalidRequest, http.StatusBadRequest, "invalid request", nil)
+	cli := &fakeDeviceClient{createBatch: func(devices []*devicev1alpha1.Device) ([]clients.BatchResult, error) {
+		for _, d := range devices {
+			if d.Name == "device-invalid" {
+				return nil, invalid
+			}
+		}
+		return succeed(devices)
+	}}
+	b := newDeviceCreateBatcher(cli, 5)
+	b.window, b.maxSize = time.Hour, 2
+
+	// the rejected batch is retried device by device, only the invalid device fails
+	results := createAll(b, "device-a", "device-invalid")
+	if batches := cli.sentBatches(); len(batches) != 3 {
+		t.Fatalf("expected the batch to be followed by a request per device, got %v", batches)
+	}
+	if results[0].Err != nil || results[0].EdgeId != "id-device-a" {
+		t.Errorf("expected device-a to be created, got %v", results[0])
+	}
+	if !clients.IsInvalidRequestErr(results[1].Err) {
+		t.Errorf("expected device-invalid to be rejected, got %v", results[1])
+	}
+
+	// the other failures of the whole request are returned to all the devices without retrying
+	unavailable := clients.NewUnavailableError("edgex is down", nil)
+	cli = &fakeDeviceClient{createBatch: func(devices []*devicev1alpha1.Device) ([]clients.BatchResult, error) {
+		return nil, unavailable
+	}}
+	b = newDeviceCreateBatcher(cli, 5)
+	b.window, b.maxSize = time.Hour, 2
+	results = createAll(b, "device-a", "device-b")
+	if batches := cli.sentBatches(); len(batches) != 1 {
+		t.Fatalf("expected a single batch, got %v", batches)
+	}
+	for _, res := range results {
+		if !clients.IsUnavailableErr(res.Err) {
+			t.Errorf("expected the unavailable error, got %v", res)
+		}
+	}
+}
+
+func TestBatchContext(t *testing.T) {
+	ctxA, cancelA := context.WithCancel(context.Background())
+	ctxB, cancelB := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelB()
+	ctx, cancel := batchContext([]*deviceCreateRequest{{ctx: ctxA}, {ctx: ctxB}})
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("expected no deadline while some reconcile has none")
+	}
+
+	cancelA()
+	select {
+	case <-ctx.Done():
+		t.Fatal("expected the batch to go on while some reconcile is still waiting")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancelB()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the batch to be canceled once all the reconciles are canceled")
+	}
+}
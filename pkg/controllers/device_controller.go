@@ -20,14 +20,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
+	"sort"
+	"strings"
 
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
 	"github.com/openyurtio/device-controller/pkg/clients"
-	edgexCli "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -36,15 +38,23 @@ import (
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 )
 
 // DeviceReconciler reconciles a Device object
 type DeviceReconciler struct {
 	client.Client
-	Scheme    *runtime.Scheme
-	deviceCli clients.DeviceInterface
+	Scheme *runtime.Scheme
+	// edge platform client, injected by the caller
+	DeviceCli clients.DeviceInterface
 	// which nodePool deviceController is deployed in
 	NodePool string
+	// merges the device creations of concurrent reconciles into batch requests
+	createBatcher *deviceCreateBatcher
+	// stores the binary values read from the devices
+	binaryValues *binaryValueStore
+	// the deadlines of the operations on edge platform
+	timeouts edgeTimeouts
 }
 
 //+kubebuilder:rbac:groups=device.openyurt.io,resources=devices,verbs=get;list;watch;create;update;patch;delete
@@ -63,14 +73,19 @@ func (r *DeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	klog.V(3).Infof("Reconciling the Device: %s", d.GetName())
+	oldStatus := d.Status.DeepCopy()
 	// Update the conditions for device
 	defer func() {
 		if !d.Spec.Managed {
 			conditions.MarkFalse(&d, devicev1alpha1.DeviceManagingCondition, "this device is not managed by openyurt", clusterv1.ConditionSeverityInfo, "")
 		}
 		conditions.SetSummary(&d,
-			conditions.WithConditions(devicev1alpha1.DeviceSyncedCondition, devicev1alpha1.DeviceManagingCondition),
+			conditions.WithConditions(devicev1alpha1.DeviceSyncedCondition, devicev1alpha1.DeviceManagingCondition, devicev1alpha1.DeviceAuthorizedCondition),
 		)
+		// the unchanged status is not written, since every write triggers another reconcile
+		if equality.Semantic.DeepEqual(oldStatus, &d.Status) {
+			return
+		}
 		err := r.Status().Update(ctx, &d)
 		if client.IgnoreNotFound(err) != nil {
 			if !apierrors.IsConflict(err) {
@@ -81,7 +96,7 @@ func (r *DeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	// 1. Handle the device deletion event
 	if err := r.reconcileDeleteDevice(ctx, &d); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return resultForObjectEdgeError(&d, devicev1alpha1.DeviceAuthorizedCondition, client.IgnoreNotFound(err))
 	} else if !d.ObjectMeta.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
@@ -89,32 +104,31 @@ func (r *DeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	if !d.Status.Synced {
 		// 2. Synchronize OpenYurt device objects to edge platform
 		if err := r.reconcileCreateDevice(ctx, &d); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{Requeue: true}, nil
-			} else {
-				return ctrl.Result{}, err
-			}
+			return resultForObjectEdgeError(&d, devicev1alpha1.DeviceAuthorizedCondition, err)
 		}
-		return ctrl.Result{}, nil
 	} else if d.Spec.Managed {
 		// 3. If the device has been synchronized and is managed by the cloud, reconcile the device properties
 		if err := r.reconcileUpdateDevice(ctx, &d); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{RequeueAfter: time.Second * 2}, nil
-			}
-			return ctrl.Result{}, err
+			return resultForObjectEdgeError(&d, devicev1alpha1.DeviceAuthorizedCondition, err)
 		}
 	}
+	markEdgeAuthorized(&d, devicev1alpha1.DeviceAuthorizedCondition, nil)
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DeviceReconciler) SetupWithManager(mgr ctrl.Manager, opts *options.YurtDeviceControllerOptions) error {
-	r.deviceCli = edgexCli.NewEdgexDeviceClient(opts.CoreMetadataAddr, opts.CoreCommandAddr)
+	if r.DeviceCli == nil {
+		return fmt.Errorf("the edge platform client of device reconciler is not set")
+	}
 	r.NodePool = opts.Nodepool
+	r.createBatcher = newDeviceCreateBatcher(r.DeviceCli, int(opts.ConcurrentDeviceReconciles))
+	r.binaryValues = newBinaryValueStore(r.Client, opts)
+	r.timeouts = newEdgeTimeouts(opts)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&devicev1alpha1.Device{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: int(opts.ConcurrentDeviceReconciles)}).
 		WithEventFilter(genFirstUpdateFilter("device")).
 		Complete(r)
 }
@@ -135,7 +149,9 @@ func (r *DeviceReconciler) reconcileDeleteDevice(ctx context.Context, d *devicev
 		}
 	} else {
 		// delete the device object on the edge platform
-		err := r.deviceCli.Delete(context.TODO(), edgeDeviceName, clients.DeleteOptions{})
+		deleteCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		err := r.DeviceCli.Delete(deleteCtx, edgeDeviceName, clients.DeleteOptions{})
+		cancel()
 		if err != nil && !clients.IsNotFoundErr(err) {
 			return err
 		}
@@ -159,7 +175,9 @@ func (r *DeviceReconciler) reconcileCreateDevice(ctx context.Context, d *devicev
 	newDeviceStatus := d.Status.DeepCopy()
 	klog.V(4).Infof("Checking if device already exist on the edge platform: %s", d.GetName())
 	// Checking if device already exist on the edge platform
-	edgeDevice, err := r.deviceCli.Get(context.TODO(), edgeDeviceName, clients.GetOptions{})
+	getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	edgeDevice, err := r.DeviceCli.Get(getCtx, edgeDeviceName, clients.GetOptions{})
+	cancel()
 	if err == nil {
 		// a. If object exists, the status of the device on OpenYurt is updated
 		klog.V(4).Infof("Device already exists on edge platform: %s", d.GetName())
@@ -168,19 +186,31 @@ func (r *DeviceReconciler) reconcileCreateDevice(ctx context.Context, d *devicev
 	} else if clients.IsNotFoundErr(err) {
 		// b. If the object does not exist, a request is sent to the edge platform to create a new device
 		klog.V(4).Infof("Adding device to the edge platform: %s", d.GetName())
-		createdEdgeObj, err := r.deviceCli.Create(context.TODO(), d, clients.CreateOptions{})
+		createCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		edgeId, err := r.createBatcher.Create(createCtx, d)
+		cancel()
+		if clients.IsAlreadyExistsErr(err) {
+			// the device has been added to the edge platform in the meantime, e.g. by the edge platform itself
+			klog.V(4).Infof("Device already exists on edge platform: %s", d.GetName())
+			var existingEdgeObj *devicev1alpha1.Device
+			getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+			if existingEdgeObj, err = r.DeviceCli.Get(getCtx, edgeDeviceName, clients.GetOptions{}); err == nil {
+				edgeId = existingEdgeObj.Status.EdgeId
+			}
+			cancel()
+		}
 		if err != nil {
 			conditions.MarkFalse(d, devicev1alpha1.DeviceSyncedCondition, "failed to create device on edge platform", clusterv1.ConditionSeverityWarning, err.Error())
-			return fmt.Errorf("fail to add Device to edge platform: %v", err)
+			return fmt.Errorf("fail to add Device to edge platform: %w", err)
 		} else {
-			klog.V(4).Infof("Successfully add Device to edge platform, Name: %s, EdgeId: %s", edgeDeviceName, createdEdgeObj.Status.EdgeId)
-			newDeviceStatus.EdgeId = createdEdgeObj.Status.EdgeId
+			klog.V(4).Infof("Successfully add Device to edge platform, Name: %s, EdgeId: %s", edgeDeviceName, edgeId)
+			newDeviceStatus.EdgeId = edgeId
 			newDeviceStatus.Synced = true
 		}
 	} else {
 		klog.V(4).ErrorS(err, "failed to visit the edge platform")
-		conditions.MarkFalse(d, devicev1alpha1.DeviceSyncedCondition, "failed to visit the EdgeX core-metadata-service", clusterv1.ConditionSeverityWarning, "")
-		return nil
+		conditions.MarkFalse(d, devicev1alpha1.DeviceSyncedCondition, "failed to visit the EdgeX core-metadata-service", clusterv1.ConditionSeverityWarning, err.Error())
+		return err
 	}
 	d.Status = *newDeviceStatus
 	conditions.MarkTrue(d, devicev1alpha1.DeviceSyncedCondition)
@@ -193,38 +223,53 @@ func (r *DeviceReconciler) reconcileUpdateDevice(ctx context.Context, d *devicev
 	// This list is used to hold the names of properties that failed to reconcile
 	var failedPropertyNames []string
 
-	// 1. reconciling the AdminState and OperatingState field of device
-	klog.V(3).Infof("DeviceName: %s, reconciling the AdminState and OperatingState field of device", d.GetName())
-	updateDevice := d.DeepCopy()
-	if d.Spec.AdminState != "" && d.Spec.AdminState != d.Status.AdminState {
-		newDeviceStatus.AdminState = d.Spec.AdminState
-	} else {
-		updateDevice.Spec.AdminState = ""
-	}
-
-	if d.Spec.OperatingState != "" && d.Spec.OperatingState != d.Status.OperatingState {
-		newDeviceStatus.OperatingState = d.Spec.OperatingState
-	} else {
-		updateDevice.Spec.OperatingState = ""
-	}
-	_, err := r.deviceCli.Update(context.TODO(), updateDevice, clients.UpdateOptions{})
+	// 1. find the fields of device which are different between OpenYurt and edge platform
+	klog.V(3).Infof("DeviceName: %s, checking the fields of device on edge platform", d.GetName())
+	getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	edgeDevice, err := r.DeviceCli.Get(getCtx, util.GetEdgeDeviceName(d, EdgeXObjectName), clients.GetOptions{})
+	cancel()
 	if err != nil {
-		conditions.MarkFalse(d, devicev1alpha1.DeviceManagingCondition, "failed to update AdminState or OperatingState of device on edge platform", clusterv1.ConditionSeverityWarning, err.Error())
+		conditions.MarkFalse(d, devicev1alpha1.DeviceManagingCondition, "failed to get device from edge platform", clusterv1.ConditionSeverityWarning, err.Error())
 		return err
 	}
+	newDeviceStatus.AdminState = edgeDevice.Status.AdminState
+	newDeviceStatus.OperatingState = edgeDevice.Status.OperatingState
+	driftFields := findDeviceDrift(d, edgeDevice)
 
-	// 2. reconciling the device properties' value
+	// 2. correct the drifted fields of device on edge platform, only the drifted fields are sent
+	if len(driftFields) != 0 {
+		klog.V(3).Infof("DeviceName: %s, correcting the fields %v of device on edge platform", d.GetName(), driftFields)
+		updateCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		_, err := r.DeviceCli.Update(updateCtx, d, clients.UpdateOptions{UpdateFields: driftFields})
+		cancel()
+		if err != nil {
+			conditions.MarkFalse(d, devicev1alpha1.DeviceManagingCondition, fmt.Sprintf("failed to update the fields %v of device on edge platform", driftFields), clusterv1.ConditionSeverityWarning, err.Error())
+			return err
+		}
+		if util.IsInStringLst(driftFields, "adminState") {
+			newDeviceStatus.AdminState = d.Spec.AdminState
+		}
+		if util.IsInStringLst(driftFields, "operatingState") {
+			newDeviceStatus.OperatingState = d.Spec.OperatingState
+		}
+	}
+	markDeviceDriftCorrected(d, driftFields)
+
+	// 3. reconciling the device properties' value
 	klog.V(3).Infof("DeviceName: %s, reconciling the device properties", d.GetName())
 	// property updates are made only when the device is up and unlocked
 	if newDeviceStatus.OperatingState == devicev1alpha1.Up && newDeviceStatus.AdminState == devicev1alpha1.UnLocked {
-		newDeviceStatus, failedPropertyNames = r.reconcileDeviceProperties(d, newDeviceStatus)
+		newDeviceStatus, failedPropertyNames = r.reconcileDeviceProperties(ctx, d, newDeviceStatus)
 	}
 
+	statusChanged := !equality.Semantic.DeepEqual(&d.Status, newDeviceStatus)
 	d.Status = *newDeviceStatus
 
-	// 3. update the device status on OpenYurt
+	// 4. update the device status on OpenYurt if it is changed
 	klog.V(3).Infof("DeviceName: %s, update the device status", d.GetName())
-	if err := r.Status().Update(ctx, d); err != nil {
+	if !statusChanged {
+		klog.V(4).Infof("DeviceName: %s, the device status is unchanged", d.GetName())
+	} else if err := r.Status().Update(ctx, d); err != nil {
 		conditions.MarkFalse(d, devicev1alpha1.DeviceManagingCondition, "failed to update status of device on openyurt", clusterv1.ConditionSeverityWarning, err.Error())
 		return err
 	} else if len(failedPropertyNames) != 0 {
@@ -238,43 +283,69 @@ func (r *DeviceReconciler) reconcileUpdateDevice(ctx context.Context, d *devicev
 
 // Update the actual property value of the device on edge platform,
 // return the latest status and the names of the property that failed to update
-func (r *DeviceReconciler) reconcileDeviceProperties(d *devicev1alpha1.Device, deviceStatus *devicev1alpha1.DeviceStatus) (*devicev1alpha1.DeviceStatus, []string) {
+func (r *DeviceReconciler) reconcileDeviceProperties(ctx context.Context, d *devicev1alpha1.Device, deviceStatus *devicev1alpha1.DeviceStatus) (*devicev1alpha1.DeviceStatus, []string) {
 	newDeviceStatus := deviceStatus.DeepCopy()
 	// This list is used to hold the names of properties that failed to reconcile
 	var failedPropertyNames []string
 	// 2. reconciling the device properties' value
 	klog.V(3).Infof("DeviceName: %s, reconciling the value of device properties", d.GetName())
 	for _, desiredProperty := range d.Spec.DeviceProperties {
-		if desiredProperty.DesiredValue == "" {
+		if desiredProperty.DesiredValue == "" && len(desiredProperty.DesiredValues) == 0 {
 			continue
 		}
 		propertyName := desiredProperty.Name
+		// the parameters of a command which sets several resources must match the resources operated by the command
+		if len(desiredProperty.DesiredValues) != 0 {
+			if err := r.validateDesiredValues(ctx, d, &desiredProperty); err != nil {
+				klog.ErrorS(err, "invalid desired values of property", "DeviceName", d.GetName(), "propertyName", propertyName)
+				failedPropertyNames = append(failedPropertyNames, propertyName)
+				continue
+			}
+		}
 		// 1.1. gets the actual property value of the current device from edge platform
 		klog.V(4).Infof("DeviceName: %s, getting the actual value of property: %s", d.GetName(), propertyName)
-		actualProperty, err := r.deviceCli.GetPropertyState(context.TODO(), propertyName, d, clients.GetOptions{})
+		readCtx, cancel := withTimeout(ctx, r.timeouts.command)
+		actualProperty, err := r.DeviceCli.GetPropertyState(readCtx, propertyName, d, clients.GetOptions{ReadOptions: propertyReadOptions(d, propertyName)})
+		cancel()
 		if err != nil {
 			if !clients.IsNotFoundErr(err) {
-				klog.Errorf("DeviceName: %s, failed to get actual property value of %s, err:%v", d.GetName(), propertyName, err)
+				if clients.IsLockedErr(err) {
+					klog.Errorf("DeviceName: %s, the device is locked or down, failed to get actual property value of %s", d.GetName(), propertyName)
+				} else {
+					klog.Errorf("DeviceName: %s, failed to get actual property value of %s, err:%v", d.GetName(), propertyName, err)
+				}
 				failedPropertyNames = append(failedPropertyNames, propertyName)
 				continue
 			}
+			// the property can not be read, but it may still be set
 			klog.Errorf("DeviceName: %s, property read command not found", d.GetName())
+			actualProperty = &devicev1alpha1.ActualPropertyState{Name: propertyName}
 		} else {
 			klog.V(4).Infof("DeviceName: %s, got the actual property state, {Name: %s, GetURL: %s, ActualValue: %s}",
 				d.GetName(), propertyName, actualProperty.GetURL, actualProperty.ActualValue)
+			r.binaryValues.storeValue(ctx, d, actualProperty)
+			keepReadingTimestamp(newDeviceStatus.DeviceProperties, actualProperty)
 		}
 
 		if newDeviceStatus.DeviceProperties == nil {
 			newDeviceStatus.DeviceProperties = map[string]devicev1alpha1.ActualPropertyState{}
-		} else {
-			newDeviceStatus.DeviceProperties[propertyName] = *actualProperty
+		}
+		newDeviceStatus.DeviceProperties[propertyName] = *actualProperty
+
+		// the reading is not returned by the device, so the desired value can't be compared with the actual one
+		if actualProperty.Unknown {
+			klog.V(4).Infof("DeviceName: %s, the actual value of property %s is unknown, skip comparing it with the desired value", d.GetName(), propertyName)
+			continue
 		}
 
 		// 1.2. set the device attribute in the edge platform to the expected value
-		if actualProperty == nil || desiredProperty.DesiredValue != actualProperty.ActualValue {
+		if actualProperty == nil || !isDesiredPropertyState(&desiredProperty, actualProperty) {
 			klog.V(4).Infof("DeviceName: %s, the desired value and the actual value are different, desired: %s, actual: %s",
-				d.GetName(), desiredProperty.DesiredValue, actualProperty.ActualValue)
-			if err := r.deviceCli.UpdatePropertyState(context.TODO(), propertyName, d, clients.UpdateOptions{}); err != nil {
+				d.GetName(), desiredPropertyValue(&desiredProperty), actualProperty.ActualValue)
+			setCtx, cancel := withTimeout(ctx, r.timeouts.command)
+			err := r.DeviceCli.UpdatePropertyState(setCtx, propertyName, d, clients.UpdateOptions{})
+			cancel()
+			if err != nil {
 				klog.ErrorS(err, "failed to update property", "DeviceName", d.GetName(), "propertyName", propertyName)
 				failedPropertyNames = append(failedPropertyNames, propertyName)
 				continue
@@ -284,10 +355,170 @@ func (r *DeviceReconciler) reconcileDeviceProperties(d *devicev1alpha1.Device, d
 			newActualProperty := devicev1alpha1.ActualPropertyState{
 				Name:        propertyName,
 				GetURL:      actualProperty.GetURL,
-				ActualValue: desiredProperty.DesiredValue,
+				ActualValue: desiredPropertyValue(&desiredProperty),
 			}
 			newDeviceStatus.DeviceProperties[propertyName] = newActualProperty
 		}
 	}
 	return newDeviceStatus, failedPropertyNames
 }
+
+// validateDesiredValues checks that the command of the property is writable in the deviceProfile of the device,
+// every desired value is for a resource operated by the command, and the resources without a default value are given
+func (r *DeviceReconciler) validateDesiredValues(ctx context.Context, d *devicev1alpha1.Device, dps *devicev1alpha1.DesiredPropertyState) error {
+	var profiles devicev1alpha1.DeviceProfileList
+	if err := r.List(ctx, &profiles, client.InNamespace(d.Namespace), client.MatchingFields{util.IndexerPathForNodepool: d.Spec.NodePool}); err != nil {
+		return err
+	}
+	for i := range profiles.Items {
+		if util.GetEdgeDeviceProfileName(&profiles.Items[i], EdgeXObjectName) != d.Spec.Profile {
+			continue
+		}
+		for _, cmd := range profiles.Items[i].Spec.DeviceCommands {
+			if cmd.Name != dps.Name {
+				continue
+			}
+			if !strings.Contains(cmd.ReadWrite, "W") {
+				return fmt.Errorf("the command %s of deviceProfile %s is not writable", cmd.Name, d.Spec.Profile)
+			}
+			return validateResourceOperations(dps.DesiredValues, cmd.ResourceOperations)
+		}
+		return fmt.Errorf("the command %s is not found in deviceProfile %s", dps.Name, d.Spec.Profile)
+	}
+	return fmt.Errorf("the deviceProfile %s of device is not found", d.Spec.Profile)
+}
+
+// validateResourceOperations checks the desired values against the resources operated by a command
+func validateResourceOperations(desiredValues map[string]string, operations []devicev1alpha1.ResourceOperation) error {
+	operated := map[string]struct{}{}
+	var missing []string
+	for _, ro := range operations {
+		operated[ro.DeviceResource] = struct{}{}
+		if _, exists := desiredValues[ro.DeviceResource]; !exists && ro.DefaultValue == "" {
+			missing = append(missing, ro.DeviceResource)
+		}
+	}
+	var unknown []string
+	for name := range desiredValues {
+		if _, exists := operated[name]; !exists {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	if len(unknown) != 0 {
+		return fmt.Errorf("the resources %v are not operated by the command", unknown)
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("the resources %v have no default value and must be given", missing)
+	}
+	return nil
+}
+
+// keepReadingTimestamp keeps the timestamp of the actual state recorded before if the reading is otherwise unchanged,
+// so that reading the same value again doesn't change the device status and trigger another reconcile
+func keepReadingTimestamp(oldProperties map[string]devicev1alpha1.ActualPropertyState, aps *devicev1alpha1.ActualPropertyState) {
+	oldAps, exists := oldProperties[aps.Name]
+	if !exists || oldAps.Timestamp == aps.Timestamp {
+		return
+	}
+	newAps := *aps
+	newAps.Timestamp = oldAps.Timestamp
+	if equality.Semantic.DeepEqual(oldAps, newAps) {
+		aps.Timestamp = oldAps.Timestamp
+	}
+}
+
+// isDesiredPropertyState returns whether the actual state of the property is the desired one, the actual value
+// of a command which operates several resources is the JSON object of the resource values
+func isDesiredPropertyState(desired *devicev1alpha1.DesiredPropertyState, actual *devicev1alpha1.ActualPropertyState) bool {
+	if len(desired.DesiredValues) == 0 {
+		return desired.DesiredValue == actual.ActualValue
+	}
+	actualValues := map[string]string{}
+	if err := json.Unmarshal([]byte(actual.ActualValue), &actualValues); err != nil {
+		return false
+	}
+	for name, value := range desired.DesiredValues {
+		if actualValues[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// desiredPropertyValue returns the desired value of the property in the form of its actual value
+func desiredPropertyValue(desired *devicev1alpha1.DesiredPropertyState) string {
+	if len(desired.DesiredValues) == 0 {
+		return desired.DesiredValue
+	}
+	serializedBytes, _ := json.Marshal(desired.DesiredValues)
+	return string(serializedBytes)
+}
+
+// markDeviceDriftCorrected records the corrected fields in the DeviceDriftCorrected condition,
+// the condition is removed once the device on edge platform matches OpenYurt again
+func markDeviceDriftCorrected(d *devicev1alpha1.Device, driftFields []string) {
+	if len(driftFields) == 0 {
+		conditions.Delete(d, devicev1alpha1.DeviceDriftCorrectedCondition)
+		return
+	}
+	conditions.Set(d, &clusterv1.Condition{
+		Type:    devicev1alpha1.DeviceDriftCorrectedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "the fields of device on edge platform were different from OpenYurt",
+		Message: fmt.Sprintf("corrected fields: %v", driftFields),
+	})
+}
+
+// findDeviceDrift returns the json names of the spec fields whose values are different between OpenYurt and edge platform,
+// the fields that only exist in OpenYurt or can not be emptied on edge platform are skipped when they are empty
+func findDeviceDrift(kubeDevice, edgeDevice *devicev1alpha1.Device) []string {
+	kubeSpec, edgeSpec := &kubeDevice.Spec, &edgeDevice.Spec
+	var driftFields []string
+	if kubeSpec.Description != edgeSpec.Description {
+		driftFields = append(driftFields, "description")
+	}
+	if kubeSpec.AdminState != "" && kubeSpec.AdminState != edgeSpec.AdminState {
+		driftFields = append(driftFields, "adminState")
+	}
+	if kubeSpec.OperatingState != "" && kubeSpec.OperatingState != edgeSpec.OperatingState {
+		driftFields = append(driftFields, "operatingState")
+	}
+	if len(kubeSpec.Protocols) != 0 && !equality.Semantic.DeepEqual(kubeSpec.Protocols, edgeSpec.Protocols) {
+		driftFields = append(driftFields, "protocols")
+	}
+	if !equality.Semantic.DeepEqual(kubeSpec.Labels, edgeSpec.Labels) {
+		driftFields = append(driftFields, "labels")
+	}
+	if !isSameLocation(kubeDevice, edgeDevice) {
+		driftFields = append(driftFields, "location")
+	}
+	if kubeSpec.Service != "" && kubeSpec.Service != edgeSpec.Service {
+		driftFields = append(driftFields, "serviceName")
+	}
+	if kubeSpec.Profile != "" && kubeSpec.Profile != edgeSpec.Profile {
+		driftFields = append(driftFields, "profileName")
+	}
+	if !equality.Semantic.DeepEqual(kubeSpec.AutoEvents, edgeSpec.AutoEvents) {
+		driftFields = append(driftFields, "autoEvents")
+	}
+	return driftFields
+}
+
+// isSameLocation returns whether the device has the same location on OpenYurt and edge platform,
+// the JSON locations are compared by their values since edge platform returns them in compact form
+func isSameLocation(kubeDevice, edgeDevice *devicev1alpha1.Device) bool {
+	kubeLoc, edgeLoc := kubeDevice.Spec.Location, edgeDevice.Spec.Location
+	if kubeLoc == edgeLoc {
+		return true
+	}
+	if kubeDevice.Annotations[devicev1alpha1.EdgeXLocationFormat] != devicev1alpha1.EdgeXLocationFormatJSON &&
+		edgeDevice.Annotations[devicev1alpha1.EdgeXLocationFormat] != devicev1alpha1.EdgeXLocationFormatJSON {
+		return false
+	}
+	var kubeValue, edgeValue interface{}
+	if json.Unmarshal([]byte(kubeLoc), &kubeValue) != nil || json.Unmarshal([]byte(edgeLoc), &edgeValue) != nil {
+		return false
+	}
+	return equality.Semantic.DeepEqual(kubeValue, edgeValue)
+}
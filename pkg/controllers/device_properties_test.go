@@ -0,0 +1,205 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestValidateResourceOperations(t *testing.T) {
+	operations := []devicev1alpha1.ResourceOperation{
+		{DeviceResource: "Setpoint"},
+		{DeviceResource: "Mode", DefaultValue: "auto"},
+	}
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr bool
+	}{
+		{"all resources", map[string]string{"Setpoint": "21.5", "Mode": "heat"}, false},
+		{"default value used", map[string]string{"Setpoint": "21.5"}, false},
+		{"missing resource", map[string]string{"Mode": "heat"}, true},
+		{"unknown resource", map[string]string{"Setpoint": "21.5", "Fan": "on"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateResourceOperations(tt.values, operations); (err != nil) != tt.wantErr {
+				t.Errorf("validateResourceOperations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsDesiredPropertyState(t *testing.T) {
+	desired := &devicev1alpha1.DesiredPropertyState{
+		Name:          "Thermostat",
+		DesiredValues: map[string]string{"Setpoint": "21.5", "Mode": "heat"},
+	}
+	actual := &devicev1alpha1.ActualPropertyState{Name: "Thermostat", ActualValue: desiredPropertyValue(desired)}
+	if !isDesiredPropertyState(desired, actual) {
+		t.Errorf("expected %s to be the desired state", actual.ActualValue)
+	}
+	// the resources that are not desired are ignored
+	actual.ActualValue = `{"Mode":"heat","Setpoint":"21.5","Fan":"on"}`
+	if !isDesiredPropertyState(desired, actual) {
+		t.Errorf("expected %s to be the desired state", actual.ActualValue)
+	}
+	actual.ActualValue = `{"Mode":"cool","Setpoint":"21.5"}`
+	if isDesiredPropertyState(desired, actual) {
+		t.Errorf("expected %s not to be the desired state", actual.ActualValue)
+	}
+
+	single := &devicev1alpha1.DesiredPropertyState{Name: "Float32", DesiredValue: "66.66"}
+	if !isDesiredPropertyState(single, &devicev1alpha1.ActualPropertyState{ActualValue: "66.66"}) {
+		t.Error("expected the single value to be the desired state")
+	}
+}
+
+func TestMarkDeviceDriftCorrected(t *testing.T) {
+	d := &devicev1alpha1.Device{}
+	markDeviceDriftCorrected(d, []string{"description", "labels"})
+	if !conditions.IsTrue(d, devicev1alpha1.DeviceDriftCorrectedCondition) {
+		t.Fatalf("expected the drift corrected condition to be true, got %v", conditions.Get(d, devicev1alpha1.DeviceDriftCorrectedCondition))
+	}
+
+	markDeviceDriftCorrected(d, nil)
+	if conditions.Has(d, devicev1alpha1.DeviceDriftCorrectedCondition) {
+		t.Errorf("expected the drift corrected condition to be removed once the device matches again")
+	}
+}
+
+func TestFindDeviceDriftJSONLocation(t *testing.T) {
+	jsonLocation := map[string]string{devicev1alpha1.EdgeXLocationFormat: devicev1alpha1.EdgeXLocationFormatJSON}
+	kubeDevice := &devicev1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Annotations: jsonLocation},
+		Spec:       devicev1alpha1.DeviceSpec{Location: `{"x": 1, "y": [2, 3]}`},
+	}
+	// edge platform returns the location in compact form
+	edgeDevice := &devicev1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Annotations: jsonLocation},
+		Spec:       devicev1alpha1.DeviceSpec{Location: `{"x":1,"y":[2,3]}`},
+	}
+	if drift := findDeviceDrift(kubeDevice, edgeDevice); len(drift) != 0 {
+		t.Errorf("expected the same JSON location not to drift, got %v", drift)
+	}
+
+	edgeDevice.Spec.Location = `{"x":2,"y":[2,3]}`
+	if drift := findDeviceDrift(kubeDevice, edgeDevice); len(drift) != 1 || drift[0] != "location" {
+		t.Errorf("expected the location to drift, got %v", drift)
+	}
+
+	// the locations which are not JSON are compared as strings
+	kubeDevice = &devicev1alpha1.Device{Spec: devicev1alpha1.DeviceSpec{Location: `{"x": 1}`}}
+	edgeDevice = &devicev1alpha1.Device{Spec: devicev1alpha1.DeviceSpec{Location: `{"x":1}`}}
+	if drift := findDeviceDrift(kubeDevice, edgeDevice); len(drift) != 1 || drift[0] != "location" {
+		t.Errorf("expected the string location to drift, got %v", drift)
+	}
+}
+
+func TestReconcileDevicePropertiesUnknownValue(t *testing.T) {
+	cli := &fakeDeviceClient{getProperty: func(name string) (*devicev1alpha1.ActualPropertyState, error) {
+		// the reading is not returned with ds-returnevent=false
+		return &devicev1alpha1.ActualPropertyState{Name: name, Unknown: name == "Trigger"}, nil
+	}}
+	r := &DeviceReconciler{DeviceCli: cli}
+	d := &devicev1alpha1.Device{
+		Spec: devicev1alpha1.DeviceSpec{DeviceProperties: map[string]devicev1alpha1.DesiredPropertyState{
+			"Trigger": {Name: "Trigger", DesiredValue: "on"},
+			"Float32": {Name: "Float32", DesiredValue: "66.66"},
+		}},
+		Status: devicev1alpha1.DeviceStatus{DeviceProperties: map[string]devicev1alpha1.ActualPropertyState{
+			"Trigger": {Name: "Trigger", ActualValue: "on"},
+		}},
+	}
+
+	status, failed := r.reconcileDeviceProperties(context.TODO(), d, &d.Status)
+	if len(failed) != 0 {
+		t.Fatalf("expected all the properties to be reconciled, got failures %v", failed)
+	}
+	if len(cli.updates) != 1 || cli.updates[0] != "Float32" {
+		t.Errorf("expected only the property whose actual value is known to be set, got %v", cli.updates)
+	}
+	if aps := status.DeviceProperties["Trigger"]; !aps.Unknown || aps.ActualValue != "" {
+		t.Errorf("expected the stale actual value to be replaced by an unknown one, got %+v", aps)
+	}
+}
+
+func TestReconcileDeviceSameReading(t *testing.T) {
+	d := &devicev1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "modbus-01", Namespace: "default", Finalizers: []string{devicev1alpha1.DeviceFinalizer}},
+		Spec: devicev1alpha1.DeviceSpec{
+			NodePool:         "hangzhou",
+			Managed:          true,
+			AdminState:       devicev1alpha1.UnLocked,
+			OperatingState:   devicev1alpha1.Up,
+			DeviceProperties: map[string]devicev1alpha1.DesiredPropertyState{"Temp": {Name: "Temp", DesiredValue: "21.5"}},
+		},
+		Status: devicev1alpha1.DeviceStatus{Synced: true, AdminState: devicev1alpha1.UnLocked, OperatingState: devicev1alpha1.Up},
+	}
+	edgeDevice := d.DeepCopy()
+	var timestamp int64
+	cli := &fakeDeviceClient{
+		getProperty: func(name string) (*devicev1alpha1.ActualPropertyState, error) {
+			// every read takes the same value at a later time
+			timestamp++
+			return &devicev1alpha1.ActualPropertyState{Name: name, ActualValue: "21.5", Timestamp: timestamp}, nil
+		},
+		get: func(name string) (*devicev1alpha1.Device, error) {
+			return edgeDevice.DeepCopy(), nil
+		},
+	}
+	r := &DeviceReconciler{Client: newFakeClient(t, d), DeviceCli: cli, NodePool: "hangzhou"}
+	key := types.NamespacedName{Namespace: "default", Name: "modbus-01"}
+
+	var reconciled devicev1alpha1.Device
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(context.TODO(), key, &reconciled); err != nil {
+		t.Fatal(err)
+	}
+	if aps := reconciled.Status.DeviceProperties["Temp"]; aps.ActualValue != "21.5" || aps.Timestamp != 1 {
+		t.Fatalf("expected the reading to be recorded, got %+v", reconciled.Status.DeviceProperties)
+	}
+
+	// the second reconcile reads the same value, only its timestamp moves, so the status is not written
+	resourceVersion := reconciled.ResourceVersion
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(context.TODO(), key, &reconciled); err != nil {
+		t.Fatal(err)
+	}
+	if cli.readCount() != 2 {
+		t.Fatalf("expected the property to be read by each reconcile, got %d reads", cli.readCount())
+	}
+	if reconciled.ResourceVersion != resourceVersion {
+		t.Errorf("expected no status write for the same reading, the resource version changed from %s to %s",
+			resourceVersion, reconciled.ResourceVersion)
+	}
+	if aps := reconciled.Status.DeviceProperties["Temp"]; aps.Timestamp != 1 {
+		t.Errorf("expected the timestamp of the first reading to be kept, got %+v", aps)
+	}
+}
@@ -24,7 +24,6 @@ import (
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
 	edgeCli "github.com/openyurtio/device-controller/pkg/clients"
-	efCli "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -42,108 +41,170 @@ type DeviceSyncer struct {
 	deviceCli edgeCli.DeviceInterface
 	// syncing period in seconds
 	syncPeriod time.Duration
-	Namespace  string
+	// the deadline of listing the devices on edge platform in a round of synchronization
+	syncTimeout time.Duration
+	Namespace   string
+	// only the objects with these labels are synchronized
+	labelSelector map[string]string
 }
 
 // NewDeviceSyncer initialize a New DeviceSyncer
-func NewDeviceSyncer(client client.Client, opts *options.YurtDeviceControllerOptions) (DeviceSyncer, error) {
+func NewDeviceSyncer(client client.Client, deviceCli edgeCli.DeviceInterface, opts *options.YurtDeviceControllerOptions) (DeviceSyncer, error) {
+	labelSelector, err := edgeCli.ParseSelector(opts.EdgeSyncSelector)
+	if err != nil {
+		return DeviceSyncer{}, err
+	}
 	return DeviceSyncer{
-		syncPeriod: time.Duration(opts.EdgeSyncPeriod) * time.Second,
-		deviceCli:  efCli.NewEdgexDeviceClient(opts.CoreMetadataAddr, opts.CoreCommandAddr),
-		Client:     client,
-		NodePool:   opts.Nodepool,
-		Namespace:  opts.Namespace,
+		syncPeriod:    time.Duration(opts.EdgeSyncPeriod) * time.Second,
+		syncTimeout:   time.Duration(opts.EdgeSyncTimeout) * time.Second,
+		deviceCli:     deviceCli,
+		Client:        client,
+		NodePool:      opts.Nodepool,
+		Namespace:     opts.Namespace,
+		labelSelector: labelSelector,
 	}, nil
 }
 
 // NewDeviceSyncerRunnable initialize a controller-runtime manager runnable
 func (ds *DeviceSyncer) NewDeviceSyncerRunnable() ctrlmgr.RunnableFunc {
 	return func(ctx context.Context) error {
-		ds.Run(ctx.Done())
+		ds.Run(ctx)
 		return nil
 	}
 }
 
-func (ds *DeviceSyncer) Run(stop <-chan struct{}) {
+func (ds *DeviceSyncer) Run(ctx context.Context) {
 	klog.V(1).Info("[Device] Starting the syncer...")
 	go func() {
 		for {
-			<-time.After(ds.syncPeriod)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ds.syncPeriod):
+			}
 			klog.V(2).Info("[Device] Start a round of synchronization.")
-			// 1. get device on edge platform and OpenYurt
-			edgeDevices, kubeDevices, err := ds.getAllDevices()
+			// 1. create or update the devices on OpenYurt according to edge platform page by page
+			edgeDeviceNames, err := ds.syncEdgeDevices(ctx)
 			if err != nil {
-				klog.V(3).ErrorS(err, "fail to list the devices")
+				// the redundant devices can not be found unless all the devices on edge platform are listed,
+				// so none of them is deleted if the listing fails or is incomplete
+				klog.V(3).ErrorS(err, "fail to list the devices on edge platform")
 				continue
 			}
 
-			// 2. find the device that need to be synchronized
-			redundantEdgeDevices, redundantKubeDevices, syncedDevices := ds.findDiffDevice(edgeDevices, kubeDevices)
-			klog.V(2).Infof("[Device] The number of objects waiting for synchronization { %s:%d, %s:%d, %s:%d }",
-				"Edge device should be added to OpenYurt", len(redundantEdgeDevices),
-				"OpenYurt device that should be deleted", len(redundantKubeDevices),
-				"Devices that should be synchronized", len(syncedDevices))
-
-			// 3. create device on OpenYurt which are exists in edge platform but not in OpenYurt
-			if err := ds.syncEdgeToKube(redundantEdgeDevices); err != nil {
-				klog.V(3).ErrorS(err, "fail to create devices on OpenYurt")
+			// 2. get devices on OpenYurt
+			kubeDevices, err := ds.getKubeDevices(ctx)
+			if err != nil {
+				klog.V(3).ErrorS(err, "fail to list the devices")
+				continue
 			}
 
-			// 4. delete redundant device on OpenYurt
-			if err := ds.deleteDevices(redundantKubeDevices); err != nil {
+			// 3. delete redundant device on OpenYurt
+			redundantKubeDevices := ds.findRedundantKubeDevices(kubeDevices, edgeDeviceNames)
+			klog.V(2).Infof("[Device] The number of objects waiting for synchronization { %s:%d }",
+				"OpenYurt device that should be deleted", len(redundantKubeDevices))
+			if err := ds.deleteDevices(ctx, redundantKubeDevices); err != nil {
 				klog.V(3).ErrorS(err, "fail to delete redundant devices on OpenYurt")
 			}
-
-			// 5. update device status on OpenYurt
-			if err := ds.updateDevices(syncedDevices); err != nil {
-				klog.V(3).ErrorS(err, "fail to update devices status")
-			}
 			klog.V(2).Info("[Device] One round of synchronization is complete")
 		}
 	}()
 
-	<-stop
+	<-ctx.Done()
 	klog.V(1).Info("[Device] Stopping the syncer")
 }
 
-// Get the existing Device on the Edge platform, as well as OpenYurt existing Device
-// edgeDevice：map[actualName]device
+// Get the existing Device on OpenYurt
 // kubeDevice：map[actualName]device
-func (ds *DeviceSyncer) getAllDevices() (map[string]devicev1alpha1.Device, map[string]devicev1alpha1.Device, error) {
-	edgeDevice := map[string]devicev1alpha1.Device{}
+func (ds *DeviceSyncer) getKubeDevices(ctx context.Context) (map[string]devicev1alpha1.Device, error) {
 	kubeDevice := map[string]devicev1alpha1.Device{}
-	// 1. list devices on edge platform
-	eDevs, err := ds.deviceCli.List(context.TODO(), edgeCli.ListOptions{})
-	if err != nil {
-		klog.V(4).ErrorS(err, "fail to list the devices object on the Edge Platform")
-		return edgeDevice, kubeDevice, err
-	}
-	// 2. list devices on OpenYurt (filter objects belonging to edgeServer)
+	// list devices on OpenYurt (filter objects belonging to edgeServer)
 	var kDevs devicev1alpha1.DeviceList
 	listOptions := client.MatchingFields{util.IndexerPathForNodepool: ds.NodePool}
-	if err = ds.List(context.TODO(), &kDevs, listOptions, client.InNamespace(ds.Namespace)); err != nil {
+	if err := ds.List(ctx, &kDevs, listOptions, client.InNamespace(ds.Namespace)); err != nil {
 		klog.V(4).ErrorS(err, "fail to list the devices object on the OpenYurt")
-		return edgeDevice, kubeDevice, err
-	}
-	for i := range eDevs {
-		deviceName := util.GetEdgeDeviceName(&eDevs[i], EdgeXObjectName)
-		edgeDevice[deviceName] = eDevs[i]
+		return kubeDevice, err
 	}
-
 	for i := range kDevs.Items {
+		// the objects which are not selected are left alone
+		if !edgeCli.LabelsMatch(kDevs.Items[i].Spec.Labels, ds.labelSelector) {
+			continue
+		}
 		deviceName := util.GetEdgeDeviceName(&kDevs.Items[i], EdgeXObjectName)
 		kubeDevice[deviceName] = kDevs.Items[i]
 	}
-	return edgeDevice, kubeDevice, nil
+	return kubeDevice, nil
+}
+
+// getKubePageDevices looks up the devices on OpenYurt of a page of edge platform by their actual names,
+// kubeDevices：map[actualName]device
+func (ds *DeviceSyncer) getKubePageDevices(ctx context.Context, edgeDevices []devicev1alpha1.Device) (map[string]devicev1alpha1.Device, error) {
+	kubeDevices := map[string]devicev1alpha1.Device{}
+	for i := range edgeDevices {
+		deviceName := util.GetEdgeDeviceName(&edgeDevices[i], EdgeXObjectName)
+		var kDevs devicev1alpha1.DeviceList
+		listOptions := client.MatchingFields{util.IndexerPathForEdgeName: util.EdgeNameIndexValue(ds.NodePool, deviceName)}
+		if err := ds.List(ctx, &kDevs, listOptions, client.InNamespace(ds.Namespace)); err != nil {
+			klog.V(4).ErrorS(err, "fail to look up the device object on the OpenYurt", "DeviceName", deviceName)
+			return kubeDevices, err
+		}
+		for j := range kDevs.Items {
+			// the objects which are not selected are left alone, the index is matched exactly by the cache,
+			// while the checks keep the lookup right with the readers which ignore the field selectors
+			if kDevs.Items[j].Spec.NodePool != ds.NodePool || util.GetEdgeDeviceName(&kDevs.Items[j], EdgeXObjectName) != deviceName || !edgeCli.LabelsMatch(kDevs.Items[j].Spec.Labels, ds.labelSelector) {
+				continue
+			}
+			kubeDevices[deviceName] = kDevs.Items[j]
+		}
+	}
+	return kubeDevices, nil
 }
 
-// Get the list of devices that need to be added, deleted and updated
+// syncEdgeDevices walks through the devices on edge platform page by page, the devices that do not exist
+// on OpenYurt are created and the others are updated. Only the devices of the page are looked up on OpenYurt,
+// while the actual names of all the devices on edge platform are returned to find the redundant devices on OpenYurt.
+func (ds *DeviceSyncer) syncEdgeDevices(ctx context.Context) (map[string]struct{}, error) {
+	edgeDeviceNames := map[string]struct{}{}
+	listCtx, cancel := withTimeout(ctx, ds.syncTimeout)
+	defer cancel()
+	err := ds.deviceCli.ListPages(listCtx, edgeCli.ListOptions{LabelSelector: ds.labelSelector}, func(edgeDevices []devicev1alpha1.Device) error {
+		for i := range edgeDevices {
+			edgeDeviceNames[util.GetEdgeDeviceName(&edgeDevices[i], EdgeXObjectName)] = struct{}{}
+		}
+
+		// a. find the devices of this page that need to be synchronized
+		kubeDevices, err := ds.getKubePageDevices(ctx, edgeDevices)
+		if err != nil {
+			return err
+		}
+		redundantEdgeDevices, syncedDevices := ds.findDiffDevice(edgeDevices, kubeDevices)
+		klog.V(2).Infof("[Device] The number of objects waiting for synchronization { %s:%d, %s:%d }",
+			"Edge device should be added to OpenYurt", len(redundantEdgeDevices),
+			"Devices that should be synchronized", len(syncedDevices))
+
+		// b. create device on OpenYurt which are exists in edge platform but not in OpenYurt
+		if err := ds.syncEdgeToKube(ctx, redundantEdgeDevices); err != nil {
+			klog.V(3).ErrorS(err, "fail to create devices on OpenYurt")
+		}
+
+		// c. update device status on OpenYurt
+		if err := ds.updateDevices(ctx, syncedDevices); err != nil {
+			klog.V(3).ErrorS(err, "fail to update devices status")
+		}
+		return nil
+	})
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to list the devices object on the Edge Platform")
+	}
+	return edgeDeviceNames, err
+}
+
+// Get the list of devices in a page of edge platform that need to be added and updated
 func (ds *DeviceSyncer) findDiffDevice(
-	edgeDevices map[string]devicev1alpha1.Device, kubeDevices map[string]devicev1alpha1.Device) (
-	redundantEdgeDevices map[string]*devicev1alpha1.Device, redundantKubeDevices map[string]*devicev1alpha1.Device, syncedDevices map[string]*devicev1alpha1.Device) {
+	edgeDevices []devicev1alpha1.Device, kubeDevices map[string]devicev1alpha1.Device) (
+	redundantEdgeDevices map[string]*devicev1alpha1.Device, syncedDevices map[string]*devicev1alpha1.Device) {
 
 	redundantEdgeDevices = map[string]*devicev1alpha1.Device{}
-	redundantKubeDevices = map[string]*devicev1alpha1.Device{}
 	syncedDevices = map[string]*devicev1alpha1.Device{}
 
 	for i := range edgeDevices {
@@ -158,24 +219,29 @@ func (ds *DeviceSyncer) findDiffDevice(
 			syncedDevices[edName] = ds.completeUpdateContent(&kd, &ed)
 		}
 	}
+	return
+}
 
+// Get the list of devices on OpenYurt that do not exist on edge platform any more
+func (ds *DeviceSyncer) findRedundantKubeDevices(kubeDevices map[string]devicev1alpha1.Device, edgeDeviceNames map[string]struct{}) map[string]*devicev1alpha1.Device {
+	redundantKubeDevices := map[string]*devicev1alpha1.Device{}
 	for i := range kubeDevices {
 		kd := kubeDevices[i]
 		if !kd.Status.Synced {
 			continue
 		}
 		kdName := util.GetEdgeDeviceName(&kd, EdgeXObjectName)
-		if _, exists := edgeDevices[kdName]; !exists {
+		if _, exists := edgeDeviceNames[kdName]; !exists {
 			redundantKubeDevices[kdName] = &kd
 		}
 	}
-	return
+	return redundantKubeDevices
 }
 
 // syncEdgeToKube creates device on OpenYurt which are exists in edge platform but not in OpenYurt
-func (ds *DeviceSyncer) syncEdgeToKube(edgeDevs map[string]*devicev1alpha1.Device) error {
+func (ds *DeviceSyncer) syncEdgeToKube(ctx context.Context, edgeDevs map[string]*devicev1alpha1.Device) error {
 	for _, ed := range edgeDevs {
-		if err := ds.Client.Create(context.TODO(), ed); err != nil {
+		if err := ds.Client.Create(ctx, ed); err != nil {
 			if apierrors.IsAlreadyExists(err) {
 				continue
 			}
@@ -187,9 +253,9 @@ func (ds *DeviceSyncer) syncEdgeToKube(edgeDevs map[string]*devicev1alpha1.Devic
 }
 
 // deleteDevices deletes redundant device on OpenYurt
-func (ds *DeviceSyncer) deleteDevices(redundantKubeDevices map[string]*devicev1alpha1.Device) error {
+func (ds *DeviceSyncer) deleteDevices(ctx context.Context, redundantKubeDevices map[string]*devicev1alpha1.Device) error {
 	for _, kd := range redundantKubeDevices {
-		if err := ds.Client.Delete(context.TODO(), kd); err != nil {
+		if err := ds.Client.Delete(ctx, kd); err != nil {
 			klog.V(5).ErrorS(err, "fail to delete the device on OpenYurt",
 				"DeviceName", kd.Name)
 			return err
@@ -199,9 +265,9 @@ func (ds *DeviceSyncer) deleteDevices(redundantKubeDevices map[string]*devicev1a
 }
 
 // updateDevicesStatus updates device status on OpenYurt
-func (ds *DeviceSyncer) updateDevices(syncedDevices map[string]*devicev1alpha1.Device) error {
+func (ds *DeviceSyncer) updateDevices(ctx context.Context, syncedDevices map[string]*devicev1alpha1.Device) error {
 	for n := range syncedDevices {
-		if err := ds.Client.Status().Update(context.TODO(), syncedDevices[n]); err != nil {
+		if err := ds.Client.Status().Update(ctx, syncedDevices[n]); err != nil {
 			if apierrors.IsConflict(err) {
 				klog.V(5).InfoS("update Conflicts", "Device", syncedDevices[n].Name)
 				continue
@@ -223,15 +289,14 @@ func (ds *DeviceSyncer) completeCreateContent(edgeDevice *devicev1alpha1.Device)
 	return createDevice
 }
 
-// completeUpdateContent completes the content of the device which will be updated on OpenYurt
+// completeUpdateContent completes the content of the device which will be updated on OpenYurt,
+// only the metadata is mirrored, the actual properties are polled by the PropertyPoller on its own schedule
 func (ds *DeviceSyncer) completeUpdateContent(kubeDevice *devicev1alpha1.Device, edgeDevice *devicev1alpha1.Device) *devicev1alpha1.Device {
 	updatedDevice := kubeDevice.DeepCopy()
-	_, aps, _ := ds.deviceCli.ListPropertiesState(context.TODO(), updatedDevice, edgeCli.ListOptions{})
 	// update device status
 	updatedDevice.Status.LastConnected = edgeDevice.Status.LastConnected
 	updatedDevice.Status.LastReported = edgeDevice.Status.LastReported
 	updatedDevice.Status.AdminState = edgeDevice.Status.AdminState
 	updatedDevice.Status.OperatingState = edgeDevice.Status.OperatingState
-	updatedDevice.Status.DeviceProperties = aps
 	return updatedDevice
 }
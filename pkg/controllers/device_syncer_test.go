@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newEdgeDevice(name string, operatingState devicev1alpha1.OperatingState) devicev1alpha1.Device {
+	return devicev1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{EdgeXObjectName: name}},
+		Status:     devicev1alpha1.DeviceStatus{Synced: true, OperatingState: operatingState},
+	}
+}
+
+func TestSyncEdgeDevicesPageByPage(t *testing.T) {
+	synced := func(name, nodePool string) *devicev1alpha1.Device {
+		return &devicev1alpha1.Device{
+			ObjectMeta: metav1.ObjectMeta{Name: nodePool + "-" + name, Namespace: "default", Labels: map[string]string{EdgeXObjectName: name}},
+			Spec:       devicev1alpha1.DeviceSpec{NodePool: nodePool},
+			Status:     devicev1alpha1.DeviceStatus{Synced: true, OperatingState: devicev1alpha1.Up},
+		}
+	}
+	cli := &fakeDeviceClient{pages: [][]devicev1alpha1.Device{
+		{newEdgeDevice("device-a", devicev1alpha1.Down), newEdgeDevice("device-b", devicev1alpha1.Up)},
+		{newEdgeDevice("device-c", devicev1alpha1.Up)},
+	}}
+	ds := &DeviceSyncer{
+		Client:    newFakeClient(t, synced("device-a", "hangzhou"), synced("device-d", "hangzhou"), synced("device-b", "beijing")),
+		NodePool:  "hangzhou",
+		Namespace: "default",
+		deviceCli: cli,
+	}
+
+	edgeDeviceNames, err := ds.syncEdgeDevices(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edgeDeviceNames) != 3 {
+		t.Errorf("expected the names of the 3 devices on edge platform, got %v", edgeDeviceNames)
+	}
+	// the device of each page is looked up in the nodePool of the syncer
+	var updated devicev1alpha1.Device
+	if err := ds.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "hangzhou-device-a"}, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.OperatingState != devicev1alpha1.Down {
+		t.Errorf("expected the operating state of device-a to be updated, got %s", updated.Status.OperatingState)
+	}
+	for _, name := range []string{"hangzhou-device-b", "hangzhou-device-c"} {
+		var created devicev1alpha1.Device
+		if err := ds.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, &created); err != nil {
+			t.Errorf("expected %s to be created, got %v", name, err)
+		}
+	}
+
+	kubeDevices, err := ds.getKubeDevices(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	redundant := ds.findRedundantKubeDevices(kubeDevices, edgeDeviceNames)
+	if _, exists := redundant["device-d"]; len(redundant) != 1 || !exists {
+		t.Errorf("expected only device-d to be redundant, got %v", redundant)
+	}
+}
@@ -24,13 +24,15 @@ import (
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
 	"github.com/openyurtio/device-controller/pkg/clients"
-	edgexclis "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -38,9 +40,12 @@ import (
 // DeviceProfileReconciler reconciles a DeviceProfile object
 type DeviceProfileReconciler struct {
 	client.Client
-	Scheme     *runtime.Scheme
-	edgeClient clients.DeviceProfileInterface
-	NodePool   string
+	Scheme *runtime.Scheme
+	// edge platform client, injected by the caller
+	DeviceProfileCli clients.DeviceProfileInterface
+	NodePool         string
+	// the deadlines of the operations on edge platform
+	timeouts edgeTimeouts
 }
 
 //+kubebuilder:rbac:groups=device.openyurt.io,resources=deviceprofiles,verbs=get;list;watch;create;update;patch;delete
@@ -57,13 +62,34 @@ func (r *DeviceProfileReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, nil
 	}
 	klog.V(3).Infof("Reconciling the DeviceProfile: %s", dp.GetName())
+	// Update deviceProfile conditions
+	defer func() {
+		if dp.Status.Synced {
+			// the deviceProfiles synchronized from edge platform exist on both sides since they were created
+			conditions.MarkTrue(&dp, devicev1alpha1.DeviceProfileSyncedCondition)
+		}
+		// the unmanaged deviceProfile is ready once it is synced, so the managing condition only counts for the managed one
+		summaryConditions := []clusterv1.ConditionType{devicev1alpha1.DeviceProfileSyncedCondition, devicev1alpha1.DeviceProfileAuthorizedCondition}
+		if dp.Spec.Managed {
+			summaryConditions = append(summaryConditions, devicev1alpha1.DeviceProfileManagingCondition)
+		} else {
+			conditions.MarkFalse(&dp, devicev1alpha1.DeviceProfileManagingCondition, "this deviceProfile is not managed by openyurt", clusterv1.ConditionSeverityInfo, "")
+		}
+		conditions.SetSummary(&dp, conditions.WithConditions(summaryConditions...))
+		err := r.Status().Update(ctx, &dp)
+		if client.IgnoreNotFound(err) != nil {
+			if !apierrors.IsConflict(err) {
+				klog.V(4).ErrorS(err, "update deviceProfile conditions failed", "deviceProfile", dp.GetName())
+			}
+		}
+	}()
 
 	// gets the actual name of deviceProfile on the edge platform from the Label of the deviceProfile
 	dpActualName := util.GetEdgeDeviceProfileName(&dp, EdgeXObjectName)
 
 	// 1. Handle the deviceProfile deletion event
 	if err := r.reconcileDeleteDeviceProfile(ctx, &dp, dpActualName); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return resultForObjectEdgeError(&dp, devicev1alpha1.DeviceProfileAuthorizedCondition, client.IgnoreNotFound(err))
 	} else if !dp.ObjectMeta.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
@@ -71,23 +97,25 @@ func (r *DeviceProfileReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	if !dp.Status.Synced {
 		// 2. Synchronize OpenYurt deviceProfile to edge platform
 		if err := r.reconcileCreateDeviceProfile(ctx, &dp, dpActualName); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{Requeue: true}, nil
-			} else {
-				return ctrl.Result{}, err
-			}
+			return resultForObjectEdgeError(&dp, devicev1alpha1.DeviceProfileAuthorizedCondition, err)
+		}
+	} else if dp.Spec.Managed {
+		// 3. If the deviceProfile has been synchronized and is managed by the cloud, reconcile the deviceProfile fields
+		if err := r.reconcileUpdateDeviceProfile(ctx, &dp, dpActualName); err != nil {
+			return resultForObjectEdgeError(&dp, devicev1alpha1.DeviceProfileAuthorizedCondition, err)
 		}
 	}
-	// 3. Handle the deviceProfile update event
-	// TODO
-
+	markEdgeAuthorized(&dp, devicev1alpha1.DeviceProfileAuthorizedCondition, nil)
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DeviceProfileReconciler) SetupWithManager(mgr ctrl.Manager, opts *options.YurtDeviceControllerOptions) error {
-	r.edgeClient = edgexclis.NewEdgexDeviceProfile(opts.CoreMetadataAddr)
+	if r.DeviceProfileCli == nil {
+		return fmt.Errorf("the edge platform client of deviceProfile reconciler is not set")
+	}
 	r.NodePool = opts.Nodepool
+	r.timeouts = newEdgeTimeouts(opts)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&devicev1alpha1.DeviceProfile{}).
@@ -127,7 +155,9 @@ func (r *DeviceProfileReconciler) reconcileDeleteDeviceProfile(ctx context.Conte
 		}
 
 		// delete the deviceProfile object on edge platform
-		err := r.edgeClient.Delete(context.TODO(), actualName, clients.DeleteOptions{})
+		deleteCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		err := r.DeviceProfileCli.Delete(deleteCtx, actualName, clients.DeleteOptions{})
+		cancel()
 		if err != nil && !clients.IsNotFoundErr(err) {
 			return err
 		}
@@ -137,27 +167,99 @@ func (r *DeviceProfileReconciler) reconcileDeleteDeviceProfile(ctx context.Conte
 
 func (r *DeviceProfileReconciler) reconcileCreateDeviceProfile(ctx context.Context, dp *devicev1alpha1.DeviceProfile, actualName string) error {
 	klog.V(4).Infof("Checking if deviceProfile already exist on the edge platform: %s", dp.GetName())
-	if edgeDp, err := r.edgeClient.Get(context.TODO(), actualName, clients.GetOptions{}); err != nil {
+	getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	edgeDp, err := r.DeviceProfileCli.Get(getCtx, actualName, clients.GetOptions{})
+	cancel()
+	if err != nil {
 		if !clients.IsNotFoundErr(err) {
 			klog.V(4).ErrorS(err, "fail to visit the edge platform")
-			return nil
+			conditions.MarkFalse(dp, devicev1alpha1.DeviceProfileSyncedCondition, "failed to visit the EdgeX core-metadata-service", clusterv1.ConditionSeverityWarning, err.Error())
+			return err
 		}
 	} else {
 		// a. If object exists, the status of the deviceProfile on OpenYurt is updated
 		klog.V(4).Info("DeviceProfile already exists on edge platform")
 		dp.Status.Synced = true
 		dp.Status.EdgeId = edgeDp.Status.EdgeId
+		conditions.MarkTrue(dp, devicev1alpha1.DeviceProfileSyncedCondition)
 		return r.Status().Update(ctx, dp)
 	}
 
 	// b. If object does not exist, a request is sent to the edge platform to create a new deviceProfile
-	createDp, err := r.edgeClient.Create(context.Background(), dp, clients.CreateOptions{})
+	createCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	createDp, err := r.DeviceProfileCli.Create(createCtx, dp, clients.CreateOptions{})
+	cancel()
+	if clients.IsAlreadyExistsErr(err) {
+		// the deviceProfile has been added to the edge platform in the meantime
+		klog.V(4).Info("DeviceProfile already exists on edge platform")
+		getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		createDp, err = r.DeviceProfileCli.Get(getCtx, actualName, clients.GetOptions{})
+		cancel()
+	}
 	if err != nil {
 		klog.V(4).ErrorS(err, "failed to create deviceProfile on edge platform")
-		return fmt.Errorf("failed to add deviceProfile to edge platform: %v", err)
+		conditions.MarkFalse(dp, devicev1alpha1.DeviceProfileSyncedCondition, "failed to add DeviceProfile to EdgeX", clusterv1.ConditionSeverityWarning, err.Error())
+		return fmt.Errorf("failed to add deviceProfile to edge platform: %w", err)
 	}
 	klog.V(3).Infof("Successfully add DeviceProfile to edge platform, Name: %s, EdgeId: %s", createDp.GetName(), createDp.Status.EdgeId)
 	dp.Status.EdgeId = createDp.Status.EdgeId
 	dp.Status.Synced = true
+	conditions.MarkTrue(dp, devicev1alpha1.DeviceProfileSyncedCondition)
 	return r.Status().Update(ctx, dp)
 }
+
+func (r *DeviceProfileReconciler) reconcileUpdateDeviceProfile(ctx context.Context, dp *devicev1alpha1.DeviceProfile, actualName string) error {
+	// 1. get the deviceProfile on edge platform
+	getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	edgeDp, err := r.DeviceProfileCli.Get(getCtx, actualName, clients.GetOptions{})
+	cancel()
+	if err != nil {
+		conditions.MarkFalse(dp, devicev1alpha1.DeviceProfileManagingCondition, "failed to get deviceProfile from edge platform", clusterv1.ConditionSeverityWarning, err.Error())
+		return err
+	}
+
+	// 2. find the fields that are different between OpenYurt and edge platform
+	driftFields := findDeviceProfileDrift(&dp.Spec, &edgeDp.Spec)
+	if len(driftFields) == 0 {
+		conditions.MarkTrue(dp, devicev1alpha1.DeviceProfileManagingCondition)
+		return nil
+	}
+	klog.V(3).Infof("DeviceProfileName: %s, the fields %v are different from edge platform", dp.GetName(), driftFields)
+
+	// 3. update the deviceProfile on edge platform to be the same as OpenYurt
+	updateCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	_, err = r.DeviceProfileCli.Update(updateCtx, dp, clients.UpdateOptions{})
+	cancel()
+	if err != nil {
+		conditions.MarkFalse(dp, devicev1alpha1.DeviceProfileManagingCondition, fmt.Sprintf("failed to update the fields %v of deviceProfile on edge platform", driftFields), clusterv1.ConditionSeverityWarning, err.Error())
+		return err
+	}
+	klog.V(4).Infof("DeviceProfileName: %s, successfully update the deviceProfile on edge platform", dp.GetName())
+	conditions.MarkTrue(dp, devicev1alpha1.DeviceProfileManagingCondition)
+	return nil
+}
+
+// findDeviceProfileDrift returns the names of the fields which are synchronized with edge platform but have different values,
+// the fields only exist in OpenYurt, such as NodePool and Managed, are ignored
+func findDeviceProfileDrift(kubeSpec, edgeSpec *devicev1alpha1.DeviceProfileSpec) []string {
+	var driftFields []string
+	if kubeSpec.Description != edgeSpec.Description {
+		driftFields = append(driftFields, "description")
+	}
+	if kubeSpec.Manufacturer != edgeSpec.Manufacturer {
+		driftFields = append(driftFields, "manufacturer")
+	}
+	if kubeSpec.Model != edgeSpec.Model {
+		driftFields = append(driftFields, "model")
+	}
+	if !equality.Semantic.DeepEqual(kubeSpec.Labels, edgeSpec.Labels) {
+		driftFields = append(driftFields, "labels")
+	}
+	if !equality.Semantic.DeepEqual(kubeSpec.DeviceResources, edgeSpec.DeviceResources) {
+		driftFields = append(driftFields, "deviceResources")
+	}
+	if !equality.Semantic.DeepEqual(kubeSpec.DeviceCommands, edgeSpec.DeviceCommands) {
+		driftFields = append(driftFields, "deviceCommands")
+	}
+	return driftFields
+}
@@ -24,11 +24,12 @@ import (
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
 	devcli "github.com/openyurtio/device-controller/pkg/clients"
-	edgexclis "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlmgr "sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -36,115 +37,179 @@ import (
 type DeviceProfileSyncer struct {
 	// syncing period in seconds
 	syncPeriod time.Duration
+	// the deadline of listing the deviceProfiles on edge platform in a round of synchronization
+	syncTimeout time.Duration
 	// edge platform client
 	edgeClient devcli.DeviceProfileInterface
 	// Kubernetes client
 	client.Client
 	NodePool  string
 	Namespace string
+	// only the objects with these labels are synchronized
+	labelSelector map[string]string
+	// the annotations kept by the edge platform client, which are mirrored along with the deviceResources
+	preservedAnnotations []string
 }
 
 // NewDeviceProfileSyncer initialize a New DeviceProfileSyncer
-func NewDeviceProfileSyncer(client client.Client, opts *options.YurtDeviceControllerOptions) (DeviceProfileSyncer, error) {
+func NewDeviceProfileSyncer(client client.Client, edgeClients *devcli.EdgePlatformClients, opts *options.YurtDeviceControllerOptions) (DeviceProfileSyncer, error) {
+	labelSelector, err := devcli.ParseSelector(opts.EdgeSyncSelector)
+	if err != nil {
+		return DeviceProfileSyncer{}, err
+	}
 	return DeviceProfileSyncer{
-		syncPeriod: time.Duration(opts.EdgeSyncPeriod) * time.Second,
-		edgeClient: edgexclis.NewEdgexDeviceProfile(opts.CoreMetadataAddr),
-		Client:     client,
-		NodePool:   opts.Nodepool,
-		Namespace:  opts.Namespace,
+		syncPeriod:           time.Duration(opts.EdgeSyncPeriod) * time.Second,
+		syncTimeout:          time.Duration(opts.EdgeSyncTimeout) * time.Second,
+		edgeClient:           edgeClients.DeviceProfileCli,
+		Client:               client,
+		NodePool:             opts.Nodepool,
+		Namespace:            opts.Namespace,
+		labelSelector:        labelSelector,
+		preservedAnnotations: edgeClients.PreservedAnnotations,
 	}, nil
 }
 
 // NewDeviceProfileSyncerRunnable initialize a controller-runtime manager runnable
 func (dps *DeviceProfileSyncer) NewDeviceProfileSyncerRunnable() ctrlmgr.RunnableFunc {
 	return func(ctx context.Context) error {
-		dps.Run(ctx.Done())
+		dps.Run(ctx)
 		return nil
 	}
 }
 
-func (dps *DeviceProfileSyncer) Run(stop <-chan struct{}) {
+func (dps *DeviceProfileSyncer) Run(ctx context.Context) {
 	klog.V(1).Info("[DeviceProfile] Starting the syncer...")
 	go func() {
 		for {
-			<-time.After(dps.syncPeriod)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(dps.syncPeriod):
+			}
 			klog.V(2).Info("[DeviceProfile] Start a round of synchronization.")
 
-			// 1. get deviceProfiles on edge platform and OpenYurt
-			edgeDeviceProfiles, kubeDeviceProfiles, err := dps.getAllDeviceProfiles()
+			// 1. create or update the deviceProfiles on OpenYurt according to edge platform page by page
+			edgeDeviceProfileNames, err := dps.syncEdgeDeviceProfiles(ctx)
 			if err != nil {
-				klog.V(3).ErrorS(err, "fail to list the deviceProfiles")
+				// the redundant deviceProfiles can not be found unless all the deviceProfiles on edge platform are listed,
+				// so none of them is deleted if the listing fails or is incomplete
+				klog.V(3).ErrorS(err, "fail to list the deviceProfiles on edge platform")
 				continue
 			}
 
-			// 2. find the deviceProfiles that need to be synchronized
-			redundantEdgeDeviceProfiles, redundantKubeDeviceProfiles, syncedDeviceProfiles :=
-				dps.findDiffDeviceProfiles(edgeDeviceProfiles, kubeDeviceProfiles)
-			klog.V(2).Infof("[DeviceProfile] The number of objects waiting for synchronization { %s:%d, %s:%d, %s:%d }",
-				"Edge deviceProfiles should be added to OpenYurt", len(redundantEdgeDeviceProfiles),
-				"OpenYurt deviceProfiles that should be deleted", len(redundantKubeDeviceProfiles),
-				"DeviceProfiles that should be synchronized", len(syncedDeviceProfiles))
-
-			// 3. create deviceProfiles on OpenYurt which are exists in edge platform but not in OpenYurt
-			if err := dps.syncEdgeToKube(redundantEdgeDeviceProfiles); err != nil {
-				klog.V(3).ErrorS(err, "fail to create deviceProfiles on OpenYurt")
+			// 2. get deviceProfiles on OpenYurt
+			kubeDeviceProfiles, err := dps.getKubeDeviceProfiles(ctx)
+			if err != nil {
+				klog.V(3).ErrorS(err, "fail to list the deviceProfiles")
+				continue
 			}
 
-			// 4. delete redundant deviceProfiles on OpenYurt
-			if err := dps.deleteDeviceProfiles(redundantKubeDeviceProfiles); err != nil {
+			// 3. delete redundant deviceProfiles on OpenYurt
+			redundantKubeDeviceProfiles := dps.findRedundantKubeDeviceProfiles(kubeDeviceProfiles, edgeDeviceProfileNames)
+			klog.V(2).Infof("[DeviceProfile] The number of objects waiting for synchronization { %s:%d }",
+				"OpenYurt deviceProfiles that should be deleted", len(redundantKubeDeviceProfiles))
+			if err := dps.deleteDeviceProfiles(ctx, redundantKubeDeviceProfiles); err != nil {
 				klog.V(3).ErrorS(err, "fail to delete redundant deviceProfiles on OpenYurt")
 			}
-
-			// 5. update deviceProfiles on OpenYurt
-			// TODO
+			klog.V(2).Info("[DeviceProfile] One round of synchronization is complete")
 		}
 	}()
 
-	<-stop
+	<-ctx.Done()
 	klog.V(1).Info("[DeviceProfile] Stopping the syncer")
 }
 
-// Get the existing DeviceProfile on the Edge platform, as well as OpenYurt existing DeviceProfile
-// edgeDeviceProfiles：map[actualName]DeviceProfile
+// Get the existing DeviceProfile on OpenYurt
 // kubeDeviceProfiles：map[actualName]DeviceProfile
-func (dps *DeviceProfileSyncer) getAllDeviceProfiles() (
-	map[string]devicev1alpha1.DeviceProfile, map[string]devicev1alpha1.DeviceProfile, error) {
-
-	edgeDeviceProfiles := map[string]devicev1alpha1.DeviceProfile{}
+func (dps *DeviceProfileSyncer) getKubeDeviceProfiles(ctx context.Context) (map[string]devicev1alpha1.DeviceProfile, error) {
 	kubeDeviceProfiles := map[string]devicev1alpha1.DeviceProfile{}
-
-	// 1. list deviceProfiles on edge platform
-	eDps, err := dps.edgeClient.List(context.TODO(), devcli.ListOptions{})
-	if err != nil {
-		klog.V(4).ErrorS(err, "fail to list the deviceProfiles on the edge platform")
-		return edgeDeviceProfiles, kubeDeviceProfiles, err
-	}
-	// 2. list deviceProfiles on OpenYurt (filter objects belonging to edgeServer)
+	// list deviceProfiles on OpenYurt (filter objects belonging to edgeServer)
 	var kDps devicev1alpha1.DeviceProfileList
 	listOptions := client.MatchingFields{util.IndexerPathForNodepool: dps.NodePool}
-	if err = dps.List(context.TODO(), &kDps, listOptions, client.InNamespace(dps.Namespace)); err != nil {
+	if err := dps.List(ctx, &kDps, listOptions, client.InNamespace(dps.Namespace)); err != nil {
 		klog.V(4).ErrorS(err, "fail to list the deviceProfiles on the Kubernetes")
-		return edgeDeviceProfiles, kubeDeviceProfiles, err
-	}
-	for i := range eDps {
-		deviceProfilesName := util.GetEdgeDeviceProfileName(&eDps[i], EdgeXObjectName)
-		edgeDeviceProfiles[deviceProfilesName] = eDps[i]
+		return kubeDeviceProfiles, err
 	}
-
 	for i := range kDps.Items {
+		// the objects which are not selected are left alone
+		if !devcli.LabelsMatch(kDps.Items[i].Spec.Labels, dps.labelSelector) {
+			continue
+		}
 		deviceProfilesName := util.GetEdgeDeviceProfileName(&kDps.Items[i], EdgeXObjectName)
 		kubeDeviceProfiles[deviceProfilesName] = kDps.Items[i]
 	}
-	return edgeDeviceProfiles, kubeDeviceProfiles, nil
+	return kubeDeviceProfiles, nil
+}
+
+// getKubePageDeviceProfiles looks up the deviceProfiles on OpenYurt of a page of edge platform by their actual names,
+// kubeDeviceProfiles：map[actualName]DeviceProfile
+func (dps *DeviceProfileSyncer) getKubePageDeviceProfiles(ctx context.Context, edgeDeviceProfiles []devicev1alpha1.DeviceProfile) (map[string]devicev1alpha1.DeviceProfile, error) {
+	kubeDeviceProfiles := map[string]devicev1alpha1.DeviceProfile{}
+	for i := range edgeDeviceProfiles {
+		deviceProfileName := util.GetEdgeDeviceProfileName(&edgeDeviceProfiles[i], EdgeXObjectName)
+		var kDps devicev1alpha1.DeviceProfileList
+		listOptions := client.MatchingFields{util.IndexerPathForEdgeName: util.EdgeNameIndexValue(dps.NodePool, deviceProfileName)}
+		if err := dps.List(ctx, &kDps, listOptions, client.InNamespace(dps.Namespace)); err != nil {
+			klog.V(4).ErrorS(err, "fail to look up the deviceProfile on the Kubernetes", "DeviceProfile", deviceProfileName)
+			return kubeDeviceProfiles, err
+		}
+		for j := range kDps.Items {
+			// the objects which are not selected are left alone, the index is matched exactly by the cache,
+			// while the checks keep the lookup right with the readers which ignore the field selectors
+			if kDps.Items[j].Spec.NodePool != dps.NodePool || util.GetEdgeDeviceProfileName(&kDps.Items[j], EdgeXObjectName) != deviceProfileName || !devcli.LabelsMatch(kDps.Items[j].Spec.Labels, dps.labelSelector) {
+				continue
+			}
+			kubeDeviceProfiles[deviceProfileName] = kDps.Items[j]
+		}
+	}
+	return kubeDeviceProfiles, nil
+}
+
+// syncEdgeDeviceProfiles walks through the deviceProfiles on edge platform page by page, the deviceProfiles that do not exist
+// on OpenYurt are created and the others are updated. Only the deviceProfiles of the page are looked up on OpenYurt, while the
+// actual names of all the deviceProfiles on edge platform are returned to find the redundant deviceProfiles on OpenYurt.
+func (dps *DeviceProfileSyncer) syncEdgeDeviceProfiles(ctx context.Context) (map[string]struct{}, error) {
+	edgeDeviceProfileNames := map[string]struct{}{}
+	listCtx, cancel := withTimeout(ctx, dps.syncTimeout)
+	defer cancel()
+	err := dps.edgeClient.ListPages(listCtx, devcli.ListOptions{LabelSelector: dps.labelSelector}, func(edgeDeviceProfiles []devicev1alpha1.DeviceProfile) error {
+		for i := range edgeDeviceProfiles {
+			edgeDeviceProfileNames[util.GetEdgeDeviceProfileName(&edgeDeviceProfiles[i], EdgeXObjectName)] = struct{}{}
+		}
+
+		// a. find the deviceProfiles of this page that need to be synchronized
+		kubeDeviceProfiles, err := dps.getKubePageDeviceProfiles(ctx, edgeDeviceProfiles)
+		if err != nil {
+			return err
+		}
+		redundantEdgeDeviceProfiles, syncedDeviceProfiles := dps.findDiffDeviceProfiles(edgeDeviceProfiles, kubeDeviceProfiles)
+		klog.V(2).Infof("[DeviceProfile] The number of objects waiting for synchronization { %s:%d, %s:%d }",
+			"Edge deviceProfiles should be added to OpenYurt", len(redundantEdgeDeviceProfiles),
+			"DeviceProfiles that should be synchronized", len(syncedDeviceProfiles))
+
+		// b. create deviceProfiles on OpenYurt which are exists in edge platform but not in OpenYurt
+		if err := dps.syncEdgeToKube(ctx, redundantEdgeDeviceProfiles); err != nil {
+			klog.V(3).ErrorS(err, "fail to create deviceProfiles on OpenYurt")
+		}
+
+		// c. update deviceProfiles on OpenYurt
+		if err := dps.updateDeviceProfiles(ctx, syncedDeviceProfiles); err != nil {
+			klog.V(3).ErrorS(err, "fail to update deviceProfiles")
+		}
+		return nil
+	})
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to list the deviceProfiles on the edge platform")
+	}
+	return edgeDeviceProfileNames, err
 }
 
-// Get the list of deviceProfiles that need to be added, deleted and updated
+// Get the list of deviceProfiles in a page of edge platform that need to be added and updated
 func (dps *DeviceProfileSyncer) findDiffDeviceProfiles(
-	edgeDeviceProfiles map[string]devicev1alpha1.DeviceProfile, kubeDeviceProfiles map[string]devicev1alpha1.DeviceProfile) (
-	redundantEdgeDeviceProfiles map[string]*devicev1alpha1.DeviceProfile, redundantKubeDeviceProfiles map[string]*devicev1alpha1.DeviceProfile, syncedDeviceProfiles map[string]*devicev1alpha1.DeviceProfile) {
+	edgeDeviceProfiles []devicev1alpha1.DeviceProfile, kubeDeviceProfiles map[string]devicev1alpha1.DeviceProfile) (
+	redundantEdgeDeviceProfiles map[string]*devicev1alpha1.DeviceProfile, syncedDeviceProfiles map[string]*devicev1alpha1.DeviceProfile) {
 
 	redundantEdgeDeviceProfiles = map[string]*devicev1alpha1.DeviceProfile{}
-	redundantKubeDeviceProfiles = map[string]*devicev1alpha1.DeviceProfile{}
 	syncedDeviceProfiles = map[string]*devicev1alpha1.DeviceProfile{}
 
 	for i := range edgeDeviceProfiles {
@@ -154,21 +219,30 @@ func (dps *DeviceProfileSyncer) findDiffDeviceProfiles(
 			redundantEdgeDeviceProfiles[edpName] = dps.completeCreateContent(&edp)
 		} else {
 			kdp := kubeDeviceProfiles[edpName]
-			syncedDeviceProfiles[edpName] = dps.completeUpdateContent(&kdp, &edp)
+			// only the deviceProfiles whose fields are different from edge platform need to be synchronized
+			if kdp.Status.Synced && len(findDeviceProfileDrift(&kdp.Spec, &edp.Spec)) != 0 {
+				syncedDeviceProfiles[edpName] = dps.completeUpdateContent(&kdp, &edp)
+			}
 		}
 	}
+	return
+}
 
+// Get the list of deviceProfiles on OpenYurt that do not exist on edge platform any more
+func (dps *DeviceProfileSyncer) findRedundantKubeDeviceProfiles(
+	kubeDeviceProfiles map[string]devicev1alpha1.DeviceProfile, edgeDeviceProfileNames map[string]struct{}) map[string]*devicev1alpha1.DeviceProfile {
+	redundantKubeDeviceProfiles := map[string]*devicev1alpha1.DeviceProfile{}
 	for i := range kubeDeviceProfiles {
 		kdp := kubeDeviceProfiles[i]
 		if !kdp.Status.Synced {
 			continue
 		}
 		kdpName := util.GetEdgeDeviceProfileName(&kdp, EdgeXObjectName)
-		if _, exists := edgeDeviceProfiles[kdpName]; !exists {
+		if _, exists := edgeDeviceProfileNames[kdpName]; !exists {
 			redundantKubeDeviceProfiles[kdpName] = &kdp
 		}
 	}
-	return
+	return redundantKubeDeviceProfiles
 }
 
 // completeCreateContent completes the content of the deviceProfile which will be created on OpenYurt
@@ -180,16 +254,42 @@ func (dps *DeviceProfileSyncer) completeCreateContent(edgeDps *devicev1alpha1.De
 	return createDeviceProfile
 }
 
-// completeUpdateContent completes the content of the deviceProfile which will be updated on OpenYurt
-// TODO
-func (dps *DeviceProfileSyncer) completeUpdateContent(kubeDps *devicev1alpha1.DeviceProfile, edgeDS *devicev1alpha1.DeviceProfile) *devicev1alpha1.DeviceProfile {
-	return kubeDps
+// completeUpdateContent completes the content of the deviceProfile which will be updated on OpenYurt.
+// If the deviceProfile is managed by cloud, only the DeviceProfileManaging condition reports the drift,
+// and the deviceProfile reconciler will correct the deviceProfile on edge platform.
+// Otherwise, the fields of deviceProfile on OpenYurt are updated to the ones on edge platform.
+func (dps *DeviceProfileSyncer) completeUpdateContent(kubeDps *devicev1alpha1.DeviceProfile, edgeDps *devicev1alpha1.DeviceProfile) *devicev1alpha1.DeviceProfile {
+	updatedDp := kubeDps.DeepCopy()
+	if updatedDp.Spec.Managed {
+		driftFields := findDeviceProfileDrift(&kubeDps.Spec, &edgeDps.Spec)
+		conditions.MarkFalse(updatedDp, devicev1alpha1.DeviceProfileManagingCondition, "the deviceProfile on edge platform is different from OpenYurt",
+			clusterv1.ConditionSeverityWarning, "different fields: %v", driftFields)
+		return updatedDp
+	}
+	updatedDp.Spec.Description = edgeDps.Spec.Description
+	updatedDp.Spec.Manufacturer = edgeDps.Spec.Manufacturer
+	updatedDp.Spec.Model = edgeDps.Spec.Model
+	updatedDp.Spec.Labels = edgeDps.Spec.Labels
+	updatedDp.Spec.DeviceResources = edgeDps.Spec.DeviceResources
+	updatedDp.Spec.DeviceCommands = edgeDps.Spec.DeviceCommands
+	// the annotations kept by the edge platform client, e.g. the types of the attributes, are mirrored along with the deviceResources
+	for _, key := range dps.preservedAnnotations {
+		if value, ok := edgeDps.Annotations[key]; ok {
+			if updatedDp.Annotations == nil {
+				updatedDp.Annotations = map[string]string{}
+			}
+			updatedDp.Annotations[key] = value
+		} else {
+			delete(updatedDp.Annotations, key)
+		}
+	}
+	return updatedDp
 }
 
 // syncEdgeToKube creates deviceProfiles on OpenYurt which are exists in edge platform but not in OpenYurt
-func (dps *DeviceProfileSyncer) syncEdgeToKube(edgeDps map[string]*devicev1alpha1.DeviceProfile) error {
+func (dps *DeviceProfileSyncer) syncEdgeToKube(ctx context.Context, edgeDps map[string]*devicev1alpha1.DeviceProfile) error {
 	for _, edp := range edgeDps {
-		if err := dps.Client.Create(context.TODO(), edp); err != nil {
+		if err := dps.Client.Create(ctx, edp); err != nil {
 			if apierrors.IsAlreadyExists(err) {
 				klog.V(5).Infof("DeviceProfile already exist on Kubernetes: %s", strings.ToLower(edp.Name))
 				continue
@@ -202,9 +302,9 @@ func (dps *DeviceProfileSyncer) syncEdgeToKube(edgeDps map[string]*devicev1alpha
 }
 
 // deleteDeviceProfiles deletes redundant deviceProfiles on OpenYurt
-func (dps *DeviceProfileSyncer) deleteDeviceProfiles(redundantKubeDeviceProfiles map[string]*devicev1alpha1.DeviceProfile) error {
+func (dps *DeviceProfileSyncer) deleteDeviceProfiles(ctx context.Context, redundantKubeDeviceProfiles map[string]*devicev1alpha1.DeviceProfile) error {
 	for _, kdp := range redundantKubeDeviceProfiles {
-		if err := dps.Client.Delete(context.TODO(), kdp); err != nil {
+		if err := dps.Client.Delete(ctx, kdp); err != nil {
 			klog.V(5).ErrorS(err, "fail to delete the DeviceProfile on Kubernetes: %s ",
 				"DeviceProfile", kdp.Name)
 			return err
@@ -212,3 +312,26 @@ func (dps *DeviceProfileSyncer) deleteDeviceProfiles(redundantKubeDeviceProfiles
 	}
 	return nil
 }
+
+// updateDeviceProfiles updates deviceProfiles on OpenYurt,
+// the managed ones only update their status while the others update their spec
+func (dps *DeviceProfileSyncer) updateDeviceProfiles(ctx context.Context, syncedDeviceProfiles map[string]*devicev1alpha1.DeviceProfile) error {
+	for _, sdp := range syncedDeviceProfiles {
+		var err error
+		if sdp.Spec.Managed {
+			err = dps.Client.Status().Update(ctx, sdp)
+		} else {
+			err = dps.Client.Update(ctx, sdp)
+		}
+		if err != nil {
+			if apierrors.IsConflict(err) {
+				klog.V(5).InfoS("update Conflicts", "DeviceProfile", sdp.Name)
+				continue
+			}
+			klog.V(5).ErrorS(err, "fail to update the DeviceProfile on Kubernetes",
+				"DeviceProfile", sdp.Name)
+			return err
+		}
+	}
+	return nil
+}
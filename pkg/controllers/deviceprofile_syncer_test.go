@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompleteUpdateContentPreservedAnnotations(t *testing.T) {
+	dps := &DeviceProfileSyncer{preservedAnnotations: []string{devicev1alpha1.EdgeXTypedAttributes, devicev1alpha1.EdgeXV3Fields}}
+	kubeDp := &devicev1alpha1.DeviceProfile{ObjectMeta: metav1.ObjectMeta{
+		Name: "hangzhou-modbus-device",
+		Annotations: map[string]string{
+			devicev1alpha1.EdgeXV3Fields: `{"tags":{"floor":1}}`,
+			"owner":                      "hangzhou",
+		},
+	}}
+	edgeDp := &devicev1alpha1.DeviceProfile{ObjectMeta: metav1.ObjectMeta{
+		Name: "modbus-device",
+		Annotations: map[string]string{
+			devicev1alpha1.EdgeXTypedAttributes: `{"Temperature":["startingAddress"]}`,
+			"edgex":                             "ignored",
+		},
+	}}
+
+	updated := dps.completeUpdateContent(kubeDp, edgeDp)
+	want := map[string]string{
+		devicev1alpha1.EdgeXTypedAttributes: `{"Temperature":["startingAddress"]}`,
+		"owner":                             "hangzhou",
+	}
+	if len(updated.Annotations) != len(want) {
+		t.Fatalf("expected the annotations %v, got %v", want, updated.Annotations)
+	}
+	for k, v := range want {
+		if updated.Annotations[k] != v {
+			t.Errorf("expected the annotations %v, got %v", want, updated.Annotations)
+		}
+	}
+}
@@ -24,9 +24,9 @@ import (
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
 	"github.com/openyurtio/device-controller/pkg/clients"
-	edgexCli "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -40,9 +40,12 @@ import (
 // DeviceServiceReconciler reconciles a DeviceService object
 type DeviceServiceReconciler struct {
 	client.Client
-	Scheme           *runtime.Scheme
-	deviceServiceCli clients.DeviceServiceInterface
+	Scheme *runtime.Scheme
+	// edge platform client, injected by the caller
+	DeviceServiceCli clients.DeviceServiceInterface
 	NodePool         string
+	// the deadlines of the operations on edge platform
+	timeouts edgeTimeouts
 }
 
 //+kubebuilder:rbac:groups=device.openyurt.io,resources=deviceservices,verbs=get;list;watch;create;update;patch;delete
@@ -67,7 +70,8 @@ func (r *DeviceServiceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 		conditions.SetSummary(&ds,
 			conditions.WithConditions(
-				devicev1alpha1.DeviceServiceSyncedCondition, devicev1alpha1.DeviceServiceManagingCondition),
+				devicev1alpha1.DeviceServiceSyncedCondition, devicev1alpha1.DeviceServiceManagingCondition,
+				devicev1alpha1.DeviceServiceAuthorizedCondition),
 		)
 		err := r.Status().Update(ctx, &ds)
 		if client.IgnoreNotFound(err) != nil {
@@ -79,7 +83,7 @@ func (r *DeviceServiceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	// 1. Handle the deviceService deletion event
 	if err := r.reconcileDeleteDeviceService(ctx, &ds); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return resultForObjectEdgeError(&ds, devicev1alpha1.DeviceServiceAuthorizedCondition, client.IgnoreNotFound(err))
 	} else if !ds.ObjectMeta.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
@@ -87,29 +91,25 @@ func (r *DeviceServiceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	if !ds.Status.Synced {
 		// 2. Synchronize OpenYurt deviceService to edge platform
 		if err := r.reconcileCreateDeviceService(ctx, &ds); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{Requeue: true}, nil
-			} else {
-				return ctrl.Result{}, err
-			}
+			return resultForObjectEdgeError(&ds, devicev1alpha1.DeviceServiceAuthorizedCondition, err)
 		}
 	} else if ds.Spec.Managed {
 		// 3. If the deviceService has been synchronized and is managed by the cloud, reconcile the deviceService fields
 		if err := r.reconcileUpdateDeviceService(ctx, &ds); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{Requeue: true}, nil
-			} else {
-				return ctrl.Result{}, err
-			}
+			return resultForObjectEdgeError(&ds, devicev1alpha1.DeviceServiceAuthorizedCondition, err)
 		}
 	}
+	markEdgeAuthorized(&ds, devicev1alpha1.DeviceServiceAuthorizedCondition, nil)
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DeviceServiceReconciler) SetupWithManager(mgr ctrl.Manager, opts *options.YurtDeviceControllerOptions) error {
-	r.deviceServiceCli = edgexCli.NewEdgexDeviceServiceClient(opts.CoreMetadataAddr)
+	if r.DeviceServiceCli == nil {
+		return fmt.Errorf("the edge platform client of deviceService reconciler is not set")
+	}
 	r.NodePool = opts.Nodepool
+	r.timeouts = newEdgeTimeouts(opts)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&devicev1alpha1.DeviceService{}).
@@ -150,7 +150,9 @@ func (r *DeviceServiceReconciler) reconcileDeleteDeviceService(ctx context.Conte
 		}
 
 		// delete the deviceService object on edge platform
-		err := r.deviceServiceCli.Delete(context.TODO(), edgeDeviceServiceName, clients.DeleteOptions{})
+		deleteCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		err := r.DeviceServiceCli.Delete(deleteCtx, edgeDeviceServiceName, clients.DeleteOptions{})
+		cancel()
 		if err != nil && !clients.IsNotFoundErr(err) {
 			return err
 		}
@@ -163,16 +165,29 @@ func (r *DeviceServiceReconciler) reconcileCreateDeviceService(ctx context.Conte
 	edgeDeviceServiceName := util.GetEdgeDeviceServiceName(ds, EdgeXObjectName)
 	klog.V(4).Infof("Checking if deviceService already exist on the edge platform: %s", ds.GetName())
 	// Checking if deviceService already exist on the edge platform
-	if edgeDs, err := r.deviceServiceCli.Get(context.TODO(), edgeDeviceServiceName, clients.GetOptions{}); err != nil {
+	getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	edgeDs, err := r.DeviceServiceCli.Get(getCtx, edgeDeviceServiceName, clients.GetOptions{})
+	cancel()
+	if err != nil {
 		if !clients.IsNotFoundErr(err) {
 			klog.V(4).ErrorS(err, "fail to visit the edge platform")
-			return nil
+			conditions.MarkFalse(ds, devicev1alpha1.DeviceServiceSyncedCondition, "failed to visit the EdgeX core-metadata-service", clusterv1.ConditionSeverityWarning, err.Error())
+			return err
 		} else {
-			createdDs, err := r.deviceServiceCli.Create(context.TODO(), ds, clients.CreateOptions{})
+			createCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+			createdDs, err := r.DeviceServiceCli.Create(createCtx, ds, clients.CreateOptions{})
+			cancel()
+			if clients.IsAlreadyExistsErr(err) {
+				// the deviceService has been added to the edge platform in the meantime
+				klog.V(4).Infof("DeviceServiceName: %s, obj already exists on edge platform", ds.GetName())
+				getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+				createdDs, err = r.DeviceServiceCli.Get(getCtx, edgeDeviceServiceName, clients.GetOptions{})
+				cancel()
+			}
 			if err != nil {
 				klog.V(4).ErrorS(err, "failed to create deviceService on edge platform")
 				conditions.MarkFalse(ds, devicev1alpha1.DeviceServiceSyncedCondition, "failed to add DeviceService to EdgeX", clusterv1.ConditionSeverityWarning, err.Error())
-				return fmt.Errorf("fail to add DeviceService to edge platform: %v", err)
+				return fmt.Errorf("fail to add DeviceService to edge platform: %w", err)
 			}
 
 			klog.V(4).Infof("Successfully add DeviceService to Edge Platform, Name: %s, EdgeId: %s", ds.GetName(), createdDs.Status.EdgeId)
@@ -191,23 +206,36 @@ func (r *DeviceServiceReconciler) reconcileCreateDeviceService(ctx context.Conte
 }
 
 func (r *DeviceServiceReconciler) reconcileUpdateDeviceService(ctx context.Context, ds *devicev1alpha1.DeviceService) error {
-	// 1. reconciling the AdminState field of deviceService
 	newDeviceServiceStatus := ds.Status.DeepCopy()
-	updateDeviceService := ds.DeepCopy()
-
-	if ds.Spec.AdminState != "" && ds.Spec.AdminState != ds.Status.AdminState {
-		newDeviceServiceStatus.AdminState = ds.Spec.AdminState
-	} else {
-		updateDeviceService.Spec.AdminState = ""
-	}
 
-	_, err := r.deviceServiceCli.Update(context.TODO(), updateDeviceService, clients.UpdateOptions{})
+	// 1. find the fields of deviceService which are different between OpenYurt and edge platform
+	klog.V(3).Infof("DeviceServiceName: %s, checking the fields of deviceService on edge platform", ds.GetName())
+	getCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+	edgeDs, err := r.DeviceServiceCli.Get(getCtx, util.GetEdgeDeviceServiceName(ds, EdgeXObjectName), clients.GetOptions{})
+	cancel()
 	if err != nil {
-		conditions.MarkFalse(ds, devicev1alpha1.DeviceServiceManagingCondition, "failed to update AdminState of deviceService on edge platform", clusterv1.ConditionSeverityWarning, err.Error())
+		conditions.MarkFalse(ds, devicev1alpha1.DeviceServiceManagingCondition, "failed to get deviceService from edge platform", clusterv1.ConditionSeverityWarning, err.Error())
 		return err
 	}
+	newDeviceServiceStatus.AdminState = edgeDs.Status.AdminState
+	driftFields := findDeviceServiceDrift(&ds.Spec, &edgeDs.Spec)
 
-	// 2. update the device status on OpenYurt
+	// 2. update the drifted fields of deviceService on edge platform, only the drifted fields are sent
+	if len(driftFields) != 0 {
+		klog.V(3).Infof("DeviceServiceName: %s, updating the fields %v of deviceService on edge platform", ds.GetName(), driftFields)
+		updateCtx, cancel := withTimeout(ctx, r.timeouts.metadata)
+		_, err := r.DeviceServiceCli.Update(updateCtx, ds, clients.UpdateOptions{UpdateFields: driftFields})
+		cancel()
+		if err != nil {
+			conditions.MarkFalse(ds, devicev1alpha1.DeviceServiceManagingCondition, fmt.Sprintf("failed to update the fields %v of deviceService on edge platform", driftFields), clusterv1.ConditionSeverityWarning, err.Error())
+			return err
+		}
+		if util.IsInStringLst(driftFields, "adminState") {
+			newDeviceServiceStatus.AdminState = ds.Spec.AdminState
+		}
+	}
+
+	// 3. update the deviceService status on OpenYurt
 	ds.Status = *newDeviceServiceStatus
 	if err = r.Status().Update(ctx, ds); err != nil {
 		conditions.MarkFalse(ds, devicev1alpha1.DeviceServiceManagingCondition, "failed to update status of deviceService on openyurt", clusterv1.ConditionSeverityWarning, err.Error())
@@ -216,3 +244,22 @@ func (r *DeviceServiceReconciler) reconcileUpdateDeviceService(ctx context.Conte
 	conditions.MarkTrue(ds, devicev1alpha1.DeviceServiceManagingCondition)
 	return nil
 }
+
+// findDeviceServiceDrift returns the json names of the spec fields whose values are different between OpenYurt and edge platform,
+// the fields that can not be emptied on edge platform are skipped when they are empty
+func findDeviceServiceDrift(kubeSpec, edgeSpec *devicev1alpha1.DeviceServiceSpec) []string {
+	var driftFields []string
+	if kubeSpec.BaseAddress != "" && kubeSpec.BaseAddress != edgeSpec.BaseAddress {
+		driftFields = append(driftFields, "baseAddress")
+	}
+	if kubeSpec.Description != edgeSpec.Description {
+		driftFields = append(driftFields, "description")
+	}
+	if !equality.Semantic.DeepEqual(kubeSpec.Labels, edgeSpec.Labels) {
+		driftFields = append(driftFields, "labels")
+	}
+	if kubeSpec.AdminState != "" && kubeSpec.AdminState != edgeSpec.AdminState {
+		driftFields = append(driftFields, "adminState")
+	}
+	return driftFields
+}
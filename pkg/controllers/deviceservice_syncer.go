@@ -24,9 +24,9 @@ import (
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
 	iotcli "github.com/openyurtio/device-controller/pkg/clients"
-	edgexCli "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 	"github.com/openyurtio/device-controller/pkg/controllers/util"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -41,109 +41,169 @@ type DeviceServiceSyncer struct {
 	deviceServiceCli iotcli.DeviceServiceInterface
 	NodePool         string
 	Namespace        string
+	// the deadline of listing the deviceServices on edge platform in a round of synchronization
+	syncTimeout time.Duration
+	// only the objects with these labels are synchronized
+	labelSelector map[string]string
 }
 
-func NewDeviceServiceSyncer(client client.Client, opts *options.YurtDeviceControllerOptions) (DeviceServiceSyncer, error) {
+// NewDeviceServiceSyncer initialize a New DeviceServiceSyncer
+func NewDeviceServiceSyncer(client client.Client, deviceServiceCli iotcli.DeviceServiceInterface, opts *options.YurtDeviceControllerOptions) (DeviceServiceSyncer, error) {
+	labelSelector, err := iotcli.ParseSelector(opts.EdgeSyncSelector)
+	if err != nil {
+		return DeviceServiceSyncer{}, err
+	}
 	return DeviceServiceSyncer{
 		syncPeriod:       time.Duration(opts.EdgeSyncPeriod) * time.Second,
-		deviceServiceCli: edgexCli.NewEdgexDeviceServiceClient(opts.CoreMetadataAddr),
+		syncTimeout:      time.Duration(opts.EdgeSyncTimeout) * time.Second,
+		deviceServiceCli: deviceServiceCli,
 		Client:           client,
 		NodePool:         opts.Nodepool,
 		Namespace:        opts.Namespace,
+		labelSelector:    labelSelector,
 	}, nil
 }
 
 func (ds *DeviceServiceSyncer) NewDeviceServiceSyncerRunnable() ctrlmgr.RunnableFunc {
 	return func(ctx context.Context) error {
-		ds.Run(ctx.Done())
+		ds.Run(ctx)
 		return nil
 	}
 }
 
-func (ds *DeviceServiceSyncer) Run(stop <-chan struct{}) {
+func (ds *DeviceServiceSyncer) Run(ctx context.Context) {
 	klog.V(1).Info("[DeviceService] Starting the syncer...")
 	go func() {
 		for {
-			<-time.After(ds.syncPeriod)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ds.syncPeriod):
+			}
 			klog.V(2).Info("[DeviceService] Start a round of synchronization.")
-			// 1. get deviceServices on edge platform and OpenYurt
-			edgeDeviceServices, kubeDeviceServices, err := ds.getAllDeviceServices()
+			// 1. create or update the deviceServices on OpenYurt according to edge platform page by page
+			edgeDeviceServiceNames, err := ds.syncEdgeDeviceServices(ctx)
 			if err != nil {
-				klog.V(3).ErrorS(err, "fail to list the deviceServices")
+				// the redundant deviceServices can not be found unless all the deviceServices on edge platform are listed,
+				// so none of them is deleted if the listing fails or is incomplete
+				klog.V(3).ErrorS(err, "fail to list the deviceServices on edge platform")
 				continue
 			}
 
-			// 2. find the deviceServices that need to be synchronized
-			redundantEdgeDeviceServices, redundantKubeDeviceServices, syncedDeviceServices :=
-				ds.findDiffDeviceServices(edgeDeviceServices, kubeDeviceServices)
-			klog.V(2).Infof("[DeviceService] The number of objects waiting for synchronization { %s:%d, %s:%d, %s:%d }",
-				"Edge deviceServices should be added to OpenYurt", len(redundantEdgeDeviceServices),
-				"OpenYurt deviceServices that should be deleted", len(redundantKubeDeviceServices),
-				"DeviceServices that should be synchronized", len(syncedDeviceServices))
-
-			// 3. create deviceServices on OpenYurt which are exists in edge platform but not in OpenYurt
-			if err := ds.syncEdgeToKube(redundantEdgeDeviceServices); err != nil {
-				klog.V(3).ErrorS(err, "fail to create deviceServices on OpenYurt")
+			// 2. get deviceServices on OpenYurt
+			kubeDeviceServices, err := ds.getKubeDeviceServices(ctx)
+			if err != nil {
+				klog.V(3).ErrorS(err, "fail to list the deviceServices")
+				continue
 			}
 
-			// 4. delete redundant deviceServices on OpenYurt
-			if err := ds.deleteDeviceServices(redundantKubeDeviceServices); err != nil {
+			// 3. delete redundant deviceServices on OpenYurt
+			redundantKubeDeviceServices := ds.findRedundantKubeDeviceServices(kubeDeviceServices, edgeDeviceServiceNames)
+			klog.V(2).Infof("[DeviceService] The number of objects waiting for synchronization { %s:%d }",
+				"OpenYurt deviceServices that should be deleted", len(redundantKubeDeviceServices))
+			if err := ds.deleteDeviceServices(ctx, redundantKubeDeviceServices); err != nil {
 				klog.V(3).ErrorS(err, "fail to delete redundant deviceServices on OpenYurt")
 			}
-
-			// 5. update deviceService status on OpenYurt
-			if err := ds.updateDeviceServices(syncedDeviceServices); err != nil {
-				klog.V(3).ErrorS(err, "fail to update deviceServices")
-			}
 			klog.V(2).Info("[DeviceService] One round of synchronization is complete")
 		}
 	}()
 
-	<-stop
+	<-ctx.Done()
 	klog.V(1).Info("[DeviceService] Stopping the syncer")
 }
 
-// Get the existing DeviceService on the Edge platform, as well as OpenYurt existing DeviceService
-// edgeDeviceServices：map[actualName]DeviceService
+// Get the existing DeviceService on OpenYurt
 // kubeDeviceServices：map[actualName]DeviceService
-func (ds *DeviceServiceSyncer) getAllDeviceServices() (
-	map[string]devicev1alpha1.DeviceService, map[string]devicev1alpha1.DeviceService, error) {
-
-	edgeDeviceServices := map[string]devicev1alpha1.DeviceService{}
+func (ds *DeviceServiceSyncer) getKubeDeviceServices(ctx context.Context) (map[string]devicev1alpha1.DeviceService, error) {
 	kubeDeviceServices := map[string]devicev1alpha1.DeviceService{}
-
-	// 1. list deviceServices on edge platform
-	eDevSs, err := ds.deviceServiceCli.List(context.TODO(), iotcli.ListOptions{})
-	if err != nil {
-		klog.V(4).ErrorS(err, "fail to list the deviceServices object on the edge platform")
-		return edgeDeviceServices, kubeDeviceServices, err
-	}
-	// 2. list deviceServices on OpenYurt (filter objects belonging to edgeServer)
+	// list deviceServices on OpenYurt (filter objects belonging to edgeServer)
 	var kDevSs devicev1alpha1.DeviceServiceList
 	listOptions := client.MatchingFields{util.IndexerPathForNodepool: ds.NodePool}
-	if err = ds.List(context.TODO(), &kDevSs, listOptions, client.InNamespace(ds.Namespace)); err != nil {
+	if err := ds.List(ctx, &kDevSs, listOptions, client.InNamespace(ds.Namespace)); err != nil {
 		klog.V(4).ErrorS(err, "fail to list the deviceServices object on the Kubernetes")
-		return edgeDeviceServices, kubeDeviceServices, err
-	}
-	for i := range eDevSs {
-		deviceServicesName := util.GetEdgeDeviceServiceName(&eDevSs[i], EdgeXObjectName)
-		edgeDeviceServices[deviceServicesName] = eDevSs[i]
+		return kubeDeviceServices, err
 	}
-
 	for i := range kDevSs.Items {
+		// the objects which are not selected are left alone
+		if !iotcli.LabelsMatch(kDevSs.Items[i].Spec.Labels, ds.labelSelector) {
+			continue
+		}
 		deviceServicesName := util.GetEdgeDeviceServiceName(&kDevSs.Items[i], EdgeXObjectName)
 		kubeDeviceServices[deviceServicesName] = kDevSs.Items[i]
 	}
-	return edgeDeviceServices, kubeDeviceServices, nil
+	return kubeDeviceServices, nil
+}
+
+// getKubePageDeviceServices looks up the deviceServices on OpenYurt of a page of edge platform by their actual names,
+// kubeDeviceServices：map[actualName]DeviceService
+func (ds *DeviceServiceSyncer) getKubePageDeviceServices(ctx context.Context, edgeDeviceServices []devicev1alpha1.DeviceService) (map[string]devicev1alpha1.DeviceService, error) {
+	kubeDeviceServices := map[string]devicev1alpha1.DeviceService{}
+	for i := range edgeDeviceServices {
+		deviceServiceName := util.GetEdgeDeviceServiceName(&edgeDeviceServices[i], EdgeXObjectName)
+		var kDevSs devicev1alpha1.DeviceServiceList
+		listOptions := client.MatchingFields{util.IndexerPathForEdgeName: util.EdgeNameIndexValue(ds.NodePool, deviceServiceName)}
+		if err := ds.List(ctx, &kDevSs, listOptions, client.InNamespace(ds.Namespace)); err != nil {
+			klog.V(4).ErrorS(err, "fail to look up the deviceService object on the Kubernetes", "DeviceService", deviceServiceName)
+			return kubeDeviceServices, err
+		}
+		for j := range kDevSs.Items {
+			// the objects which are not selected are left alone, the index is matched exactly by the cache,
+			// while the checks keep the lookup right with the readers which ignore the field selectors
+			if kDevSs.Items[j].Spec.NodePool != ds.NodePool || util.GetEdgeDeviceServiceName(&kDevSs.Items[j], EdgeXObjectName) != deviceServiceName || !iotcli.LabelsMatch(kDevSs.Items[j].Spec.Labels, ds.labelSelector) {
+				continue
+			}
+			kubeDeviceServices[deviceServiceName] = kDevSs.Items[j]
+		}
+	}
+	return kubeDeviceServices, nil
 }
 
-// Get the list of deviceServices that need to be added, deleted and updated
+// syncEdgeDeviceServices walks through the deviceServices on edge platform page by page, the deviceServices that do not exist
+// on OpenYurt are created and the others are updated. Only the deviceServices of the page are looked up on OpenYurt, while the
+// actual names of all the deviceServices on edge platform are returned to find the redundant deviceServices on OpenYurt.
+func (ds *DeviceServiceSyncer) syncEdgeDeviceServices(ctx context.Context) (map[string]struct{}, error) {
+	edgeDeviceServiceNames := map[string]struct{}{}
+	listCtx, cancel := withTimeout(ctx, ds.syncTimeout)
+	defer cancel()
+	err := ds.deviceServiceCli.ListPages(listCtx, iotcli.ListOptions{LabelSelector: ds.labelSelector}, func(edgeDeviceServices []devicev1alpha1.DeviceService) error {
+		for i := range edgeDeviceServices {
+			edgeDeviceServiceNames[util.GetEdgeDeviceServiceName(&edgeDeviceServices[i], EdgeXObjectName)] = struct{}{}
+		}
+
+		// a. find the deviceServices of this page that need to be synchronized
+		kubeDeviceServices, err := ds.getKubePageDeviceServices(ctx, edgeDeviceServices)
+		if err != nil {
+			return err
+		}
+		redundantEdgeDeviceServices, syncedDeviceServices := ds.findDiffDeviceServices(edgeDeviceServices, kubeDeviceServices)
+		klog.V(2).Infof("[DeviceService] The number of objects waiting for synchronization { %s:%d, %s:%d }",
+			"Edge deviceServices should be added to OpenYurt", len(redundantEdgeDeviceServices),
+			"DeviceServices that should be synchronized", len(syncedDeviceServices))
+
+		// b. create deviceServices on OpenYurt which are exists in edge platform but not in OpenYurt
+		if err := ds.syncEdgeToKube(ctx, redundantEdgeDeviceServices); err != nil {
+			klog.V(3).ErrorS(err, "fail to create deviceServices on OpenYurt")
+		}
+
+		// c. update deviceService status on OpenYurt
+		if err := ds.updateDeviceServices(ctx, kubeDeviceServices, syncedDeviceServices); err != nil {
+			klog.V(3).ErrorS(err, "fail to update deviceServices")
+		}
+		return nil
+	})
+	if err != nil {
+		klog.V(4).ErrorS(err, "fail to list the deviceServices object on the edge platform")
+	}
+	return edgeDeviceServiceNames, err
+}
+
+// Get the list of deviceServices in a page of edge platform that need to be added and updated,
+// the deviceServices on OpenYurt which are already the same as edge platform are not updated
 func (ds *DeviceServiceSyncer) findDiffDeviceServices(
-	edgeDeviceService map[string]devicev1alpha1.DeviceService, kubeDeviceService map[string]devicev1alpha1.DeviceService) (
-	redundantEdgeDeviceServices map[string]*devicev1alpha1.DeviceService, redundantKubeDeviceServices map[string]*devicev1alpha1.DeviceService, syncedDeviceServices map[string]*devicev1alpha1.DeviceService) {
+	edgeDeviceService []devicev1alpha1.DeviceService, kubeDeviceService map[string]devicev1alpha1.DeviceService) (
+	redundantEdgeDeviceServices map[string]*devicev1alpha1.DeviceService, syncedDeviceServices map[string]*devicev1alpha1.DeviceService) {
 
 	redundantEdgeDeviceServices = map[string]*devicev1alpha1.DeviceService{}
-	redundantKubeDeviceServices = map[string]*devicev1alpha1.DeviceService{}
 	syncedDeviceServices = map[string]*devicev1alpha1.DeviceService{}
 
 	for i := range edgeDeviceService {
@@ -153,27 +213,36 @@ func (ds *DeviceServiceSyncer) findDiffDeviceServices(
 			redundantEdgeDeviceServices[edName] = ds.completeCreateContent(&eds)
 		} else {
 			kd := kubeDeviceService[edName]
-			syncedDeviceServices[edName] = ds.completeUpdateContent(&kd, &eds)
+			updatedDS := ds.completeUpdateContent(&kd, &eds)
+			if !equality.Semantic.DeepEqual(kd.Spec, updatedDS.Spec) || !equality.Semantic.DeepEqual(kd.Status, updatedDS.Status) {
+				syncedDeviceServices[edName] = updatedDS
+			}
 		}
 	}
+	return
+}
 
+// Get the list of deviceServices on OpenYurt that do not exist on edge platform any more
+func (ds *DeviceServiceSyncer) findRedundantKubeDeviceServices(
+	kubeDeviceService map[string]devicev1alpha1.DeviceService, edgeDeviceServiceNames map[string]struct{}) map[string]*devicev1alpha1.DeviceService {
+	redundantKubeDeviceServices := map[string]*devicev1alpha1.DeviceService{}
 	for i := range kubeDeviceService {
 		kds := kubeDeviceService[i]
 		if !kds.Status.Synced {
 			continue
 		}
 		kdName := util.GetEdgeDeviceServiceName(&kds, EdgeXObjectName)
-		if _, exists := edgeDeviceService[kdName]; !exists {
+		if _, exists := edgeDeviceServiceNames[kdName]; !exists {
 			redundantKubeDeviceServices[kdName] = &kds
 		}
 	}
-	return
+	return redundantKubeDeviceServices
 }
 
 // syncEdgeToKube creates deviceServices on OpenYurt which are exists in edge platform but not in OpenYurt
-func (ds *DeviceServiceSyncer) syncEdgeToKube(edgeDevs map[string]*devicev1alpha1.DeviceService) error {
+func (ds *DeviceServiceSyncer) syncEdgeToKube(ctx context.Context, edgeDevs map[string]*devicev1alpha1.DeviceService) error {
 	for _, ed := range edgeDevs {
-		if err := ds.Client.Create(context.TODO(), ed); err != nil {
+		if err := ds.Client.Create(ctx, ed); err != nil {
 			if apierrors.IsAlreadyExists(err) {
 				klog.V(5).InfoS("DeviceService already exist on Kubernetes",
 					"DeviceService", strings.ToLower(ed.Name))
@@ -187,9 +256,9 @@ func (ds *DeviceServiceSyncer) syncEdgeToKube(edgeDevs map[string]*devicev1alpha
 }
 
 // deleteDeviceServices deletes redundant deviceServices on OpenYurt
-func (ds *DeviceServiceSyncer) deleteDeviceServices(redundantKubeDeviceServices map[string]*devicev1alpha1.DeviceService) error {
+func (ds *DeviceServiceSyncer) deleteDeviceServices(ctx context.Context, redundantKubeDeviceServices map[string]*devicev1alpha1.DeviceService) error {
 	for _, kds := range redundantKubeDeviceServices {
-		if err := ds.Client.Delete(context.TODO(), kds); err != nil {
+		if err := ds.Client.Delete(ctx, kds); err != nil {
 			klog.V(5).ErrorS(err, "fail to delete the DeviceService on Kubernetes",
 				"DeviceService", kds.Name)
 			return err
@@ -198,13 +267,33 @@ func (ds *DeviceServiceSyncer) deleteDeviceServices(redundantKubeDeviceServices
 	return nil
 }
 
-// updateDeviceServices updates deviceServices status on OpenYurt
-func (ds *DeviceServiceSyncer) updateDeviceServices(syncedDeviceServices map[string]*devicev1alpha1.DeviceService) error {
-	for _, sd := range syncedDeviceServices {
+// updateDeviceServices updates deviceServices on OpenYurt, only the spec or the status which differs from
+// the one in kubeDeviceServices is updated, and the spec is only updated for the deviceServices which are not managed by cloud
+func (ds *DeviceServiceSyncer) updateDeviceServices(ctx context.Context, kubeDeviceServices map[string]devicev1alpha1.DeviceService,
+	syncedDeviceServices map[string]*devicev1alpha1.DeviceService) error {
+	for name, sd := range syncedDeviceServices {
 		if sd.ObjectMeta.ResourceVersion == "" {
 			continue
 		}
-		if err := ds.Client.Status().Update(context.TODO(), sd); err != nil {
+		kds := kubeDeviceServices[name]
+		if !sd.Spec.Managed && !equality.Semantic.DeepEqual(kds.Spec, sd.Spec) {
+			// the status is overwritten by the response of updating the spec, so keep it in advance
+			status := sd.Status.DeepCopy()
+			if err := ds.Client.Update(ctx, sd); err != nil {
+				if apierrors.IsConflict(err) {
+					klog.V(5).InfoS("update Conflicts", "DeviceService", sd.Name)
+					continue
+				}
+				klog.V(5).ErrorS(err, "fail to update the DeviceService on Kubernetes",
+					"DeviceService", sd.Name)
+				return err
+			}
+			sd.Status = *status
+		}
+		if equality.Semantic.DeepEqual(kds.Status, sd.Status) {
+			continue
+		}
+		if err := ds.Client.Status().Update(ctx, sd); err != nil {
 			if apierrors.IsConflict(err) {
 				klog.V(5).InfoS("update Conflicts", "DeviceService", sd.Name)
 				continue
@@ -234,5 +323,13 @@ func (ds *DeviceServiceSyncer) completeUpdateContent(kubeDS *devicev1alpha1.Devi
 	updatedDS.Status.LastConnected = edgeDS.Status.LastConnected
 	updatedDS.Status.LastReported = edgeDS.Status.LastReported
 	updatedDS.Status.AdminState = edgeDS.Status.AdminState
+	// the deviceService managed by cloud is corrected by the deviceService reconciler,
+	// otherwise the changes made on edge platform are mirrored to OpenYurt
+	if !updatedDS.Spec.Managed {
+		updatedDS.Spec.BaseAddress = edgeDS.Spec.BaseAddress
+		updatedDS.Spec.Description = edgeDS.Spec.Description
+		updatedDS.Spec.Labels = edgeDS.Spec.Labels
+		updatedDS.Spec.AdminState = edgeDS.Spec.AdminState
+	}
 	return updatedDS
 }
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newFakeClient returns a fake Kubernetes client which knows the device types and the core types
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := devicev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add the device types to scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add the core types to scheme: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestUpdateDeviceServicesSkipsUnchanged(t *testing.T) {
+	kds := &devicev1alpha1.DeviceService{
+		ObjectMeta: metav1.ObjectMeta{Name: "hangzhou-device-virtual", Namespace: "default"},
+		Spec:       devicev1alpha1.DeviceServiceSpec{BaseAddress: "http://edgex-device-virtual:59900", NodePool: "hangzhou"},
+		Status:     devicev1alpha1.DeviceServiceStatus{Synced: true, AdminState: devicev1alpha1.UnLocked},
+	}
+	ds := &DeviceServiceSyncer{Client: newFakeClient(t, kds), NodePool: "hangzhou", Namespace: "default"}
+	key := types.NamespacedName{Name: kds.Name, Namespace: kds.Namespace}
+	var current devicev1alpha1.DeviceService
+	if err := ds.Get(context.TODO(), key, &current); err != nil {
+		t.Fatal(err)
+	}
+	kubeDeviceServices := map[string]devicev1alpha1.DeviceService{"device-virtual": current}
+
+	edgeDS := devicev1alpha1.DeviceService{
+		ObjectMeta: metav1.ObjectMeta{Name: "device-virtual"},
+		Spec:       devicev1alpha1.DeviceServiceSpec{BaseAddress: "http://edgex-device-virtual:59900"},
+		Status:     devicev1alpha1.DeviceServiceStatus{AdminState: devicev1alpha1.UnLocked},
+	}
+	_, synced := ds.findDiffDeviceServices([]devicev1alpha1.DeviceService{edgeDS}, kubeDeviceServices)
+	if len(synced) != 0 {
+		t.Fatalf("expected the unchanged deviceService not to be updated, got %v", synced)
+	}
+
+	edgeDS.Spec.BaseAddress = "http://edgex-device-virtual:59910"
+	_, synced = ds.findDiffDeviceServices([]devicev1alpha1.DeviceService{edgeDS}, kubeDeviceServices)
+	if len(synced) != 1 {
+		t.Fatalf("expected the changed deviceService to be updated, got %v", synced)
+	}
+	if err := ds.updateDeviceServices(context.TODO(), kubeDeviceServices, synced); err != nil {
+		t.Fatal(err)
+	}
+	var updated devicev1alpha1.DeviceService
+	if err := ds.Get(context.TODO(), key, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Spec.BaseAddress != edgeDS.Spec.BaseAddress {
+		t.Errorf("expected the baseAddress %s to be mirrored, got %s", edgeDS.Spec.BaseAddress, updated.Spec.BaseAddress)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// defaultEdgeUnavailableRequeueAfter is how long the reconcilers wait before retrying
+// when the edge platform can not be reached and it does not suggest a retry interval
+const defaultEdgeUnavailableRequeueAfter = 10 * time.Second
+
+// resultForEdgeError converts the error returned by a reconcile step into the result of Reconcile.
+// While the edge platform is unavailable, the object is requeued after an explicit interval
+// instead of being retried by the rate limiter of the work queue.
+func resultForEdgeError(err error) (ctrl.Result, error) {
+	switch {
+	case err == nil:
+		return ctrl.Result{}, nil
+	case apierrors.IsConflict(err):
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if d, ok := clients.SuggestsRetryAfter(err); ok {
+		klog.V(4).InfoS("the edge platform is unavailable, requeue later", "RequeueAfter", d, "Error", err.Error())
+		return ctrl.Result{RequeueAfter: d}, nil
+	}
+	if clients.IsUnavailableErr(err) {
+		klog.V(4).InfoS("the edge platform is unavailable, requeue later", "RequeueAfter", defaultEdgeUnavailableRequeueAfter, "Error", err.Error())
+		return ctrl.Result{RequeueAfter: defaultEdgeUnavailableRequeueAfter}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// resultForObjectEdgeError is resultForEdgeError which also records on the authorized condition of the object
+// whether the edge platform has rejected the credentials of the controller
+func resultForObjectEdgeError(obj conditions.Setter, authorized clusterv1.ConditionType, err error) (ctrl.Result, error) {
+	markEdgeAuthorized(obj, authorized, err)
+	return resultForEdgeError(err)
+}
+
+// markEdgeAuthorized marks the authorized condition false if the edge platform rejects the credentials or doesn't
+// permit the request, the condition is only set after a rejection, and is marked true again once the reconcile steps succeed
+func markEdgeAuthorized(obj conditions.Setter, authorized clusterv1.ConditionType, err error) {
+	switch {
+	case clients.IsUnauthorizedErr(err), clients.IsForbiddenErr(err):
+		conditions.MarkFalse(obj, authorized, string(clients.ReasonForError(err)), clusterv1.ConditionSeverityError, "%s", err.Error())
+	case err == nil && conditions.Has(obj, authorized):
+		conditions.MarkTrue(obj, authorized)
+	}
+}
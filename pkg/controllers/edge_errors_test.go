@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestMarkEdgeAuthorized(t *testing.T) {
+	d := &devicev1alpha1.Device{}
+	markEdgeAuthorized(d, devicev1alpha1.DeviceAuthorizedCondition, nil)
+	if conditions.Has(d, devicev1alpha1.DeviceAuthorizedCondition) {
+		t.Fatalf("expected no authorized condition before any request is rejected")
+	}
+
+	markEdgeAuthorized(d, devicev1alpha1.DeviceAuthorizedCondition, clients.NewUnavailableError("edgex is down", nil))
+	if conditions.Has(d, devicev1alpha1.DeviceAuthorizedCondition) {
+		t.Fatalf("expected no authorized condition for the unavailable edge platform")
+	}
+
+	rejected := clients.NewStatusError(clients.ReasonForStatusCode(http.StatusUnauthorized), http.StatusUnauthorized, "failed to get device", nil)
+	result, err := resultForObjectEdgeError(d, devicev1alpha1.DeviceAuthorizedCondition, rejected)
+	if err == nil || result.RequeueAfter != 0 {
+		t.Errorf("expected the rejection to be returned, got %v %v", result, err)
+	}
+	if !conditions.IsFalse(d, devicev1alpha1.DeviceAuthorizedCondition) ||
+		conditions.GetReason(d, devicev1alpha1.DeviceAuthorizedCondition) != string(clients.StatusReasonUnauthorized) {
+		t.Fatalf("expected the authorized condition to be false, got %v", conditions.Get(d, devicev1alpha1.DeviceAuthorizedCondition))
+	}
+
+	// the request which is not permitted is told apart from the rejected credentials
+	forbidden := clients.NewStatusError(clients.ReasonForStatusCode(http.StatusForbidden), http.StatusForbidden, "failed to get device", nil)
+	markEdgeAuthorized(d, devicev1alpha1.DeviceAuthorizedCondition, forbidden)
+	if !conditions.IsFalse(d, devicev1alpha1.DeviceAuthorizedCondition) ||
+		conditions.GetReason(d, devicev1alpha1.DeviceAuthorizedCondition) != string(clients.StatusReasonForbidden) {
+		t.Fatalf("expected the authorized condition to be false as forbidden, got %v", conditions.Get(d, devicev1alpha1.DeviceAuthorizedCondition))
+	}
+
+	markEdgeAuthorized(d, devicev1alpha1.DeviceAuthorizedCondition, nil)
+	if !conditions.IsTrue(d, devicev1alpha1.DeviceAuthorizedCondition) {
+		t.Errorf("expected the authorized condition to be true once the reconcile succeeds")
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
+)
+
+// edgeTimeouts are the deadlines of the kinds of operations on edge platform, 0 means no deadline.
+// A deadline covers the whole operation, including the retries of its requests
+type edgeTimeouts struct {
+	// metadata is the deadline of creating, getting, updating or deleting an object
+	metadata time.Duration
+	// command is the deadline of reading or setting the properties of a device
+	command time.Duration
+	// sync is the deadline of listing the objects in a synchronization
+	sync time.Duration
+}
+
+func newEdgeTimeouts(opts *options.YurtDeviceControllerOptions) edgeTimeouts {
+	return edgeTimeouts{
+		metadata: time.Duration(opts.EdgeMetadataTimeout) * time.Second,
+		command:  time.Duration(opts.EdgeCommandTimeout) * time.Second,
+		sync:     time.Duration(opts.EdgeSyncTimeout) * time.Second,
+	}
+}
+
+// withTimeout returns the context of an operation which is canceled once the timeout elapses,
+// the context only inherits the deadline of ctx if the timeout is 0
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
+)
+
+func TestNewEdgeTimeouts(t *testing.T) {
+	opts := options.NewYurtDeviceControllerOptions()
+	opts.EdgeMetadataTimeout, opts.EdgeCommandTimeout, opts.EdgeSyncTimeout = 5, 15, 0
+	timeouts := newEdgeTimeouts(opts)
+	if timeouts.metadata != 5*time.Second || timeouts.command != 15*time.Second || timeouts.sync != 0 {
+		t.Errorf("unexpected timeouts %+v", timeouts)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := withTimeout(context.Background(), time.Minute)
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("expected the operation to be due within a minute, got %v", deadline)
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("expected the operation to be canceled")
+	}
+
+	// the operation without a timeout only inherits the deadline of the caller
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+	ctx, cancel = withTimeout(parent, 0)
+	defer cancel()
+	parentDeadline, _ := parent.Deadline()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("expected the deadline %v of the caller, got %v", parentDeadline, deadline)
+	}
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
+	edgeCli "github.com/openyurtio/device-controller/pkg/clients"
+	"github.com/openyurtio/device-controller/pkg/controllers/util"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmgr "sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// propertyPollTick is the resolution of the poll schedule
+	propertyPollTick = time.Second
+	// propertyPollJitterFactor spreads the polls of the devices with the same interval over time
+	propertyPollJitterFactor = 0.1
+)
+
+// PropertyPoller polls the actual properties of the devices on the edge platform and records them in the device status.
+// Every device is polled with its own interval, the number of requests sent per second and the number of devices
+// polled concurrently are limited, so that the physical devices are not overwhelmed by the polls.
+type PropertyPoller struct {
+	// kubernetes client
+	client.Client
+	// which nodePool deviceController is deployed in
+	NodePool  string
+	Namespace string
+	// edge platform's client
+	deviceCli edgeCli.DeviceInterface
+	// the poll interval of the devices without the poll-interval annotation
+	defaultInterval time.Duration
+	// the deadline of reading the properties of a device
+	commandTimeout time.Duration
+	// the global budget of the requests sent to the devices
+	limiter *rate.Limiter
+	// the slots of the devices that are allowed to be polled concurrently
+	slots chan struct{}
+	// only the devices with these labels are polled
+	labelSelector map[string]string
+	// stores the binary values read from the devices
+	binaryValues *binaryValueStore
+
+	mu sync.Mutex
+	// nextPoll records when the devices and the properties with their own intervals are due to be polled
+	nextPoll map[string]time.Time
+	// polling records the devices that are being polled
+	polling map[types.NamespacedName]struct{}
+}
+
+// devicePoll is the work of polling a device, either all its properties or some of them are polled
+type devicePoll struct {
+	device     devicev1alpha1.Device
+	all        bool
+	properties []string
+}
+
+// NewPropertyPoller initialize a New PropertyPoller
+func NewPropertyPoller(client client.Client, deviceCli edgeCli.DeviceInterface, opts *options.YurtDeviceControllerOptions) (*PropertyPoller, error) {
+	labelSelector, err := edgeCli.ParseSelector(opts.EdgeSyncSelector)
+	if err != nil {
+		return nil, err
+	}
+	limit, burst := rate.Inf, 1
+	if opts.PropertyPollQPS > 0 {
+		limit = rate.Limit(opts.PropertyPollQPS)
+		if opts.PropertyPollQPS > 1 {
+			burst = int(opts.PropertyPollQPS)
+		}
+	}
+	concurrency := int(opts.ConcurrentPropertyPolls)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &PropertyPoller{
+		Client:          client,
+		NodePool:        opts.Nodepool,
+		Namespace:       opts.Namespace,
+		deviceCli:       deviceCli,
+		defaultInterval: time.Duration(opts.PropertyPollPeriod) * time.Second,
+		commandTimeout:  time.Duration(opts.EdgeCommandTimeout) * time.Second,
+		limiter:         rate.NewLimiter(limit, burst),
+		slots:           make(chan struct{}, concurrency),
+		labelSelector:   labelSelector,
+		binaryValues:    newBinaryValueStore(client, opts),
+		nextPoll:        map[string]time.Time{},
+		polling:         map[types.NamespacedName]struct{}{},
+	}, nil
+}
+
+// NewPropertyPollerRunnable initialize a controller-runtime manager runnable
+func (pp *PropertyPoller) NewPropertyPollerRunnable() ctrlmgr.RunnableFunc {
+	return func(ctx context.Context) error {
+		pp.Run(ctx)
+		return nil
+	}
+}
+
+func (pp *PropertyPoller) Run(ctx context.Context) {
+	klog.V(1).Info("[Property] Starting the poller...")
+	wait.Until(func() {
+		polls, err := pp.schedule(ctx, time.Now())
+		if err != nil {
+			klog.V(3).ErrorS(err, "fail to schedule the property polls")
+			return
+		}
+		for i := range polls {
+			go pp.poll(ctx, polls[i])
+		}
+	}, propertyPollTick, ctx.Done())
+	klog.V(1).Info("[Property] Stopping the poller")
+}
+
+// schedule returns the devices that are due to be polled at now, and moves their next polls forward
+func (pp *PropertyPoller) schedule(ctx context.Context, now time.Time) ([]*devicePoll, error) {
+	var kDevs devicev1alpha1.DeviceList
+	listOptions := client.MatchingFields{util.IndexerPathForNodepool: pp.NodePool}
+	if err := pp.List(ctx, &kDevs, listOptions, client.InNamespace(pp.Namespace)); err != nil {
+		return nil, err
+	}
+
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	var polls []*devicePoll
+	seen := map[string]struct{}{}
+	for i := range kDevs.Items {
+		d := &kDevs.Items[i]
+		// the devices which are not added to the edge platform yet can not be polled
+		if !d.Status.Synced || !d.DeletionTimestamp.IsZero() || !edgeCli.LabelsMatch(d.Spec.Labels, pp.labelSelector) {
+			continue
+		}
+		key := types.NamespacedName{Namespace: d.Namespace, Name: d.Name}
+		interval, propertyIntervals := pollIntervals(d, pp.defaultInterval)
+		if interval > 0 {
+			seen[key.String()] = struct{}{}
+		}
+		for name := range propertyIntervals {
+			seen[key.String()+"/"+name] = struct{}{}
+		}
+		// the schedule of the device is kept while the last poll is not finished yet,
+		// so that the device is polled at the next tick after the last poll finishes
+		if _, exists := pp.polling[key]; exists {
+			continue
+		}
+		p := &devicePoll{device: *d}
+		if interval > 0 {
+			p.all = pp.due(key.String(), interval, now)
+		}
+		for name, propertyInterval := range propertyIntervals {
+			if !p.all && pp.due(key.String()+"/"+name, propertyInterval, now) {
+				p.properties = append(p.properties, name)
+			}
+		}
+		if !p.all && len(p.properties) == 0 {
+			continue
+		}
+		pp.polling[key] = struct{}{}
+		polls = append(polls, p)
+	}
+	// forget the schedule of the devices that are deleted or not polled any more
+	for key := range pp.nextPoll {
+		if _, exists := seen[key]; !exists {
+			delete(pp.nextPoll, key)
+		}
+	}
+	return polls, nil
+}
+
+// due returns whether the poll identified by key is due at now, the next poll is scheduled if it is,
+// it must be called with the lock held
+func (pp *PropertyPoller) due(key string, interval time.Duration, now time.Time) bool {
+	next, exists := pp.nextPoll[key]
+	if exists && now.Before(next) {
+		return false
+	}
+	pp.nextPoll[key] = now.Add(wait.Jitter(interval, propertyPollJitterFactor))
+	return true
+}
+
+// poll gets the actual properties of the device from the edge platform and records them in the device status
+func (pp *PropertyPoller) poll(ctx context.Context, p *devicePoll) {
+	key := types.NamespacedName{Namespace: p.device.Namespace, Name: p.device.Name}
+	defer func() {
+		pp.mu.Lock()
+		delete(pp.polling, key)
+		pp.mu.Unlock()
+	}()
+	select {
+	case pp.slots <- struct{}{}:
+		defer func() { <-pp.slots }()
+	case <-ctx.Done():
+		return
+	}
+
+	actualProperties := map[string]devicev1alpha1.ActualPropertyState{}
+	if p.all {
+		readCtx, cancel := pp.readContext(ctx)
+		_, aps, err := pp.deviceCli.ListPropertiesState(readCtx, &p.device, listReadOptions(&p.device))
+		cancel()
+		if err != nil {
+			klog.V(4).ErrorS(err, "fail to poll the properties of device", "Device", key)
+			return
+		}
+		actualProperties = aps
+	}
+	for _, name := range p.properties {
+		if ctx.Err() != nil {
+			return
+		}
+		readCtx, cancel := pp.readContext(ctx)
+		aps, err := pp.deviceCli.GetPropertyState(readCtx, name, &p.device, edgeCli.GetOptions{ReadOptions: propertyReadOptions(&p.device, name)})
+		cancel()
+		if err != nil {
+			klog.V(4).ErrorS(err, "fail to poll the property of device", "Device", key, "Property", name)
+			continue
+		}
+		actualProperties[name] = *aps
+	}
+	if len(actualProperties) == 0 && !p.all {
+		return
+	}
+	pp.binaryValues.storeValues(ctx, &p.device, actualProperties)
+	if err := pp.updateActualProperties(ctx, key, actualProperties, p.all); err != nil {
+		klog.V(4).ErrorS(err, "fail to update the actual properties of device", "Device", key)
+	}
+}
+
+// readContext returns the context of reading the properties of a device, every request sent to the edge platform
+// with it takes a token of the global budget, and the deadline of the read includes the waits for the tokens
+func (pp *PropertyPoller) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	readCtx, cancel := withTimeout(ctx, pp.commandTimeout)
+	return edgeCli.WithRequestLimiter(readCtx, pp.limiter), cancel
+}
+
+// updateActualProperties records the polled properties in the status of the device, the properties polled
+// before are replaced if all the properties of the device are polled, and the binary values which are no
+// longer referred to by the status are deleted
+func (pp *PropertyPoller) updateActualProperties(ctx context.Context, key types.NamespacedName,
+	actualProperties map[string]devicev1alpha1.ActualPropertyState, all bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var d devicev1alpha1.Device
+		if err := pp.Get(ctx, key, &d); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		deviceProperties := map[string]devicev1alpha1.ActualPropertyState{}
+		if !all {
+			for name, aps := range d.Status.DeviceProperties {
+				deviceProperties[name] = aps
+			}
+		}
+		for name, aps := range actualProperties {
+			keepReadingTimestamp(d.Status.DeviceProperties, &aps)
+			deviceProperties[name] = aps
+		}
+		if equality.Semantic.DeepEqual(d.Status.DeviceProperties, deviceProperties) {
+			return nil
+		}
+		oldProperties := d.Status.DeviceProperties
+		d.Status.DeviceProperties = deviceProperties
+		if err := pp.Status().Update(ctx, &d); err != nil {
+			return err
+		}
+		pp.binaryValues.deleteValues(ctx, &d, oldProperties, deviceProperties)
+		return nil
+	})
+}
+
+// pollIntervals returns the poll interval of the device and the intervals of the properties that are polled
+// on their own, the invalid annotations are ignored
+func pollIntervals(d *devicev1alpha1.Device, defaultInterval time.Duration) (time.Duration, map[string]time.Duration) {
+	interval := defaultInterval
+	propertyIntervals := map[string]time.Duration{}
+	for k, v := range d.GetAnnotations() {
+		if k != DevicePollIntervalAnnotation && !strings.HasPrefix(k, DevicePropertyPollIntervalAnnotationPrefix) {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed < 0 {
+			klog.V(4).Infof("DeviceName: %s, ignore the invalid poll interval %s=%s", d.GetName(), k, v)
+			continue
+		}
+		if k == DevicePollIntervalAnnotation {
+			interval = parsed
+		} else if parsed > 0 {
+			propertyIntervals[strings.TrimPrefix(k, DevicePropertyPollIntervalAnnotationPrefix)] = parsed
+		}
+	}
+	return interval, propertyIntervals
+}
+
+// propertyReadOptions returns the options of reading the property from the annotations of the device,
+// the annotations of the property take precedence over the ones of the device
+func propertyReadOptions(d *devicev1alpha1.Device, propertyName string) edgeCli.PropertyReadOptions {
+	return edgeCli.PropertyReadOptions{
+		PushEvent:   boolAnnotation(d, DevicePushEventAnnotation, propertyName),
+		ReturnEvent: boolAnnotation(d, DeviceReturnEventAnnotation, propertyName),
+	}
+}
+
+// listReadOptions returns the options of reading all the properties of the device from its annotations
+func listReadOptions(d *devicev1alpha1.Device) edgeCli.ListOptions {
+	opts := edgeCli.ListOptions{ReadOptions: propertyReadOptions(d, "")}
+	for k := range d.GetAnnotations() {
+		for _, prefix := range []string{DevicePushEventAnnotation + ".", DeviceReturnEventAnnotation + "."} {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			name := strings.TrimPrefix(k, prefix)
+			if opts.PropertyReadOptions == nil {
+				opts.PropertyReadOptions = map[string]edgeCli.PropertyReadOptions{}
+			}
+			opts.PropertyReadOptions[name] = propertyReadOptions(d, name)
+		}
+	}
+	return opts
+}
+
+// boolAnnotation returns the value of the annotation of the property, or the one of the device if the property
+// has no such annotation, nil is returned if neither is set or valid
+func boolAnnotation(d *devicev1alpha1.Device, annotation, propertyName string) *bool {
+	keys := []string{annotation}
+	if propertyName != "" {
+		keys = []string{annotation + "." + propertyName, annotation}
+	}
+	for _, k := range keys {
+		v, exists := d.GetAnnotations()[k]
+		if !exists {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			klog.V(4).Infof("DeviceName: %s, ignore the invalid annotation %s=%s", d.GetName(), k, v)
+			continue
+		}
+		return &b
+	}
+	return nil
+}
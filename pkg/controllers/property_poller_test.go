@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
+
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPollIntervals(t *testing.T) {
+	d := &devicev1alpha1.Device{ObjectMeta: metav1.ObjectMeta{Name: "modbus-01"}}
+	interval, propertyIntervals := pollIntervals(d, 30*time.Second)
+	if interval != 30*time.Second || len(propertyIntervals) != 0 {
+		t.Fatalf("expected the default interval, got %v %v", interval, propertyIntervals)
+	}
+
+	d.Annotations = map[string]string{
+		DevicePollIntervalAnnotation:                           "5m",
+		DevicePropertyPollIntervalAnnotationPrefix + "Temp":    "10s",
+		DevicePropertyPollIntervalAnnotationPrefix + "Invalid": "often",
+		DevicePropertyPollIntervalAnnotationPrefix + "Off":     "0s",
+		"unrelated": "1s",
+	}
+	interval, propertyIntervals = pollIntervals(d, 30*time.Second)
+	if interval != 5*time.Minute {
+		t.Errorf("expected the interval of the annotation, got %v", interval)
+	}
+	if len(propertyIntervals) != 1 || propertyIntervals["Temp"] != 10*time.Second {
+		t.Errorf("expected only the interval of Temp, got %v", propertyIntervals)
+	}
+}
+
+func TestPropertyPollDue(t *testing.T) {
+	pp := &PropertyPoller{nextPoll: map[string]time.Time{}}
+	now := time.Now()
+	if !pp.due("default/modbus-01", time.Minute, now) {
+		t.Fatal("expected the first poll to be due at once")
+	}
+	if pp.due("default/modbus-01", time.Minute, now.Add(59*time.Second)) {
+		t.Error("expected the poll not to be due within the interval")
+	}
+	// the jitter never makes the interval longer than 1+propertyPollJitterFactor times
+	if !pp.due("default/modbus-01", time.Minute, now.Add(67*time.Second)) {
+		t.Error("expected the poll to be due after the interval")
+	}
+}
+
+func TestPropertyReadOptions(t *testing.T) {
+	d := &devicev1alpha1.Device{ObjectMeta: metav1.ObjectMeta{
+		Name: "modbus-01",
+		Annotations: map[string]string{
+			DevicePushEventAnnotation:                "false",
+			DevicePushEventAnnotation + ".Audit":     "true",
+			DeviceReturnEventAnnotation + ".Trigger": "false",
+			DeviceReturnEventAnnotation + ".Invalid": "maybe",
+		},
+	}}
+	ro := propertyReadOptions(d, "Temp")
+	if ro.PushEvent == nil || *ro.PushEvent || ro.ReturnEvent != nil {
+		t.Errorf("expected the options of the device, got %+v", ro)
+	}
+	ro = propertyReadOptions(d, "Audit")
+	if ro.PushEvent == nil || !*ro.PushEvent {
+		t.Errorf("expected the options of the property to take precedence, got %+v", ro)
+	}
+	ro = propertyReadOptions(d, "Invalid")
+	if ro.ReturnEvent != nil {
+		t.Errorf("expected the invalid annotation to be ignored, got %+v", ro)
+	}
+
+	opts := listReadOptions(d)
+	if ro := opts.ReadOptionsOf("Trigger"); ro.ReturnEvent == nil || *ro.ReturnEvent || ro.PushEvent == nil || *ro.PushEvent {
+		t.Errorf("expected the options of Trigger to be resolved, got %+v", ro)
+	}
+	if ro := opts.ReadOptionsOf("Temp"); ro.PushEvent == nil || *ro.PushEvent {
+		t.Errorf("expected the options of the device for Temp, got %+v", ro)
+	}
+}
+
+func newTestPropertyPoller(t *testing.T, deviceCli *fakeDeviceClient, objs ...client.Object) *PropertyPoller {
+	return &PropertyPoller{
+		Client:          newFakeClient(t, objs...),
+		NodePool:        "hangzhou",
+		Namespace:       "default",
+		deviceCli:       deviceCli,
+		defaultInterval: time.Minute,
+		limiter:         rate.NewLimiter(rate.Inf, 1),
+		slots:           make(chan struct{}, 1),
+		nextPoll:        map[string]time.Time{},
+		polling:         map[types.NamespacedName]struct{}{},
+	}
+}
+
+func newPolledDevice(name string, synced bool, annotations map[string]string) *devicev1alpha1.Device {
+	return &devicev1alpha1.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+		Spec: devicev1alpha1.DeviceSpec{
+			NodePool:         "hangzhou",
+			DeviceProperties: map[string]devicev1alpha1.DesiredPropertyState{"Temp": {Name: "Temp"}},
+		},
+		Status: devicev1alpha1.DeviceStatus{Synced: synced},
+	}
+}
+
+func TestPropertyPollerSchedule(t *testing.T) {
+	pp := newTestPropertyPoller(t, nil,
+		newPolledDevice("modbus-01", true, nil),
+		newPolledDevice("modbus-02", false, nil),
+		newPolledDevice("modbus-03", true, map[string]string{
+			DevicePollIntervalAnnotation:                        "0s",
+			DevicePropertyPollIntervalAnnotationPrefix + "Temp": "10s",
+		}),
+	)
+	now := time.Now()
+	polls, err := pp.schedule(context.TODO(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the device which is not synced is not polled, and modbus-03 only polls its own property
+	if len(polls) != 2 {
+		t.Fatalf("expected 2 devices to be polled, got %d", len(polls))
+	}
+	for _, p := range polls {
+		switch p.device.Name {
+		case "modbus-01":
+			if !p.all {
+				t.Errorf("expected all the properties of modbus-01 to be polled")
+			}
+		case "modbus-03":
+			if p.all || len(p.properties) != 1 || p.properties[0] != "Temp" {
+				t.Errorf("expected only Temp of modbus-03 to be polled, got %+v", p)
+			}
+		default:
+			t.Errorf("unexpected poll of %s", p.device.Name)
+		}
+	}
+
+	// the devices are polled again after their intervals, unless their last polls are still running
+	delete(pp.polling, types.NamespacedName{Namespace: "default", Name: "modbus-03"})
+	polls, err = pp.schedule(context.TODO(), now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polls) != 1 || polls[0].device.Name != "modbus-03" {
+		t.Fatalf("expected only modbus-03 to be polled while modbus-01 is being polled, got %v", polls)
+	}
+
+	// the device whose last poll finishes late is polled at once instead of skipping an interval
+	delete(pp.polling, types.NamespacedName{Namespace: "default", Name: "modbus-01"})
+	polls, err = pp.schedule(context.TODO(), now.Add(2*time.Minute+time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polls) != 1 || polls[0].device.Name != "modbus-01" || !polls[0].all {
+		t.Fatalf("expected modbus-01 to be polled once its last poll finishes, got %v", polls)
+	}
+}
+
+func TestPropertyPollerPoll(t *testing.T) {
+	d := newPolledDevice("modbus-01", true, nil)
+	cli := &fakeDeviceClient{getProperty: func(name string) (*devicev1alpha1.ActualPropertyState, error) {
+		return &devicev1alpha1.ActualPropertyState{Name: name, ActualValue: "21.5"}, nil
+	}}
+	pp := newTestPropertyPoller(t, cli, d)
+	key := types.NamespacedName{Namespace: "default", Name: "modbus-01"}
+	if err := pp.Get(context.TODO(), key, d); err != nil {
+		t.Fatal(err)
+	}
+
+	pp.polling[key] = struct{}{}
+	pp.poll(context.TODO(), &devicePoll{device: *d, all: true})
+	var polled devicev1alpha1.Device
+	if err := pp.Get(context.TODO(), key, &polled); err != nil {
+		t.Fatal(err)
+	}
+	if aps := polled.Status.DeviceProperties["Temp"]; aps.ActualValue != "21.5" {
+		t.Errorf("expected the polled value to be recorded, got %+v", polled.Status.DeviceProperties)
+	}
+	if _, exists := pp.polling[key]; exists {
+		t.Errorf("expected the device not to be polling once the poll finishes")
+	}
+
+	// the polls wait for the budget of the requests, and give up once they are canceled
+	pp.limiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	pp.limiter.Allow()
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	pp.polling[key] = struct{}{}
+	pp.poll(ctx, &devicePoll{device: *d, properties: []string{"Temp"}})
+	if reads := cli.readCount(); reads != 1 {
+		t.Errorf("expected no request to be sent beyond the budget, got %d reads", reads)
+	}
+	if _, exists := pp.polling[key]; exists {
+		t.Errorf("expected the device not to be polling once the poll gives up")
+	}
+}
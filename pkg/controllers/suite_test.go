@@ -24,6 +24,8 @@ import (
 
 	devicev1alpha1 "github.com/openyurtio/device-controller/apis/device.openyurt.io/v1alpha1"
 	"github.com/openyurtio/device-controller/cmd/yurt-device-controller/options"
+	"github.com/openyurtio/device-controller/pkg/clients"
+	_ "github.com/openyurtio/device-controller/pkg/clients/edgex-foundry"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -106,27 +108,34 @@ var _ = BeforeSuite(func() {
 		EnableLeaderElection: false,
 		Nodepool:             PoolName,
 		Namespace:            CommonNamespace,
+		EdgePlatform:         "edgex",
 		CoreDataAddr:         "edgex-core-data:59880",
 		CoreMetadataAddr:     "edgex-core-metadata:59881",
 		CoreCommandAddr:      "edgex-core-command:59882",
 		EdgeSyncPeriod:       5,
 	}
 
+	edgeClients, err := clients.NewEdgePlatformClients(co.EdgePlatform, co.EdgePlatformConfig())
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&DeviceProfileReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:           k8sManager.GetClient(),
+		Scheme:           k8sManager.GetScheme(),
+		DeviceProfileCli: edgeClients.DeviceProfileCli,
 	}).SetupWithManager(k8sManager, co)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&DeviceServiceReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:           k8sManager.GetClient(),
+		Scheme:           k8sManager.GetScheme(),
+		DeviceServiceCli: edgeClients.DeviceServiceCli,
 	}).SetupWithManager(k8sManager, co)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&DeviceReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:    k8sManager.GetClient(),
+		Scheme:    k8sManager.GetScheme(),
+		DeviceCli: edgeClients.DeviceCli,
 	}).SetupWithManager(k8sManager, co)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openyurtio/device-controller/pkg/clients"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the keys of the Secret which holds the credentials of the edge platform,
+// the Secret which holds the TLS material has the keys of the kubernetes.io/tls Secret and "ca.crt"
+const (
+	CredentialsTokenKey    = "token"
+	CredentialsUsernameKey = "username"
+	CredentialsPasswordKey = "password"
+	CredentialsAuthURLKey  = "authURL"
+)
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// SecretCredentials returns the credentials of the edge platform read from the Secret,
+// the Secret is read again every time the credentials are requested so that the rotated ones are picked up
+func SecretCredentials(reader client.Reader, key types.NamespacedName) clients.CredentialsFunc {
+	return func(ctx context.Context) (*clients.Credentials, error) {
+		var secret corev1.Secret
+		if err := reader.Get(ctx, key, &secret); err != nil {
+			return nil, fmt.Errorf("failed to read the Secret %s: %v", key, err)
+		}
+		creds := &clients.Credentials{
+			Token:    string(secret.Data[CredentialsTokenKey]),
+			Username: string(secret.Data[CredentialsUsernameKey]),
+			Password: string(secret.Data[CredentialsPasswordKey]),
+			AuthURL:  string(secret.Data[CredentialsAuthURLKey]),
+		}
+		if creds.Token == "" && creds.Username == "" {
+			return nil, fmt.Errorf("the Secret %s has neither %q nor %q", key, CredentialsTokenKey, CredentialsUsernameKey)
+		}
+		return creds, nil
+	}
+}
+
+// SecretTLS returns the TLS material of the edge platform read from the "ca.crt", "tls.crt" and "tls.key" of the Secret,
+// the Secret is read again every time the material is checked for rotation
+func SecretTLS(reader client.Reader, key types.NamespacedName) clients.TLSFunc {
+	return func(ctx context.Context) (*clients.TLSMaterial, error) {
+		var secret corev1.Secret
+		if err := reader.Get(ctx, key, &secret); err != nil {
+			return nil, fmt.Errorf("failed to read the Secret %s: %v", key, err)
+		}
+		return &clients.TLSMaterial{
+			CA:   secret.Data[corev1.ServiceAccountRootCAKey],
+			Cert: secret.Data[corev1.TLSCertKey],
+			Key:  secret.Data[corev1.TLSPrivateKeyKey],
+		}, nil
+	}
+}
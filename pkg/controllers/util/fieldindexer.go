@@ -27,11 +27,21 @@ import (
 
 const (
 	IndexerPathForNodepool = "spec.nodePool"
+	// IndexerPathForEdgeName indexes the objects by their nodePool and their actual names on edge platform,
+	// the values are made by EdgeNameIndexValue
+	IndexerPathForEdgeName = "spec.nodePool/edgeName"
 )
 
 var registerOnce sync.Once
 
-func RegisterFieldIndexers(fi client.FieldIndexer) error {
+// EdgeNameIndexValue returns the value of IndexerPathForEdgeName of the object with the actual name in the nodePool
+func EdgeNameIndexValue(nodePool, edgeName string) string {
+	return nodePool + "/" + edgeName
+}
+
+// RegisterFieldIndexers registers the field indexers of the device objects, the actual names of the objects
+// on edge platform are read from the edgeNameLabel
+func RegisterFieldIndexers(fi client.FieldIndexer, edgeNameLabel string) error {
 	var err error
 	registerOnce.Do(func() {
 		// register the fieldIndexer for device
@@ -57,6 +67,26 @@ func RegisterFieldIndexers(fi client.FieldIndexer) error {
 		}); err != nil {
 			return
 		}
+
+		// register the fieldIndexers of the actual names on edge platform
+		if err = fi.IndexField(context.TODO(), &v1alpha1.Device{}, IndexerPathForEdgeName, func(rawObj client.Object) []string {
+			device := rawObj.(*v1alpha1.Device)
+			return []string{EdgeNameIndexValue(device.Spec.NodePool, GetEdgeDeviceName(device, edgeNameLabel))}
+		}); err != nil {
+			return
+		}
+		if err = fi.IndexField(context.TODO(), &v1alpha1.DeviceService{}, IndexerPathForEdgeName, func(rawObj client.Object) []string {
+			deviceService := rawObj.(*v1alpha1.DeviceService)
+			return []string{EdgeNameIndexValue(deviceService.Spec.NodePool, GetEdgeDeviceServiceName(deviceService, edgeNameLabel))}
+		}); err != nil {
+			return
+		}
+		if err = fi.IndexField(context.TODO(), &v1alpha1.DeviceProfile{}, IndexerPathForEdgeName, func(rawObj client.Object) []string {
+			profile := rawObj.(*v1alpha1.DeviceProfile)
+			return []string{EdgeNameIndexValue(profile.Spec.NodePool, GetEdgeDeviceProfileName(profile, edgeNameLabel))}
+		}); err != nil {
+			return
+		}
 	})
 	return err
 }
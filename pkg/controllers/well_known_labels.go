@@ -19,3 +19,23 @@ package controllers
 const (
 	EdgeXObjectName = "device-controller/edgex-object.name"
 )
+
+const (
+	// DevicePollIntervalAnnotation overrides the interval of polling the actual properties of the device, e.g. "30s",
+	// "0s" means that the properties of the device are not polled
+	DevicePollIntervalAnnotation = "device.openyurt.io/poll-interval"
+	// DevicePropertyPollIntervalAnnotationPrefix is followed by the name of a property, e.g. "device.openyurt.io/poll-interval.Float32",
+	// to poll that property with its own interval besides the polls of the whole device
+	DevicePropertyPollIntervalAnnotationPrefix = DevicePollIntervalAnnotation + "."
+)
+
+const (
+	// DevicePushEventAnnotation controls whether the readings taken by reading the actual properties of the device
+	// are persisted by the edge platform, "true" or "false". It is followed by "." and the name of a property
+	// to control the reads of that property only, e.g. "device.openyurt.io/push-event.Float32"
+	DevicePushEventAnnotation = "device.openyurt.io/push-event"
+	// DeviceReturnEventAnnotation controls whether the readings are returned by the reads of the actual properties,
+	// "true" or "false", the actual properties are not updated if they are not returned. It can be followed by
+	// "." and the name of a property like DevicePushEventAnnotation
+	DeviceReturnEventAnnotation = "device.openyurt.io/return-event"
+)